@@ -8,8 +8,10 @@ require (
 	gioui.org v0.8.0
 	github.com/goccy/go-yaml v1.18.0
 	github.com/gorilla/websocket v1.5.3
+	github.com/lucasb-eyer/go-colorful v1.2.0
 	github.com/stretchr/testify v1.10.0
 	github.com/tgiv014/dexcom-share v0.0.0-20230407060014-4a7fb8995bae
+	golang.org/x/exp/shiny v0.0.0-20240707233637-46b078467d37
 )
 
 require (
@@ -18,11 +20,9 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/go-text/typesetting v0.2.1 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
-	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rogpeppe/go-internal v1.12.0 // indirect
 	golang.org/x/exp v0.0.0-20250210185358-939b2ce775ac // indirect
-	golang.org/x/exp/shiny v0.0.0-20240707233637-46b078467d37 // indirect
 	golang.org/x/image v0.24.0 // indirect
 	golang.org/x/sys v0.31.0 // indirect
 	golang.org/x/text v0.23.0 // indirect
@@ -1,22 +1,33 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"log"
 	"os"
+	"time"
 
 	"gioui.org/app"
 	"gioui.org/layout"
 	"gioui.org/op"
-	"gioui.org/op/clip"
-	"gioui.org/op/paint"
-	"gioui.org/text"
-	"gioui.org/widget/material"
 
 	"github.com/mntndev/dash/pkg/config"
 	"github.com/mntndev/dash/pkg/dashboard"
+	"github.com/mntndev/dash/pkg/integrations"
+	"github.com/mntndev/dash/pkg/render"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		if err := runRender(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(2)
+			}
+			log.Fatal(err)
+		}
+		return
+	}
+
 	go func() {
 		w := new(app.Window)
 		w.Option(app.Title("Dash - Dashboard"))
@@ -38,7 +49,7 @@ func main() {
 			w.Option(app.Size(1200, 800))
 		}
 
-		if err := run(w, cfg); err != nil {
+		if err := run(w); err != nil {
 			log.Fatal(err)
 		}
 		os.Exit(0)
@@ -48,12 +59,9 @@ func main() {
 
 type App struct {
 	dashService *dashboard.DashboardService
-	theme       *material.Theme
 }
 
-func run(w *app.Window, cfg *config.Config) error {
-	th := cfg.Theme()
-
+func run(w *app.Window) error {
 	// Create dashboard service
 	dashService := dashboard.NewDashboardService(w)
 
@@ -64,7 +72,6 @@ func run(w *app.Window, cfg *config.Config) error {
 
 	dashApp := &App{
 		dashService: dashService,
-		theme:       th,
 	}
 
 	var ops op.Ops
@@ -81,29 +88,59 @@ func run(w *app.Window, cfg *config.Config) error {
 	}
 }
 
-type Layoutable interface {
-	Layout(gtx layout.Context) layout.Dimensions
+func (a *App) Layout(gtx layout.Context) layout.Dimensions {
+	return a.dashService.Layout(gtx)
 }
 
-func (a *App) Layout(gtx layout.Context) layout.Dimensions {
-	// Get the root widget from the dashboard service
-	var rootWidget Layoutable = a.dashService.GetRootWidget()
-
-	if rootWidget == nil {
-		// Show loading or error state
-		title := material.H3(a.theme, "Loading Dashboard...")
-		title.Alignment = text.Middle
-		rootWidget = title
+// runRender implements "dash render". It builds the dashboard from a config
+// file without a window and saves a single frame as a PNG.
+func runRender(args []string) error {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	configPath := flags.String("config", config.GetDefaultConfigPath(), "config file to render")
+	size := flags.String("size", "800x480", "image size in pixels, WIDTHxHEIGHT")
+	scale := flags.Float64("scale", 1, "pixels per dp")
+	out := flags.String("out", "dash.png", "PNG file to write")
+	live := flags.Bool("live", false, "connect to the configured integrations instead of rendering without data")
+	wait := flags.Duration("wait", 0, "how long to let widgets load data before rendering, e.g. 3s with -live")
+	fixture := flags.String("fixture", "", "JSON file with data to show by widget ID, as GET /api/widgets/{id} returns it")
+	if err := flags.Parse(args); err != nil {
+		return err
 	}
 
-	return layout.Background{}.Layout(gtx,
-		func(gtx layout.Context) layout.Dimensions {
-			defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
-			paint.Fill(gtx.Ops, a.theme.Bg)
-			return layout.Dimensions{Size: gtx.Constraints.Min}
-		}, func(gtx layout.Context) layout.Dimensions {
-			return rootWidget.Layout(gtx)
-		})
+	imageSize, err := render.ParseSize(*size)
+	if err != nil {
+		return err
+	}
+
+	var registry *integrations.Registry
+	if *live {
+		registry = integrations.NewDefaultRegistry()
+	}
+	dashService, err := dashboard.NewHeadlessService(*configPath, registry)
+	if err != nil {
+		return err
+	}
+	defer dashService.Close()
+
+	if err := dashService.Initialize(); err != nil {
+		return err
+	}
+	time.Sleep(*wait)
+	if *fixture != "" {
+		if err := dashService.LoadFixtures(*fixture); err != nil {
+			return err
+		}
+	}
+
+	img, err := render.Render(dashService.Layout, render.Options{Size: imageSize, Scale: float32(*scale)})
+	if err != nil {
+		return err
+	}
+	if err := render.WritePNG(*out, img); err != nil {
+		return err
+	}
+	log.Printf("Rendered %s to %s", *configPath, *out)
+	return nil
 }
 
 func getDefaultConfig() *config.Config {
@@ -3,17 +3,23 @@ package config
 import (
 	"fmt"
 	"image/color"
+	"net"
 	"os"
 	"path/filepath"
+	"regexp"
+	"time"
 
 	"gioui.org/font/gofont"
 	"gioui.org/text"
 	"gioui.org/widget/material"
 	"github.com/goccy/go-yaml"
 	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
 	"github.com/lucasb-eyer/go-colorful"
 )
 
+var widgetIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
 type Config struct {
 	Dashboard    DashboardConfig    `yaml:"dashboard"`
 	Integrations IntegrationsConfig `yaml:"integrations"`
@@ -25,6 +31,29 @@ type DashboardConfig struct {
 	Colors     *ColorConfig `yaml:"colors,omitempty"`
 	Fullscreen bool         `yaml:"fullscreen"`
 	Widget     WidgetConfig `yaml:"widget"`
+	// Pages replaces Widget with several screens the user swipes between.
+	// LoadConfig turns them into a pages widget at the root.
+	Pages []PageConfig `yaml:"pages,omitempty"`
+	// TabBar shows the page names along the bottom of the screen.
+	TabBar bool `yaml:"tab_bar,omitempty"`
+	// API enables the HTTP control API.
+	API *APIConfig `yaml:"api,omitempty"`
+}
+
+// APIConfig configures the HTTP control API.
+type APIConfig struct {
+	// Listen is the address to serve on, e.g. "127.0.0.1:8080".
+	Listen string `yaml:"listen"`
+	// Token is required as a bearer token on every request. It may only be
+	// left out when listening on a loopback address.
+	Token string `yaml:"token,omitempty"`
+}
+
+type PageConfig struct {
+	Name string `yaml:"name"`
+	// Icon is shown next to the name in the tab bar, e.g. "lights".
+	Icon   string       `yaml:"icon,omitempty"`
+	Widget WidgetConfig `yaml:"widget"`
 }
 
 type ColorConfig struct {
@@ -35,21 +64,36 @@ type ColorConfig struct {
 }
 
 type WidgetConfig struct {
+	// ID names the widget for the API and reloads. Widgets without one get
+	// an ID from their place in the tree.
+	ID       string         `yaml:"id,omitempty" json:"ID,omitempty"`
 	Type     string         `yaml:"type" json:"Type"`
 	Config   ast.Node       `yaml:"config,omitempty" json:"-"`
 	Children []WidgetConfig `yaml:"children,omitempty" json:"Children,omitempty"`
+	// Position places the widget when its parent is a grid.
+	Position *Position `yaml:"position,omitempty" json:"Position,omitempty"`
 }
 
 type IntegrationsConfig struct {
 	HomeAssistant *HomeAssistantConfig `yaml:"home_assistant,omitempty"`
 	Dexcom        *DexcomConfig        `yaml:"dexcom,omitempty"`
+	Nightscout    *NightscoutConfig    `yaml:"nightscout,omitempty"`
 	Prometheus    *PrometheusConfig    `yaml:"prometheus,omitempty"`
 	RSS           []RSSConfig          `yaml:"rss,omitempty"`
+
+	// Raw holds every section under integrations: by key, including ones
+	// without a typed field above. The dashboard creates integrations from it.
+	Raw map[string]ast.Node `yaml:"-"`
 }
 
 type HomeAssistantConfig struct {
 	URL   string `yaml:"url"`
 	Token string `yaml:"token"`
+	// PingInterval is how often the connection is probed, e.g. "30s".
+	PingInterval string `yaml:"ping_interval,omitempty"`
+	// PingMaxMissed is how many pings may go unanswered before the
+	// connection is considered dead.
+	PingMaxMissed int `yaml:"ping_max_missed,omitempty"`
 }
 
 type DexcomConfig struct {
@@ -57,11 +101,28 @@ type DexcomConfig struct {
 	Password string `yaml:"password"`
 }
 
+type NightscoutConfig struct {
+	URL string `yaml:"url"`
+	// APISecret is sent hashed in the api-secret header.
+	APISecret string `yaml:"api_secret,omitempty"`
+	// Token is an access token, used instead of the API secret.
+	Token string `yaml:"token,omitempty"`
+	// PollInterval is how often readings are fetched, e.g. "1m".
+	PollInterval string `yaml:"poll_interval,omitempty"`
+}
+
 type PrometheusConfig struct {
 	URL string `yaml:"url"`
+	// Username and Password enable basic auth.
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// BearerToken is sent as an Authorization header instead of basic auth.
+	BearerToken string `yaml:"bearer_token,omitempty"`
 }
 
 type RSSConfig struct {
+	// Name lets widgets pick the feed. Widgets can also use the URL.
+	Name            string `yaml:"name,omitempty"`
 	URL             string `yaml:"url"`
 	RefreshInterval string `yaml:"refresh_interval"`
 }
@@ -81,23 +142,249 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	var raw struct {
+		Integrations map[string]ast.Node `yaml:"integrations"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse integrations: %w", err)
+	}
+	config.Integrations.Raw = raw.Integrations
+
 	if err := validateConfig(&config); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
+	if len(config.Dashboard.Pages) > 0 {
+		root, err := pagesWidget(config.Dashboard)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config: %w", err)
+		}
+		config.Dashboard.Widget = root
+	}
+
 	return &config, nil
 }
 
+// pagesWidget builds the root widget for a dashboard with pages. Each page
+// becomes a child, the names and icons go into the widget config.
+func pagesWidget(dashboard DashboardConfig) (WidgetConfig, error) {
+	type pageInfo struct {
+		Name string `yaml:"name"`
+		Icon string `yaml:"icon,omitempty"`
+	}
+	pagesConfig := struct {
+		TabBar bool       `yaml:"tab_bar"`
+		Pages  []pageInfo `yaml:"pages"`
+	}{TabBar: dashboard.TabBar}
+
+	children := make([]WidgetConfig, 0, len(dashboard.Pages))
+	for _, page := range dashboard.Pages {
+		pagesConfig.Pages = append(pagesConfig.Pages, pageInfo{Name: page.Name, Icon: page.Icon})
+		children = append(children, page.Widget)
+	}
+
+	data, err := yaml.Marshal(pagesConfig)
+	if err != nil {
+		return WidgetConfig{}, fmt.Errorf("failed to encode pages: %w", err)
+	}
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		return WidgetConfig{}, fmt.Errorf("failed to encode pages: %w", err)
+	}
+
+	return WidgetConfig{
+		Type:     "pages",
+		Config:   file.Docs[0].Body,
+		Children: children,
+	}, nil
+}
+
 func validateConfig(config *Config) error {
 	if config.Dashboard.Title == "" {
 		return fmt.Errorf("dashboard title is required")
 	}
 
-	if config.Dashboard.Widget.Type == "" {
-		return fmt.Errorf("dashboard widget type is required")
+	if err := validateIntegrations(config.Integrations); err != nil {
+		return err
 	}
 
-	return validateWidget(config.Dashboard.Widget)
+	if config.Dashboard.API != nil {
+		if err := validateAPI(*config.Dashboard.API); err != nil {
+			return err
+		}
+	}
+
+	if len(config.Dashboard.Pages) > 0 {
+		if err := validatePages(config.Dashboard); err != nil {
+			return err
+		}
+	} else {
+		if config.Dashboard.Widget.Type == "" {
+			return fmt.Errorf("dashboard widget type is required")
+		}
+		if err := validateWidget(config.Dashboard.Widget); err != nil {
+			return err
+		}
+	}
+
+	return validateWidgetIDs(config.Dashboard)
+}
+
+// RootWidgetPrefix is the prefix the ID of the root widget is made from.
+const RootWidgetPrefix = "root"
+
+// WidgetID returns the ID of a widget in the running dashboard, its id: if
+// set. Otherwise it is made from prefix, which names its place in the tree,
+// and its type.
+func WidgetID(widget WidgetConfig, prefix string) string {
+	if widget.ID != "" {
+		return widget.ID
+	}
+	return fmt.Sprintf("%s_%s", prefix, widget.Type)
+}
+
+// ChildPrefix returns the prefix of child i of widget for WidgetID. Children
+// of a widget with an id: are named after it, so edits elsewhere in the
+// config do not change them.
+func ChildPrefix(widget WidgetConfig, prefix string, i int) string {
+	if widget.ID != "" {
+		prefix = widget.ID
+	}
+	return fmt.Sprintf("%s_child_%d", prefix, i)
+}
+
+// validateWidgetIDs checks that widget IDs are unique across the whole
+// dashboard, pages included, and safe to use in a URL path. IDs generated
+// for widgets without an id: count as well, an id: may not take one.
+func validateWidgetIDs(dashboard DashboardConfig) error {
+	seen := make(map[string]bool)
+	var walk func(widget WidgetConfig, prefix string) error
+	walk = func(widget WidgetConfig, prefix string) error {
+		if widget.ID != "" && !widgetIDPattern.MatchString(widget.ID) {
+			return fmt.Errorf("widget id %q may only contain letters, digits, '_', '-' and '.'", widget.ID)
+		}
+		id := WidgetID(widget, prefix)
+		if seen[id] {
+			return fmt.Errorf("duplicate widget id %q", id)
+		}
+		seen[id] = true
+		for i, child := range widget.Children {
+			if err := walk(child, ChildPrefix(widget, prefix, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	root := dashboard.Widget
+	if len(dashboard.Pages) > 0 {
+		// The pages become the children of the root, as in pagesWidget
+		root = WidgetConfig{Type: "pages"}
+		for _, page := range dashboard.Pages {
+			root.Children = append(root.Children, page.Widget)
+		}
+	}
+	return walk(root, RootWidgetPrefix)
+}
+
+func validatePages(dashboard DashboardConfig) error {
+	if dashboard.Widget.Type != "" {
+		return fmt.Errorf("dashboard cannot have both a widget and pages")
+	}
+
+	names := make(map[string]bool)
+	for i, page := range dashboard.Pages {
+		if page.Name == "" {
+			return fmt.Errorf("page %d: name is required", i)
+		}
+		if names[page.Name] {
+			return fmt.Errorf("page %d: duplicate name %q", i, page.Name)
+		}
+		names[page.Name] = true
+
+		if page.Widget.Type == "" {
+			return fmt.Errorf("page %s: widget type is required", page.Name)
+		}
+		if err := validateWidget(page.Widget); err != nil {
+			return fmt.Errorf("page %s: %w", page.Name, err)
+		}
+	}
+	return nil
+}
+
+func validateAPI(api APIConfig) error {
+	if api.Listen == "" {
+		return fmt.Errorf("api listen address is required")
+	}
+	host, _, err := net.SplitHostPort(api.Listen)
+	if err != nil {
+		return fmt.Errorf("api listen: %w", err)
+	}
+	if api.Token != "" {
+		return nil
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("api token is required unless listening on a loopback address")
+	}
+	return nil
+}
+
+func validateIntegrations(integrations IntegrationsConfig) error {
+	if ha := integrations.HomeAssistant; ha != nil {
+		if ha.PingInterval != "" {
+			interval, err := time.ParseDuration(ha.PingInterval)
+			if err != nil {
+				return fmt.Errorf("home_assistant ping_interval: %w", err)
+			}
+			if interval <= 0 {
+				return fmt.Errorf("home_assistant ping_interval must be positive")
+			}
+		}
+		if ha.PingMaxMissed < 0 {
+			return fmt.Errorf("home_assistant ping_max_missed must not be negative")
+		}
+	}
+
+	if ns := integrations.Nightscout; ns != nil {
+		if ns.URL == "" {
+			return fmt.Errorf("nightscout url is required")
+		}
+		if ns.PollInterval != "" {
+			interval, err := time.ParseDuration(ns.PollInterval)
+			if err != nil {
+				return fmt.Errorf("nightscout poll_interval: %w", err)
+			}
+			if interval <= 0 {
+				return fmt.Errorf("nightscout poll_interval must be positive")
+			}
+		}
+	}
+
+	if prom := integrations.Prometheus; prom != nil {
+		if prom.URL == "" {
+			return fmt.Errorf("prometheus url is required")
+		}
+		if prom.BearerToken != "" && (prom.Username != "" || prom.Password != "") {
+			return fmt.Errorf("prometheus bearer_token cannot be combined with basic auth")
+		}
+	}
+
+	for i, feed := range integrations.RSS {
+		if feed.URL == "" {
+			return fmt.Errorf("rss feed %d: url is required", i)
+		}
+		if feed.RefreshInterval != "" {
+			interval, err := time.ParseDuration(feed.RefreshInterval)
+			if err != nil {
+				return fmt.Errorf("rss feed %s refresh_interval: %w", feed.URL, err)
+			}
+			if interval <= 0 {
+				return fmt.Errorf("rss feed %s refresh_interval must be positive", feed.URL)
+			}
+		}
+	}
+
+	return nil
 }
 
 func validateWidget(widget WidgetConfig) error {
@@ -105,15 +392,20 @@ func validateWidget(widget WidgetConfig) error {
 		return fmt.Errorf("widget type is required")
 	}
 
-	// Validate children for layout widgets
-	if widget.Type == "horizontal_split" || widget.Type == "vertical_split" {
-		if len(widget.Children) == 0 {
-			return fmt.Errorf("layout widget must have at least one child")
+	if (widget.Type == "horizontal_split" || widget.Type == "vertical_split") && len(widget.Children) == 0 {
+		return fmt.Errorf("layout widget must have at least one child")
+	}
+
+	if widget.Type == "grid" {
+		if err := validateGrid(widget); err != nil {
+			return err
 		}
-		for i, child := range widget.Children {
-			if err := validateWidget(child); err != nil {
-				return fmt.Errorf("child %d: %w", i, err)
-			}
+	}
+
+	// Any widget may contain a grid, so check the children of all of them
+	for i, child := range widget.Children {
+		if err := validateWidget(child); err != nil {
+			return fmt.Errorf("child %d: %w", i, err)
 		}
 	}
 
@@ -160,8 +452,8 @@ func getConfigPaths() []string {
 	return paths
 }
 
-// parseColor parses a color string (hex, CSS names, etc.) into color.NRGBA using go-colorful
-func parseColor(colorStr string) (color.NRGBA, error) {
+// ParseColor parses a color string (hex, CSS names, etc.) into color.NRGBA using go-colorful
+func ParseColor(colorStr string) (color.NRGBA, error) {
 	if colorStr == "" {
 		return color.NRGBA{}, fmt.Errorf("empty color string")
 	}
@@ -185,25 +477,25 @@ func (c *Config) Theme() *material.Theme {
 		palette := &th.Palette
 
 		if c.Dashboard.Colors.Bg != "" {
-			if color, err := parseColor(c.Dashboard.Colors.Bg); err == nil {
+			if color, err := ParseColor(c.Dashboard.Colors.Bg); err == nil {
 				palette.Bg = color
 			}
 		}
 
 		if c.Dashboard.Colors.Fg != "" {
-			if color, err := parseColor(c.Dashboard.Colors.Fg); err == nil {
+			if color, err := ParseColor(c.Dashboard.Colors.Fg); err == nil {
 				palette.Fg = color
 			}
 		}
 
 		if c.Dashboard.Colors.ContrastBg != "" {
-			if color, err := parseColor(c.Dashboard.Colors.ContrastBg); err == nil {
+			if color, err := ParseColor(c.Dashboard.Colors.ContrastBg); err == nil {
 				palette.ContrastBg = color
 			}
 		}
 
 		if c.Dashboard.Colors.ContrastFg != "" {
-			if color, err := parseColor(c.Dashboard.Colors.ContrastFg); err == nil {
+			if color, err := ParseColor(c.Dashboard.Colors.ContrastFg); err == nil {
 				palette.ContrastFg = color
 			}
 		}
@@ -44,6 +44,11 @@ func TestLoadConfig(t *testing.T) {
 				assert.Len(t, config.Integrations.RSS, 1)
 				assert.Equal(t, "https://example.com/feed.xml", config.Integrations.RSS[0].URL)
 				assert.Equal(t, "1h", config.Integrations.RSS[0].RefreshInterval)
+
+				// Every section is kept raw for the integration registry
+				assert.Len(t, config.Integrations.Raw, 4)
+				assert.Contains(t, config.Integrations.Raw["prometheus"].String(), "http://localhost:9090")
+				assert.Equal(t, ast.SequenceType, config.Integrations.Raw["rss"].Type())
 			},
 		},
 		{
@@ -62,6 +67,36 @@ func TestLoadConfig(t *testing.T) {
 				assert.Nil(t, config.Integrations.Dexcom)
 				assert.Nil(t, config.Integrations.Prometheus)
 				assert.Empty(t, config.Integrations.RSS)
+				assert.Empty(t, config.Integrations.Raw)
+			},
+		},
+		{
+			name:        "pages",
+			filename:    "valid_pages.yaml",
+			expectError: false,
+			validate: func(t *testing.T, config *Config) {
+				require.Len(t, config.Dashboard.Pages, 2)
+				assert.Equal(t, "lights", config.Dashboard.Pages[0].Icon)
+
+				// Pages become the children of a pages widget at the root
+				root := config.Dashboard.Widget
+				assert.Equal(t, "pages", root.Type)
+				require.Len(t, root.Children, 2)
+				assert.Equal(t, "home_assistant.switch", root.Children[0].Type)
+				assert.Equal(t, "clock", root.Children[1].Type)
+
+				var pagesConfig struct {
+					TabBar bool `yaml:"tab_bar"`
+					Pages  []struct {
+						Name string `yaml:"name"`
+						Icon string `yaml:"icon"`
+					} `yaml:"pages"`
+				}
+				require.NoError(t, yaml.NodeToValue(root.Config, &pagesConfig))
+				assert.True(t, pagesConfig.TabBar)
+				require.Len(t, pagesConfig.Pages, 2)
+				assert.Equal(t, "Climate", pagesConfig.Pages[1].Name)
+				assert.Equal(t, "climate", pagesConfig.Pages[1].Icon)
 			},
 		},
 		{
@@ -142,6 +177,67 @@ func TestValidateConfig(t *testing.T) {
 			expectError: true,
 			errorMsg:    "dashboard widget type is required",
 		},
+		{
+			name: "pages without a root widget",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title: "Test Dashboard",
+					Pages: []PageConfig{
+						{Name: "One", Widget: WidgetConfig{Type: "clock"}},
+						{Name: "Two", Widget: WidgetConfig{Type: "clock"}},
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "pages and root widget",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title:  "Test Dashboard",
+					Widget: WidgetConfig{Type: "clock"},
+					Pages:  []PageConfig{{Name: "One", Widget: WidgetConfig{Type: "clock"}}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "dashboard cannot have both a widget and pages",
+		},
+		{
+			name: "page without name",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title: "Test Dashboard",
+					Pages: []PageConfig{{Widget: WidgetConfig{Type: "clock"}}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "page 0: name is required",
+		},
+		{
+			name: "duplicate page names",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title: "Test Dashboard",
+					Pages: []PageConfig{
+						{Name: "One", Widget: WidgetConfig{Type: "clock"}},
+						{Name: "One", Widget: WidgetConfig{Type: "clock"}},
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    `page 1: duplicate name "One"`,
+		},
+		{
+			name: "page without widget",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title: "Test Dashboard",
+					Pages: []PageConfig{{Name: "One"}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "page One: widget type is required",
+		},
 		{
 			name: "empty dashboard",
 			config: &Config{
@@ -149,6 +245,219 @@ func TestValidateConfig(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "valid home assistant heartbeat",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title:  "Test Dashboard",
+					Widget: WidgetConfig{Type: "clock"},
+				},
+				Integrations: IntegrationsConfig{
+					HomeAssistant: &HomeAssistantConfig{
+						URL:           "ws://localhost:8123/api/websocket",
+						PingInterval:  "15s",
+						PingMaxMissed: 2,
+					},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid home assistant ping interval",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title:  "Test Dashboard",
+					Widget: WidgetConfig{Type: "clock"},
+				},
+				Integrations: IntegrationsConfig{
+					HomeAssistant: &HomeAssistantConfig{
+						URL:          "ws://localhost:8123/api/websocket",
+						PingInterval: "often",
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "ping_interval",
+		},
+		{
+			name: "nightscout without url",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title:  "Test Dashboard",
+					Widget: WidgetConfig{Type: "clock"},
+				},
+				Integrations: IntegrationsConfig{
+					Nightscout: &NightscoutConfig{Token: "dash-1234"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "nightscout url is required",
+		},
+		{
+			name: "invalid nightscout poll interval",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title:  "Test Dashboard",
+					Widget: WidgetConfig{Type: "clock"},
+				},
+				Integrations: IntegrationsConfig{
+					Nightscout: &NightscoutConfig{
+						URL:          "https://ns.example.com",
+						PollInterval: "0s",
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "poll_interval must be positive",
+		},
+		{
+			name: "prometheus with both auth methods",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title:  "Test Dashboard",
+					Widget: WidgetConfig{Type: "clock"},
+				},
+				Integrations: IntegrationsConfig{
+					Prometheus: &PrometheusConfig{
+						URL:         "http://localhost:9090",
+						Username:    "admin",
+						Password:    "secret",
+						BearerToken: "token",
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "bearer_token",
+		},
+		{
+			name: "unique widget ids",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title: "Test Dashboard",
+					Widget: WidgetConfig{Type: "hstack", ID: "main", Children: []WidgetConfig{
+						{Type: "clock", ID: "clock"},
+						{Type: "clock"},
+					}},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "duplicate widget id",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title: "Test Dashboard",
+					Widget: WidgetConfig{Type: "hstack", Children: []WidgetConfig{
+						{Type: "clock", ID: "clock"},
+						{Type: "vstack", Children: []WidgetConfig{{Type: "clock", ID: "clock"}}},
+					}},
+				},
+			},
+			expectError: true,
+			errorMsg:    `duplicate widget id "clock"`,
+		},
+		{
+			name: "widget id taken by a generated one",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title: "Test Dashboard",
+					Widget: WidgetConfig{Type: "hstack", Children: []WidgetConfig{
+						{Type: "clock", ID: "root_child_1_clock"},
+						{Type: "clock"},
+					}},
+				},
+			},
+			expectError: true,
+			errorMsg:    `duplicate widget id "root_child_1_clock"`,
+		},
+		{
+			name: "widget id taken by a generated one on a page",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title: "Test Dashboard",
+					Pages: []PageConfig{
+						{Name: "One", Widget: WidgetConfig{Type: "clock", ID: "root_child_1_clock"}},
+						{Name: "Two", Widget: WidgetConfig{Type: "clock"}},
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    `duplicate widget id "root_child_1_clock"`,
+		},
+		{
+			name: "duplicate widget id across pages",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title: "Test Dashboard",
+					Pages: []PageConfig{
+						{Name: "One", Widget: WidgetConfig{Type: "clock", ID: "clock"}},
+						{Name: "Two", Widget: WidgetConfig{Type: "clock", ID: "clock"}},
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    `duplicate widget id "clock"`,
+		},
+		{
+			name: "widget id with a slash",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title:  "Test Dashboard",
+					Widget: WidgetConfig{Type: "clock", ID: "living/room"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "may only contain",
+		},
+		{
+			name: "api on loopback without token",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title:  "Test Dashboard",
+					Widget: WidgetConfig{Type: "clock"},
+					API:    &APIConfig{Listen: "127.0.0.1:8080"},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "api on all interfaces without token",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title:  "Test Dashboard",
+					Widget: WidgetConfig{Type: "clock"},
+					API:    &APIConfig{Listen: ":8080"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "api token is required",
+		},
+		{
+			name: "api without port",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title:  "Test Dashboard",
+					Widget: WidgetConfig{Type: "clock"},
+					API:    &APIConfig{Listen: "localhost", Token: "secret"},
+				},
+			},
+			expectError: true,
+			errorMsg:    "api listen",
+		},
+		{
+			name: "rss feed without url",
+			config: &Config{
+				Dashboard: DashboardConfig{
+					Title:  "Test Dashboard",
+					Widget: WidgetConfig{Type: "clock"},
+				},
+				Integrations: IntegrationsConfig{
+					RSS: []RSSConfig{{Name: "news", RefreshInterval: "10m"}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "url is required",
+		},
 	}
 
 	for _, tt := range tests {
@@ -223,6 +532,69 @@ func TestValidateWidget(t *testing.T) {
 			expectError: true,
 			errorMsg:    "layout widget must have at least one child",
 		},
+		{
+			name: "valid grid",
+			widget: WidgetConfig{
+				Type:   "grid",
+				Config: mustNode(map[string]interface{}{"columns": 2, "rows": 1}),
+				Children: []WidgetConfig{
+					{Type: "clock", Position: &Position{X: 0, Y: 0, Width: 1, Height: 1}},
+					{Type: "clock", Position: &Position{X: 1, Y: 0, Width: 1, Height: 1}},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "grid without columns",
+			widget: WidgetConfig{
+				Type:     "grid",
+				Children: []WidgetConfig{{Type: "clock"}},
+			},
+			expectError: true,
+			errorMsg:    "grid columns must be positive",
+		},
+		{
+			name: "grid with overlapping children",
+			widget: WidgetConfig{
+				Type:   "grid",
+				Config: mustNode(map[string]interface{}{"columns": 2}),
+				Children: []WidgetConfig{
+					{Type: "clock", Position: &Position{X: 0, Y: 0, Width: 2, Height: 1}},
+					{Type: "clock", Position: &Position{X: 1, Y: 0, Width: 1, Height: 1}},
+				},
+			},
+			expectError: true,
+			errorMsg:    "child 1 overlaps child 0",
+		},
+		{
+			name: "grid with invalid child",
+			widget: WidgetConfig{
+				Type:     "grid",
+				Config:   mustNode(map[string]interface{}{"columns": 2}),
+				Children: []WidgetConfig{{Type: "clock"}, {}},
+			},
+			expectError: true,
+			errorMsg:    "child 1: widget type is required",
+		},
+		{
+			name: "grid nested in a stack",
+			widget: WidgetConfig{
+				Type: "vstack",
+				Children: []WidgetConfig{
+					{Type: "clock"},
+					{
+						Type:   "grid",
+						Config: mustNode(map[string]interface{}{"columns": 2}),
+						Children: []WidgetConfig{
+							{Type: "clock", Position: &Position{X: 5, Y: 0, Width: 1, Height: 1}},
+							{Type: "clock", Position: &Position{X: 5, Y: 1, Width: 1, Height: 1}},
+						},
+					},
+				},
+			},
+			expectError: true,
+			errorMsg:    "child 1: child 0: spans columns 5 to 5 but the grid has 2",
+		},
 		{
 			name: "layout with invalid child",
 			widget: WidgetConfig{
@@ -253,6 +625,14 @@ func TestValidateWidget(t *testing.T) {
 	}
 }
 
+func mustNode(value interface{}) ast.Node {
+	node, err := yaml.ValueToNode(value)
+	if err != nil {
+		panic(err)
+	}
+	return node
+}
+
 func TestConfigPathFunctions(t *testing.T) {
 	t.Run("get config paths", func(t *testing.T) {
 		paths := getConfigPaths()
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+)
+
+// Position places a child of a grid widget. X and Y are zero-based cell
+// coordinates, Width and Height the number of cells it spans.
+type Position struct {
+	X      int `yaml:"x" json:"x"`
+	Y      int `yaml:"y" json:"y"`
+	Width  int `yaml:"width" json:"width"`
+	Height int `yaml:"height" json:"height"`
+}
+
+// GridConfig is the config of a grid widget.
+type GridConfig struct {
+	Columns int `yaml:"columns"`
+	// Rows defaults to as many as the children need.
+	Rows int `yaml:"rows"`
+	// Gap is the space between cells in dp.
+	Gap float32 `yaml:"gap"`
+}
+
+func (p Position) overlaps(other Position) bool {
+	return p.X < other.X+other.Width && other.X < p.X+p.Width &&
+		p.Y < other.Y+other.Height && other.Y < p.Y+p.Height
+}
+
+// ParseGridConfig reads and checks the config of a grid widget.
+func ParseGridConfig(node ast.Node) (GridConfig, error) {
+	var grid GridConfig
+	if node != nil {
+		if err := yaml.NodeToValue(node, &grid); err != nil {
+			return GridConfig{}, fmt.Errorf("failed to parse grid config: %w", err)
+		}
+	}
+
+	if grid.Columns <= 0 {
+		return GridConfig{}, fmt.Errorf("grid columns must be positive")
+	}
+	if grid.Rows < 0 {
+		return GridConfig{}, fmt.Errorf("grid rows must not be negative")
+	}
+	if grid.Gap < 0 {
+		return GridConfig{}, fmt.Errorf("grid gap must not be negative")
+	}
+	return grid, nil
+}
+
+// PlaceGridCells works out where each child of a grid goes. Children with a
+// position keep it, the others fill the free cells left to right, top to
+// bottom, one cell each. It fails if children overlap or do not fit.
+func PlaceGridCells(grid GridConfig, positions []*Position) ([]Position, error) {
+	placed := make([]Position, len(positions))
+	fixed := make([]bool, len(positions))
+
+	for i, pos := range positions {
+		if pos == nil {
+			continue
+		}
+		if pos.X < 0 || pos.Y < 0 || pos.Width <= 0 || pos.Height <= 0 {
+			return nil, fmt.Errorf("child %d: position needs x and y of at least 0 and a positive width and height", i)
+		}
+		if pos.X+pos.Width > grid.Columns {
+			return nil, fmt.Errorf("child %d: spans columns %d to %d but the grid has %d", i, pos.X, pos.X+pos.Width-1, grid.Columns)
+		}
+		if grid.Rows > 0 && pos.Y+pos.Height > grid.Rows {
+			return nil, fmt.Errorf("child %d: spans rows %d to %d but the grid has %d", i, pos.Y, pos.Y+pos.Height-1, grid.Rows)
+		}
+		for j := range i {
+			if fixed[j] && placed[j].overlaps(*pos) {
+				return nil, fmt.Errorf("child %d overlaps child %d", i, j)
+			}
+		}
+		placed[i] = *pos
+		fixed[i] = true
+	}
+
+	// Children not placed yet have no size and overlap nothing
+	taken := func(cell Position) bool {
+		for i := range placed {
+			if placed[i].overlaps(cell) {
+				return true
+			}
+		}
+		return false
+	}
+
+	next := 0
+	for i := range positions {
+		if fixed[i] {
+			continue
+		}
+		for {
+			cell := Position{X: next % grid.Columns, Y: next / grid.Columns, Width: 1, Height: 1}
+			if grid.Rows > 0 && cell.Y >= grid.Rows {
+				return nil, fmt.Errorf("child %d: no free cell left in the %dx%d grid", i, grid.Columns, grid.Rows)
+			}
+			next++
+			if !taken(cell) {
+				placed[i] = cell
+				break
+			}
+		}
+	}
+
+	return placed, nil
+}
+
+// GridRows returns how many rows a grid shows for the given placements.
+func GridRows(grid GridConfig, placed []Position) int {
+	if grid.Rows > 0 {
+		return grid.Rows
+	}
+	rows := 0
+	for _, pos := range placed {
+		rows = max(rows, pos.Y+pos.Height)
+	}
+	return rows
+}
+
+func validateGrid(widget WidgetConfig) error {
+	grid, err := ParseGridConfig(widget.Config)
+	if err != nil {
+		return err
+	}
+
+	positions := make([]*Position, len(widget.Children))
+	for i, child := range widget.Children {
+		positions[i] = child.Position
+	}
+	_, err = PlaceGridCells(grid, positions)
+	return err
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaceGridCells(t *testing.T) {
+	tests := []struct {
+		name      string
+		grid      GridConfig
+		positions []*Position
+		expected  []Position
+		rows      int
+		errorMsg  string
+	}{
+		{
+			name: "fixed positions",
+			grid: GridConfig{Columns: 3, Rows: 2},
+			positions: []*Position{
+				{X: 0, Y: 0, Width: 2, Height: 1},
+				{X: 2, Y: 0, Width: 1, Height: 2},
+			},
+			expected: []Position{
+				{X: 0, Y: 0, Width: 2, Height: 1},
+				{X: 2, Y: 0, Width: 1, Height: 2},
+			},
+			rows: 2,
+		},
+		{
+			name: "auto placement skips taken cells",
+			grid: GridConfig{Columns: 2},
+			positions: []*Position{
+				nil,
+				{X: 1, Y: 0, Width: 1, Height: 2},
+				nil,
+				nil,
+			},
+			expected: []Position{
+				{X: 0, Y: 0, Width: 1, Height: 1},
+				{X: 1, Y: 0, Width: 1, Height: 2},
+				{X: 0, Y: 1, Width: 1, Height: 1},
+				{X: 0, Y: 2, Width: 1, Height: 1},
+			},
+			rows: 3,
+		},
+		{
+			name: "overlap",
+			grid: GridConfig{Columns: 3},
+			positions: []*Position{
+				{X: 0, Y: 0, Width: 2, Height: 2},
+				{X: 1, Y: 1, Width: 1, Height: 1},
+			},
+			errorMsg: "child 1 overlaps child 0",
+		},
+		{
+			name:      "too wide",
+			grid:      GridConfig{Columns: 3},
+			positions: []*Position{{X: 2, Y: 0, Width: 2, Height: 1}},
+			errorMsg:  "spans columns 2 to 3 but the grid has 3",
+		},
+		{
+			name:      "too tall",
+			grid:      GridConfig{Columns: 3, Rows: 1},
+			positions: []*Position{{X: 0, Y: 0, Width: 1, Height: 2}},
+			errorMsg:  "spans rows 0 to 1 but the grid has 1",
+		},
+		{
+			name:      "empty span",
+			grid:      GridConfig{Columns: 3},
+			positions: []*Position{{X: 0, Y: 0}},
+			errorMsg:  "positive width and height",
+		},
+		{
+			name:      "no room for auto placement",
+			grid:      GridConfig{Columns: 1, Rows: 1},
+			positions: []*Position{nil, nil},
+			errorMsg:  "child 1: no free cell left in the 1x1 grid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			placed, err := PlaceGridCells(tt.grid, tt.positions)
+			if tt.errorMsg != "" {
+				assert.ErrorContains(t, err, tt.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, placed)
+			assert.Equal(t, tt.rows, GridRows(tt.grid, placed))
+		})
+	}
+}
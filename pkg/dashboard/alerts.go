@@ -0,0 +1,240 @@
+package dashboard
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"time"
+
+	"gioui.org/io/event"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/mntndev/dash/pkg/events"
+	"github.com/mntndev/dash/pkg/widgets"
+)
+
+// defaultAlertSnooze is how long snoozing hides alerts that do not say.
+const defaultAlertSnooze = 30 * time.Minute
+
+// ErrAlertNotFound is returned for alerts that are not active.
+var ErrAlertNotFound = errors.New("alert not found")
+
+// alertState is an active alert and whether someone dealt with it.
+type alertState struct {
+	alert        events.Alert
+	acknowledged bool
+	snoozedUntil time.Time
+}
+
+// alertButtons are the clickables of the alert overlay.
+type alertButtons struct {
+	acknowledge widget.Clickable
+	snooze      widget.Clickable
+}
+
+// watchAlerts refreshes the alerts whenever one is published. The events
+// only say that something changed: a subscription that is full drops them,
+// so the widgets are asked for their alerts instead.
+func (ds *DashboardService) watchAlerts(sub *events.Subscription[events.Alert]) {
+	for range sub.C() {
+		ds.refreshAlerts()
+	}
+}
+
+// refreshAlerts collects the alerts of the widgets in the tree. An alert
+// whose condition changes is shown again even if it was acknowledged, the
+// alerts of widgets that left the tree end.
+func (ds *DashboardService) refreshAlerts() {
+	var raised []events.Alert
+	ds.mu.RLock()
+	if ds.widgetManager != nil {
+		for _, widget := range ds.widgetManager.GetAllWidgets() {
+			if source, ok := widget.(widgets.AlertSource); ok {
+				if alert := source.Alert(); alert.Active() {
+					raised = append(raised, alert)
+				}
+			}
+		}
+	}
+	ds.mu.RUnlock()
+
+	ds.alertsMu.Lock()
+	alerts := make(map[string]*alertState, len(raised))
+	for _, alert := range raised {
+		current, ok := ds.alerts[alert.ID]
+		if ok && current.alert.Condition == alert.Condition {
+			current.alert = alert
+		} else {
+			current = &alertState{alert: alert}
+		}
+		alerts[alert.ID] = current
+	}
+	ds.alerts = alerts
+	ds.alertsMu.Unlock()
+	ds.invalidate()
+}
+
+// GetAlerts returns the active alerts, urgent ones first, including
+// acknowledged and snoozed ones.
+func (ds *DashboardService) GetAlerts() []events.Alert {
+	ds.alertsMu.Lock()
+	defer ds.alertsMu.Unlock()
+
+	alerts := make([]events.Alert, 0, len(ds.alerts))
+	for _, state := range ds.alerts {
+		alerts = append(alerts, state.alert)
+	}
+	sortAlerts(alerts)
+	return alerts
+}
+
+// visibleAlert returns the alert the overlay shows at now, if any, and when
+// the next snooze ends.
+func (ds *DashboardService) visibleAlert(now time.Time) (events.Alert, bool, time.Time) {
+	ds.alertsMu.Lock()
+	defer ds.alertsMu.Unlock()
+
+	var visible []events.Alert
+	var wake time.Time
+	for _, state := range ds.alerts {
+		if state.acknowledged {
+			continue
+		}
+		if now.Before(state.snoozedUntil) {
+			if wake.IsZero() || state.snoozedUntil.Before(wake) {
+				wake = state.snoozedUntil
+			}
+			continue
+		}
+		visible = append(visible, state.alert)
+	}
+	if len(visible) == 0 {
+		return events.Alert{}, false, wake
+	}
+	sortAlerts(visible)
+	return visible[0], true, wake
+}
+
+// AcknowledgeAlert hides an alert until its condition changes.
+func (ds *DashboardService) AcknowledgeAlert(id string) error {
+	return ds.dismissAlert(id, false)
+}
+
+// SnoozeAlert hides an alert for its snooze time. It comes back if the
+// condition still holds by then.
+func (ds *DashboardService) SnoozeAlert(id string) error {
+	return ds.dismissAlert(id, true)
+}
+
+func (ds *DashboardService) dismissAlert(id string, snooze bool) error {
+	ds.alertsMu.Lock()
+	state, ok := ds.alerts[id]
+	if !ok {
+		ds.alertsMu.Unlock()
+		return fmt.Errorf("alert %q: %w", id, ErrAlertNotFound)
+	}
+
+	var until time.Time
+	if snooze {
+		duration := state.alert.Snooze
+		if duration <= 0 {
+			duration = defaultAlertSnooze
+		}
+		until = time.Now().Add(duration)
+		state.snoozedUntil = until
+	} else {
+		state.acknowledged = true
+	}
+	ds.alertsMu.Unlock()
+
+	ds.bus.Publish(events.AlertAcknowledged{ID: id, Until: until})
+	ds.invalidate()
+	return nil
+}
+
+func sortAlerts(alerts []events.Alert) {
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Urgent != alerts[j].Urgent {
+			return alerts[i].Urgent
+		}
+		return alerts[i].ID < alerts[j].ID
+	})
+}
+
+// layoutAlert covers the screen with alert and buttons to acknowledge or
+// snooze it. Input does not reach the dashboard below.
+func (ds *DashboardService) layoutAlert(gtx layout.Context, th *material.Theme, alert events.Alert) layout.Dimensions {
+	if ds.alertButtons.acknowledge.Clicked(gtx) {
+		_ = ds.AcknowledgeAlert(alert.ID)
+	}
+	if ds.alertButtons.snooze.Clicked(gtx) {
+		_ = ds.SnoozeAlert(alert.ID)
+	}
+
+	size := gtx.Constraints.Max
+	defer clip.Rect{Max: size}.Push(gtx.Ops).Pop()
+	for {
+		if _, ok := gtx.Event(pointer.Filter{Target: &ds.alertButtons, Kinds: pointer.Press | pointer.Release}); !ok {
+			break
+		}
+	}
+	event.Op(gtx.Ops, &ds.alertButtons)
+
+	bg := color.NRGBA{R: 0xc0, G: 0x80, B: 0x00, A: 0xf0}
+	if alert.Urgent {
+		bg = color.NRGBA{R: 0xb0, G: 0x20, B: 0x20, A: 0xf0}
+	}
+	paint.Fill(gtx.Ops, bg)
+
+	white := color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	gtx.Constraints.Min = image.Point{}
+	return layout.Center.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical, Alignment: layout.Middle}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				message := material.H3(th, alert.Message)
+				message.Color = white
+				message.Alignment = text.Middle
+				return message.Layout(gtx)
+			}),
+			layout.Rigid(layout.Spacer{Height: unit.Dp(24)}.Layout),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{}.Layout(gtx,
+					layout.Rigid(material.Button(th, &ds.alertButtons.acknowledge, "Acknowledge").Layout),
+					layout.Rigid(layout.Spacer{Width: unit.Dp(16)}.Layout),
+					layout.Rigid(material.Button(th, &ds.alertButtons.snooze, "Snooze").Layout),
+				)
+			}),
+		)
+	})
+}
+
+// layoutAlertOverlay draws the visible alert over screen and redraws when a
+// snooze ends.
+func (ds *DashboardService) layoutAlertOverlay(gtx layout.Context, th *material.Theme, screen layout.Widget) layout.Dimensions {
+	now := gtx.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	alert, ok, wake := ds.visibleAlert(now)
+	if !wake.IsZero() {
+		gtx.Execute(op.InvalidateCmd{At: wake})
+	}
+	if !ok {
+		return screen(gtx)
+	}
+
+	return layout.Stack{}.Layout(gtx,
+		layout.Expanded(screen),
+		layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+			return ds.layoutAlert(gtx, th, alert)
+		}))
+}
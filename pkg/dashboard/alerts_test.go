@@ -0,0 +1,127 @@
+package dashboard
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mntndev/dash/pkg/events"
+	"github.com/mntndev/dash/pkg/widgets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// alertWidget raises whatever alert a test sets.
+type alertWidget struct {
+	*widgets.BaseWidget
+	mu    sync.Mutex
+	alert events.Alert
+}
+
+func (w *alertWidget) Alert() events.Alert {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.alert
+}
+
+func (w *alertWidget) set(alert events.Alert) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.alert = alert
+}
+
+// addAlertWidget puts a widget that raises alerts into the running tree.
+func addAlertWidget(ds *DashboardService, id string) *alertWidget {
+	widget := &alertWidget{BaseWidget: &widgets.BaseWidget{ID: id, Type: "alerting"}, alert: events.Alert{ID: id}}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.widgetManager.StoreWidget(id, widget)
+	return widget
+}
+
+func TestAlerts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, reloadConfigBefore)
+
+	ds := newDashboardService(nil, path)
+	require.NoError(t, ds.Initialize())
+	defer ds.Close()
+
+	acks := events.Subscribe[events.AlertAcknowledged](ds.Events(), 8)
+	visible := func(now time.Time) string {
+		alert, ok, _ := ds.visibleAlert(now)
+		if !ok {
+			return ""
+		}
+		return alert.ID + ":" + alert.Condition
+	}
+	sources := map[string]*alertWidget{
+		"glucose": addAlertWidget(ds, "glucose"),
+		"other":   addAlertWidget(ds, "other"),
+	}
+	// raise has a widget raise alert and waits for the dashboard to pick it
+	// up
+	raise := func(alert events.Alert) {
+		sources[alert.ID].set(alert)
+		ds.Events().Publish(alert)
+		require.Eventually(t, func() bool {
+			for _, active := range ds.GetAlerts() {
+				if active.ID == alert.ID {
+					return active == alert
+				}
+			}
+			return !alert.Active()
+		}, time.Second, time.Millisecond)
+	}
+
+	raise(events.Alert{ID: "glucose", Condition: "high", Message: "High: 260 mg/dL"})
+	raise(events.Alert{ID: "other", Condition: "urgent_low", Urgent: true, Message: "Urgent low"})
+	assert.Equal(t, "other:urgent_low", visible(time.Now()), "urgent alerts come first")
+
+	require.NoError(t, ds.AcknowledgeAlert("other"))
+	assert.Equal(t, "glucose:high", visible(time.Now()))
+	assert.Equal(t, events.AlertAcknowledged{ID: "other"}, <-acks.C())
+
+	t.Run("acknowledged alerts come back when the condition changes", func(t *testing.T) {
+		raise(events.Alert{ID: "other", Condition: "urgent_low", Urgent: true, Message: "Still urgent low"})
+		assert.Equal(t, "glucose:high", visible(time.Now()))
+
+		raise(events.Alert{ID: "other", Condition: "low", Message: "Low"})
+		assert.Equal(t, "glucose:high", visible(time.Now()), "sorted by ID among equals")
+		require.NoError(t, ds.AcknowledgeAlert("other"))
+		<-acks.C()
+	})
+
+	t.Run("snoozed alerts come back after the snooze", func(t *testing.T) {
+		raise(events.Alert{ID: "glucose", Condition: "high", Message: "High: 270 mg/dL", Snooze: time.Hour})
+		require.NoError(t, ds.SnoozeAlert("glucose"))
+		ack := <-acks.C()
+		assert.Equal(t, "glucose", ack.ID)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), ack.Until, time.Minute)
+
+		_, ok, wake := ds.visibleAlert(time.Now())
+		assert.False(t, ok)
+		assert.Equal(t, ack.Until, wake)
+		assert.Equal(t, "glucose:high", visible(time.Now().Add(2*time.Hour)))
+	})
+
+	t.Run("ended alerts are removed", func(t *testing.T) {
+		raise(events.Alert{ID: "glucose"})
+		raise(events.Alert{ID: "other"})
+		assert.Empty(t, ds.GetAlerts())
+		assert.ErrorIs(t, ds.AcknowledgeAlert("glucose"), ErrAlertNotFound)
+	})
+
+	t.Run("alerts whose event was dropped show with the next one", func(t *testing.T) {
+		sources["glucose"].set(events.Alert{ID: "glucose", Condition: "low", Message: "Low"})
+		raise(events.Alert{ID: "other", Condition: "high", Message: "High"})
+		assert.Equal(t, "glucose:low", visible(time.Now()))
+	})
+
+	t.Run("alerts of widgets that left the tree end", func(t *testing.T) {
+		ds.reloadConfig()
+		require.NoError(t, ds.GetConfigError())
+		assert.Empty(t, ds.GetAlerts())
+	})
+}
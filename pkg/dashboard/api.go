@@ -0,0 +1,372 @@
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mntndev/dash/pkg/events"
+	"github.com/mntndev/dash/pkg/render"
+	"github.com/mntndev/dash/pkg/widgets"
+)
+
+// apiShutdownTimeout is how long Close waits for API requests to finish.
+const apiShutdownTimeout = 5 * time.Second
+
+// defaultScreenshotSize is the size of screenshots that do not ask for one,
+// the same as dash render.
+var defaultScreenshotSize = image.Pt(800, 480)
+
+// WidgetNode describes one widget of the running tree for the API.
+type WidgetNode struct {
+	ID          string       `json:"id"`
+	Type        string       `json:"type"`
+	Triggerable bool         `json:"triggerable,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	Children    []WidgetNode `json:"children,omitempty"`
+}
+
+// PagesInfo lists the pages of the dashboard and the one on screen.
+type PagesInfo struct {
+	Pages   []widgets.PageInfo `json:"pages"`
+	Current int                `json:"current"`
+}
+
+// pageRequest selects a page by index or by name.
+type pageRequest struct {
+	Index *int   `json:"index,omitempty"`
+	Name  string `json:"name,omitempty"`
+}
+
+// startAPI serves the HTTP API if the config enables it. A failure is shown
+// in the status overlay, the dashboard runs without the API. The caller must
+// hold mu.
+func (ds *DashboardService) startAPI() {
+	apiConfig := ds.config.Dashboard.API
+	if apiConfig == nil {
+		return
+	}
+
+	listener, err := net.Listen("tcp", apiConfig.Listen)
+	if err != nil {
+		log.Printf("Failed to start API: %v", err)
+		ds.apiErr = err
+		return
+	}
+
+	ds.api = &http.Server{
+		// The actual address, in case the config asks for a random port
+		Addr:              listener.Addr().String(),
+		Handler:           ds.apiHandler(apiConfig.Token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("API listening on %s", ds.api.Addr)
+
+	go func(server *http.Server) {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("API server stopped: %v", err)
+		}
+	}(ds.api)
+}
+
+func (ds *DashboardService) stopAPI() {
+	ds.mu.Lock()
+	server := ds.api
+	ds.api = nil
+	ds.mu.Unlock()
+
+	if server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), apiShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Failed to stop API: %v", err)
+	}
+}
+
+// apiHandler returns the routes of the HTTP API. Every request needs the
+// token as a bearer token unless it is empty.
+func (ds *DashboardService) apiHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/info", ds.handleInfo)
+	mux.HandleFunc("GET /api/widgets", ds.handleWidgets)
+	mux.HandleFunc("GET /api/widgets/{id}", ds.handleWidget)
+	mux.HandleFunc("POST /api/widgets/{id}/trigger", ds.handleTrigger)
+	mux.HandleFunc("GET /api/pages", ds.handlePages)
+	mux.HandleFunc("PUT /api/pages/current", ds.handleSetPage)
+	mux.HandleFunc("POST /api/reload", ds.handleReload)
+	mux.HandleFunc("GET /api/events", ds.handleEvents)
+	mux.HandleFunc("GET /api/alerts", ds.handleAlerts)
+	mux.HandleFunc("POST /api/alerts/{id}/acknowledge", ds.handleDismissAlert(ds.AcknowledgeAlert))
+	mux.HandleFunc("POST /api/alerts/{id}/snooze", ds.handleDismissAlert(ds.SnoozeAlert))
+	mux.HandleFunc("GET /api/screenshot", ds.handleScreenshot)
+
+	if token == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("invalid or missing token"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (ds *DashboardService) handleInfo(w http.ResponseWriter, r *http.Request) {
+	ds.mu.RLock()
+	info := ds.getDashboardInfo()
+	ds.mu.RUnlock()
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (ds *DashboardService) handleWidgets(w http.ResponseWriter, r *http.Request) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	if ds.rootWidget == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("dashboard is not initialized"))
+		return
+	}
+	writeJSON(w, http.StatusOK, widgetTree(ds.rootWidget))
+}
+
+func (ds *DashboardService) handleWidget(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	widget, exists := ds.GetWidget(id)
+	if !exists {
+		writeError(w, http.StatusNotFound, fmt.Errorf("widget %s: %w", id, ErrWidgetNotFound))
+		return
+	}
+
+	data := WidgetData{ID: widget.GetID(), Type: widget.GetType()}
+	if provider, ok := widget.(widgets.DataProvider); ok {
+		data.Data = provider.GetData()
+	}
+	if updater, ok := widget.(widgets.LastUpdater); ok {
+		data.LastUpdate = updater.GetLastUpdate()
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+func (ds *DashboardService) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	err := ds.TriggerWidget(r.PathValue("id"))
+	switch {
+	case errors.Is(err, ErrWidgetNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrNotTriggerable):
+		writeError(w, http.StatusBadRequest, err)
+	case err != nil:
+		// The widget's service call failed
+		writeError(w, http.StatusBadGateway, err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (ds *DashboardService) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, ds.GetAlerts())
+}
+
+// handleDismissAlert acknowledges or snoozes the alert in the path.
+func (ds *DashboardService) handleDismissAlert(dismiss func(id string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := dismiss(r.PathValue("id")); err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleScreenshot renders the dashboard as a PNG. ?size= and ?scale= work
+// like the flags of dash render.
+func (ds *DashboardService) handleScreenshot(w http.ResponseWriter, r *http.Request) {
+	opts := render.Options{Size: defaultScreenshotSize, Scale: 1}
+	if size := r.URL.Query().Get("size"); size != "" {
+		parsed, err := render.ParseSize(size)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		opts.Size = parsed
+	}
+	if scale := r.URL.Query().Get("scale"); scale != "" {
+		parsed, err := strconv.ParseFloat(scale, 32)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid scale %q", scale))
+			return
+		}
+		opts.Scale = float32(parsed)
+	}
+
+	img, err := ds.screenshot(r.Context(), opts)
+	switch {
+	case errors.Is(err, render.ErrUnavailable), errors.Is(err, errNoFrame):
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to encode screenshot: %w", err))
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		log.Printf("Failed to write screenshot: %v", err)
+	}
+}
+
+func (ds *DashboardService) handlePages(w http.ResponseWriter, r *http.Request) {
+	pages := ds.GetPages()
+	if pages == nil {
+		writeError(w, http.StatusNotFound, errors.New("dashboard has no pages"))
+		return
+	}
+	writeJSON(w, http.StatusOK, PagesInfo{Pages: pages, Current: ds.CurrentPage()})
+}
+
+func (ds *DashboardService) handleSetPage(w http.ResponseWriter, r *http.Request) {
+	var request pageRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+
+	pages := ds.GetPages()
+	if pages == nil {
+		writeError(w, http.StatusNotFound, errors.New("dashboard has no pages"))
+		return
+	}
+
+	index := -1
+	switch {
+	case request.Index != nil:
+		index = *request.Index
+	case request.Name != "":
+		for i, page := range pages {
+			if page.Name == request.Name {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			writeError(w, http.StatusNotFound, fmt.Errorf("page %q not found", request.Name))
+			return
+		}
+	default:
+		writeError(w, http.StatusBadRequest, errors.New("index or name is required"))
+		return
+	}
+
+	if err := ds.SetPage(index); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, PagesInfo{Pages: pages, Current: ds.CurrentPage()})
+}
+
+func (ds *DashboardService) handleReload(w http.ResponseWriter, r *http.Request) {
+	ds.reloadConfig()
+	if err := ds.GetConfigError(); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	ds.handleInfo(w, r)
+}
+
+// handleEvents streams the events of the bus as server-sent events until the
+// client goes away or the dashboard closes. Repeat ?topic= to only receive
+// some topics.
+func (ds *DashboardService) handleEvents(w http.ResponseWriter, r *http.Request) {
+	topics := make(map[events.Topic]bool)
+	for _, topic := range r.URL.Query()["topic"] {
+		topics[events.Topic(topic)] = true
+	}
+
+	sub := events.SubscribeAll(ds.bus, events.DefaultBuffer)
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		log.Printf("Failed to stream API events: %v", err)
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ds.ctx.Done():
+			return
+		case event, ok := <-sub.C():
+			if !ok {
+				return
+			}
+			if len(topics) > 0 && !topics[event.Topic()] {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("Failed to encode %s event: %v", event.Topic(), err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Topic(), data); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// widgetTree describes widget and its children. The caller must hold mu so
+// a reload does not swap the children meanwhile.
+func widgetTree(widget widgets.Widget) WidgetNode {
+	node := WidgetNode{ID: widget.GetID(), Type: widget.GetType()}
+	_, node.Triggerable = widget.(widgets.Triggerable)
+	if failed, ok := widget.(*widgets.ErrorWidget); ok {
+		node.Error = failed.Err().Err.Error()
+	} else if err, ok := widgets.LayoutError(widget); ok {
+		node.Error = err.Err.Error()
+	}
+
+	for _, child := range widget.GetChildren() {
+		node.Children = append(node.Children, widgetTree(child))
+	}
+	return node
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write API response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,244 @@
+package dashboard
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/unit"
+	"github.com/mntndev/dash/pkg/events"
+	"github.com/mntndev/dash/pkg/render"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const apiConfig = `dashboard:
+  title: "API Test"
+  pages:
+    - name: "Lighting"
+      widget:
+        type: "hstack"
+        children:
+          - type: "clock"
+            id: "clock"
+          - type: "does_not_exist"
+    - name: "Climate"
+      widget:
+        type: "clock"
+`
+
+// apiRequest sends a request to handler and decodes the JSON response into
+// out if it is not nil.
+func apiRequest(t *testing.T, handler http.Handler, method, path, body string, out interface{}) int {
+	t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if out != nil {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), out), w.Body.String())
+	}
+	return w.Code
+}
+
+func TestAPI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, apiConfig)
+
+	ds := newDashboardService(nil, path)
+	require.NoError(t, ds.Initialize())
+	defer ds.Close()
+
+	handler := ds.apiHandler("secret")
+
+	t.Run("token is required", func(t *testing.T) {
+		for _, auth := range []string{"", "Bearer wrong", "secret"} {
+			r := httptest.NewRequest(http.MethodGet, "/api/info", nil)
+			r.Header.Set("Authorization", auth)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, http.StatusUnauthorized, w.Code, "authorization %q", auth)
+		}
+	})
+
+	t.Run("info", func(t *testing.T) {
+		var info DashboardInfo
+		assert.Equal(t, http.StatusOK, apiRequest(t, handler, http.MethodGet, "/api/info", "", &info))
+		assert.Equal(t, "API Test", info.Title)
+	})
+
+	t.Run("widget tree", func(t *testing.T) {
+		var root WidgetNode
+		assert.Equal(t, http.StatusOK, apiRequest(t, handler, http.MethodGet, "/api/widgets", "", &root))
+		assert.Equal(t, "pages", root.Type)
+		require.Len(t, root.Children, 2)
+
+		lighting := root.Children[0]
+		require.Len(t, lighting.Children, 2)
+		assert.Equal(t, "clock", lighting.Children[0].ID)
+		assert.Contains(t, lighting.Children[1].Error, "unsupported widget type")
+	})
+
+	t.Run("widget data", func(t *testing.T) {
+		var data struct {
+			WidgetData
+			Data map[string]interface{} `json:"data"`
+		}
+		assert.Equal(t, http.StatusOK, apiRequest(t, handler, http.MethodGet, "/api/widgets/clock", "", &data))
+		assert.Equal(t, "clock", data.Type)
+		assert.NotEmpty(t, data.Data["display"])
+		assert.False(t, data.LastUpdate.IsZero())
+
+		assert.Equal(t, http.StatusNotFound, apiRequest(t, handler, http.MethodGet, "/api/widgets/missing", "", nil))
+	})
+
+	t.Run("trigger", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, apiRequest(t, handler, http.MethodPost, "/api/widgets/clock/trigger", "", nil))
+		assert.Equal(t, http.StatusNotFound, apiRequest(t, handler, http.MethodPost, "/api/widgets/missing/trigger", "", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, apiRequest(t, handler, http.MethodGet, "/api/widgets/clock/trigger", "", nil))
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		var pages PagesInfo
+		assert.Equal(t, http.StatusOK, apiRequest(t, handler, http.MethodGet, "/api/pages", "", &pages))
+		assert.Equal(t, 0, pages.Current)
+		require.Len(t, pages.Pages, 2)
+
+		assert.Equal(t, http.StatusOK, apiRequest(t, handler, http.MethodPut, "/api/pages/current", `{"name": "Climate"}`, &pages))
+		assert.Equal(t, 1, pages.Current)
+		assert.Equal(t, 1, ds.CurrentPage())
+
+		assert.Equal(t, http.StatusOK, apiRequest(t, handler, http.MethodPut, "/api/pages/current", `{"index": 0}`, &pages))
+		assert.Equal(t, 0, ds.CurrentPage())
+
+		assert.Equal(t, http.StatusBadRequest, apiRequest(t, handler, http.MethodPut, "/api/pages/current", `{"index": 5}`, nil))
+		assert.Equal(t, http.StatusNotFound, apiRequest(t, handler, http.MethodPut, "/api/pages/current", `{"name": "Garage"}`, nil))
+		assert.Equal(t, http.StatusBadRequest, apiRequest(t, handler, http.MethodPut, "/api/pages/current", `{}`, nil))
+	})
+
+	t.Run("alerts", func(t *testing.T) {
+		alert := events.Alert{ID: "glucose", Condition: "low", Message: "Low: 65 mg/dL"}
+		addAlertWidget(ds, "glucose").set(alert)
+		ds.Events().Publish(alert)
+		var alerts []events.Alert
+		require.Eventually(t, func() bool {
+			return apiRequest(t, handler, http.MethodGet, "/api/alerts", "", &alerts) == http.StatusOK && len(alerts) == 1
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, "low", alerts[0].Condition)
+
+		assert.Equal(t, http.StatusNoContent, apiRequest(t, handler, http.MethodPost, "/api/alerts/glucose/snooze", "", nil))
+		assert.Equal(t, http.StatusNoContent, apiRequest(t, handler, http.MethodPost, "/api/alerts/glucose/acknowledge", "", nil))
+		_, visible, _ := ds.visibleAlert(time.Now().Add(time.Hour))
+		assert.False(t, visible)
+		assert.Equal(t, http.StatusNotFound, apiRequest(t, handler, http.MethodPost, "/api/alerts/missing/acknowledge", "", nil))
+	})
+
+	t.Run("screenshot", func(t *testing.T) {
+		var body map[string]string
+		assert.Equal(t, http.StatusBadRequest, apiRequest(t, handler, http.MethodGet, "/api/screenshot?size=800", "", &body))
+		assert.Equal(t, http.StatusBadRequest, apiRequest(t, handler, http.MethodGet, "/api/screenshot?scale=0", "", &body))
+
+		r := httptest.NewRequest(http.MethodGet, "/api/screenshot?size=200x100&scale=2", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code == http.StatusServiceUnavailable {
+			t.Skip(w.Body.String())
+		}
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		img, err := png.Decode(w.Body)
+		require.NoError(t, err)
+		assert.Equal(t, image.Pt(200, 100), img.Bounds().Size())
+	})
+
+	t.Run("screenshot with a window renders with the next frame", func(t *testing.T) {
+		req := screenshotRequest{opts: render.Options{Size: image.Pt(40, 30)}, reply: make(chan screenshotResult, 1)}
+		ds.screenshots <- req
+		var ops op.Ops
+		ds.Layout(layout.Context{
+			Ops:         &ops,
+			Metric:      unit.Metric{PxPerDp: 1, PxPerSp: 1},
+			Constraints: layout.Exact(image.Pt(400, 300)),
+			Now:         time.Now(),
+		})
+		require.Len(t, req.reply, 1)
+		result := <-req.reply
+		if errors.Is(result.err, render.ErrUnavailable) {
+			t.Skip(result.err)
+		}
+		require.NoError(t, result.err)
+		assert.Equal(t, image.Pt(40, 30), result.img.Bounds().Size())
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		writeConfig(t, path, strings.Replace(apiConfig, "API Test", "Reloaded", 1))
+		var info DashboardInfo
+		assert.Equal(t, http.StatusOK, apiRequest(t, handler, http.MethodPost, "/api/reload", "", &info))
+		assert.Equal(t, "Reloaded", info.Title)
+
+		writeConfig(t, path, "dashboard: [unclosed")
+		var body map[string]string
+		assert.Equal(t, http.StatusUnprocessableEntity, apiRequest(t, handler, http.MethodPost, "/api/reload", "", &body))
+		assert.NotEmpty(t, body["error"])
+	})
+}
+
+func TestAPIServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, `dashboard:
+  title: "API Test"
+  api:
+    listen: "127.0.0.1:0"
+  widget:
+    type: "clock"
+`)
+
+	ds := newDashboardService(nil, path)
+	require.NoError(t, ds.Initialize())
+	defer ds.Close()
+
+	ds.mu.RLock()
+	require.NotNil(t, ds.api)
+	url := "http://" + ds.api.Addr + "/api/info"
+	ds.mu.RUnlock()
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), `"title":"API Test"`)
+
+	t.Run("events are streamed", func(t *testing.T) {
+		resp, err := http.Get(strings.TrimSuffix(url, "info") + "events?topic=config_reloaded")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		ds.Events().Publish(events.PageChanged{Index: 1})
+		ds.reloadConfig()
+
+		reader := bufio.NewReader(resp.Body)
+		var lines []string
+		for range 2 {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			lines = append(lines, strings.TrimSpace(line))
+		}
+		assert.Equal(t, []string{"event: config_reloaded", `data: {"title":"API Test"}`}, lines)
+	})
+}
@@ -0,0 +1,46 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/mntndev/dash/pkg/widgets"
+)
+
+// LoadFixtures shows the widget data in the JSON file at path instead of
+// what the integrations report. The file maps widget IDs to data in the
+// shape GET /api/widgets/{id} returns it under "data".
+func (ds *DashboardService) LoadFixtures(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read fixtures: %w", err)
+	}
+
+	var fixtures map[string]json.RawMessage
+	if err := json.Unmarshal(content, &fixtures); err != nil {
+		return fmt.Errorf("failed to parse fixtures %s: %w", path, err)
+	}
+
+	ids := make([]string, 0, len(fixtures))
+	for id := range fixtures {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		widget, exists := ds.GetWidget(id)
+		if !exists {
+			return fmt.Errorf("fixture for widget %s: %w", id, ErrWidgetNotFound)
+		}
+		loader, ok := widget.(widgets.FixtureLoader)
+		if !ok {
+			return fmt.Errorf("fixture for widget %s: %s widgets do not take fixtures", id, widget.GetType())
+		}
+		if err := loader.LoadFixture(fixtures[id]); err != nil {
+			return fmt.Errorf("fixture for widget %s: %w", id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package dashboard
+
+import (
+	"image/color"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"github.com/mntndev/dash/pkg/widgets"
+)
+
+// Layout draws the whole screen: the widget tree on the theme background
+// with the status overlay and any alert on top. Screenshots asked for over
+// the API are rendered along with it.
+func (ds *DashboardService) Layout(gtx layout.Context) layout.Dimensions {
+	dims := ds.draw(gtx)
+	ds.takeScreenshots()
+	return dims
+}
+
+func (ds *DashboardService) draw(gtx layout.Context) layout.Dimensions {
+	ds.treeMu.RLock()
+	defer ds.treeMu.RUnlock()
+
+	th := ds.GetTheme()
+	return ds.layoutAlertOverlay(gtx, th, ds.layoutScreen)
+}
+
+// layoutScreen draws the widget tree and the status overlay.
+func (ds *DashboardService) layoutScreen(gtx layout.Context) layout.Dimensions {
+	th := ds.GetTheme()
+	root := ds.GetRootWidget()
+
+	rootWidget := func(gtx layout.Context) layout.Dimensions {
+		// A root widget that panics is replaced by an error tile
+		return widgets.LayoutWidget(gtx, root)
+	}
+
+	if root == nil {
+		// Show loading or error state
+		title := material.H3(th, "Loading Dashboard...")
+		title.Alignment = text.Middle
+		rootWidget = title.Layout
+	}
+
+	content := func(gtx layout.Context) layout.Dimensions {
+		return layout.Background{}.Layout(gtx,
+			func(gtx layout.Context) layout.Dimensions {
+				defer clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops).Pop()
+				paint.Fill(gtx.Ops, th.Bg)
+				return layout.Dimensions{Size: gtx.Constraints.Min}
+			}, func(gtx layout.Context) layout.Dimensions {
+				return rootWidget(gtx)
+			})
+	}
+
+	messages := ds.GetStatusMessages()
+	if len(messages) == 0 {
+		return content(gtx)
+	}
+
+	// Keep the running dashboard and show the status overlay on top of it
+	return layout.Stack{}.Layout(gtx,
+		layout.Expanded(content),
+		layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+			banners := make([]layout.FlexChild, 0, len(messages))
+			for _, message := range messages {
+				banners = append(banners, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return layoutBanner(gtx, th, message)
+				}))
+			}
+			return layout.Flex{Axis: layout.Vertical}.Layout(gtx, banners...)
+		}))
+}
+
+// layoutBanner draws a full-width message strip at the top of the screen.
+func layoutBanner(gtx layout.Context, th *material.Theme, message StatusMessage) layout.Dimensions {
+	bg := color.NRGBA{R: 0xb0, G: 0x20, B: 0x20, A: 0xff}
+	if message.Level == StatusWarning {
+		bg = color.NRGBA{R: 0xc0, G: 0x80, B: 0x00, A: 0xff}
+	}
+
+	gtx.Constraints.Min.X = gtx.Constraints.Max.X
+	return layout.Background{}.Layout(gtx,
+		func(gtx layout.Context) layout.Dimensions {
+			defer clip.Rect{Max: gtx.Constraints.Min}.Push(gtx.Ops).Pop()
+			paint.Fill(gtx.Ops, bg)
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		}, func(gtx layout.Context) layout.Dimensions {
+			return layout.UniformInset(unit.Dp(8)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				label := material.Body1(th, message.Text)
+				label.Color = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+				return label.Layout(gtx)
+			})
+		})
+}
@@ -0,0 +1,58 @@
+package dashboard
+
+import (
+	"fmt"
+
+	"github.com/mntndev/dash/pkg/widgets"
+)
+
+// pagesWidget returns the root widget if the dashboard has pages.
+func (ds *DashboardService) pagesWidget() (*widgets.PagesWidget, bool) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	pages, ok := ds.rootWidget.(*widgets.PagesWidget)
+	return pages, ok
+}
+
+// GetPages returns the name and icon of every page, or nil if the dashboard
+// has a single root widget.
+func (ds *DashboardService) GetPages() []widgets.PageInfo {
+	pages, ok := ds.pagesWidget()
+	if !ok {
+		return nil
+	}
+	return pages.Pages()
+}
+
+// CurrentPage returns the index of the page on screen. Dashboards without
+// pages are always on page 0.
+func (ds *DashboardService) CurrentPage() int {
+	pages, ok := ds.pagesWidget()
+	if !ok {
+		return 0
+	}
+	return pages.CurrentPage()
+}
+
+// SetPage switches the dashboard to the page at index.
+func (ds *DashboardService) SetPage(index int) error {
+	pages, ok := ds.pagesWidget()
+	if !ok {
+		return fmt.Errorf("dashboard has no pages")
+	}
+	return pages.SetPage(index)
+}
+
+// keepPage shows the same page after a reload rebuilt the pages widget, or
+// the last one if the dashboard has fewer pages now.
+func keepPage(previous, current widgets.Widget) {
+	before, ok := previous.(*widgets.PagesWidget)
+	if !ok {
+		return
+	}
+	after, ok := current.(*widgets.PagesWidget)
+	if !ok || after == before {
+		return
+	}
+	_ = after.SetPage(min(before.CurrentPage(), len(after.Pages())-1))
+}
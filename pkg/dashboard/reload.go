@@ -0,0 +1,377 @@
+package dashboard
+
+import (
+	"context"
+	"log"
+	"os"
+	"reflect"
+	"time"
+
+	"gioui.org/widget/material"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/mntndev/dash/pkg/config"
+	"github.com/mntndev/dash/pkg/events"
+	"github.com/mntndev/dash/pkg/widgets"
+)
+
+// configPollInterval is how often the config file is checked for changes.
+// Polling keeps working when editors replace the file instead of writing it.
+const configPollInterval = time.Second
+
+// configStamp identifies one version of the config file on disk.
+type configStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statConfig(path string) (configStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return configStamp{}, err
+	}
+	return configStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// widgetSnapshot captures the running widget tree so a reload can reuse
+// unchanged widgets and close the rest.
+type widgetSnapshot struct {
+	manager *widgets.WidgetManager
+	configs map[string]config.WidgetConfig
+	cancels map[string]context.CancelFunc
+}
+
+type createdWidget struct {
+	widget widgets.Widget
+	cancel context.CancelFunc
+}
+
+// treeBuild collects a new widget tree and the side effects of building it
+// so they can be applied once the whole tree was built, or rolled back if it
+// failed. It is built without holding the service lock and installed at once.
+type treeBuild struct {
+	config  *config.Config
+	theme   *material.Theme
+	manager *widgets.WidgetManager
+	configs map[string]config.WidgetConfig
+	cancels map[string]context.CancelFunc
+	root    widgets.Widget
+	// children holds the new children of every widget in the tree, which
+	// differ from the current ones for reused containers until install.
+	children map[string][]widgets.Widget
+
+	previous     *widgetSnapshot
+	allowReuse   bool
+	reused       map[string]bool
+	created      []createdWidget
+	childUpdates []func()
+}
+
+func newTreeBuild(cfg *config.Config, theme *material.Theme, factory widgets.WidgetFactory, previous *widgetSnapshot) *treeBuild {
+	return &treeBuild{
+		config:     cfg,
+		theme:      theme,
+		manager:    widgets.NewWidgetManager(factory),
+		configs:    make(map[string]config.WidgetConfig),
+		cancels:    make(map[string]context.CancelFunc),
+		children:   make(map[string][]widgets.Widget),
+		previous:   previous,
+		allowReuse: previous != nil,
+		reused:     make(map[string]bool),
+	}
+}
+
+// reuse returns the running widget for id if its type and config are
+// unchanged. Containers are kept as well, their new children are attached
+// when the tree is installed.
+func (b *treeBuild) reuse(id string, widgetConfig config.WidgetConfig, children []widgets.Widget) (widgets.Widget, bool) {
+	if !b.allowReuse {
+		return nil, false
+	}
+
+	widget, exists := b.previous.manager.GetWidget(id)
+	if !exists || !sameWidgetConfig(b.previous.configs[id], widgetConfig) {
+		return nil, false
+	}
+
+	// Failed widgets get another try
+	if _, failed := widget.(*widgets.ErrorWidget); failed {
+		return nil, false
+	}
+	if _, failed := widgets.LayoutError(widget); failed {
+		return nil, false
+	}
+
+	// Grids place their children when created, moving one means a new grid
+	if _, ok := widget.(widgets.Placer); ok && !samePositions(b.previous.configs[id].Children, widgetConfig.Children) {
+		return nil, false
+	}
+
+	if len(children) > 0 || len(widget.GetChildren()) > 0 {
+		container, ok := widget.(widgets.Container)
+		if !ok {
+			return nil, false
+		}
+		if !sameWidgets(widget.GetChildren(), children) {
+			b.childUpdates = append(b.childUpdates, func() {
+				container.SetChildren(children)
+			})
+		}
+	}
+
+	b.reused[id] = true
+	return widget, true
+}
+
+// commit closes every widget of the previous tree that did not make it into
+// the new one.
+func (b *treeBuild) commit() {
+	if b.previous == nil {
+		return
+	}
+
+	for id, widget := range b.previous.manager.GetAllWidgets() {
+		if b.reused[id] {
+			continue
+		}
+		if cancel := b.previous.cancels[id]; cancel != nil {
+			cancel()
+		}
+		closeWidget(id, widget)
+	}
+}
+
+// drop removes children of a failed container from the tree. Widgets
+// created for them are stopped and closed now, reused ones are closed on
+// commit like every other widget that left the tree.
+func (b *treeBuild) drop(children []widgets.Widget) {
+	for _, child := range children {
+		id := child.GetID()
+		b.drop(b.children[id])
+
+		b.manager.ForgetWidget(id)
+		delete(b.configs, id)
+		delete(b.cancels, id)
+		delete(b.children, id)
+		if b.reused[id] {
+			delete(b.reused, id)
+			continue
+		}
+		for i, created := range b.created {
+			if created.widget == child {
+				created.cancel()
+				closeWidget(id, child)
+				b.created = append(b.created[:i], b.created[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// discard closes every widget created during a failed build. The previous
+// tree is left untouched.
+func (b *treeBuild) discard() {
+	for _, created := range b.created {
+		created.cancel()
+		closeWidget(created.widget.GetID(), created.widget)
+	}
+}
+
+// closeWidget closes a single widget. Containers are detached from their
+// children first since those are closed on their own or still in use.
+func closeWidget(id string, widget widgets.Widget) {
+	if container, ok := widget.(widgets.Container); ok {
+		container.SetChildren(nil)
+	}
+	if err := widget.Close(); err != nil {
+		log.Printf("Failed to close widget %s: %v", id, err)
+	}
+	widgets.ForgetLayoutError(widget)
+}
+
+func sameWidgetConfig(a, b config.WidgetConfig) bool {
+	return a.Type == b.Type && nodeString(a) == nodeString(b)
+}
+
+func samePositions(a, b []config.WidgetConfig) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !reflect.DeepEqual(a[i].Position, b[i].Position) {
+			return false
+		}
+	}
+	return true
+}
+
+func nodeString(widgetConfig config.WidgetConfig) string {
+	if widgetConfig.Config == nil {
+		return ""
+	}
+	return widgetConfig.Config.String()
+}
+
+// sameIntegrations compares the integration sections as written, so moving
+// them around in the file does not count as a change.
+func sameIntegrations(a, b config.IntegrationsConfig) bool {
+	if len(a.Raw) != len(b.Raw) {
+		return false
+	}
+	for name, node := range a.Raw {
+		other, exists := b.Raw[name]
+		if !exists || rawString(node) != rawString(other) {
+			return false
+		}
+	}
+	return true
+}
+
+func rawString(node ast.Node) string {
+	if node == nil {
+		return ""
+	}
+	return node.String()
+}
+
+func sameWidgets(a, b []widgets.Widget) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameTheme(a, b *config.Config) bool {
+	return a.Dashboard.Theme == b.Dashboard.Theme &&
+		reflect.DeepEqual(a.Dashboard.Colors, b.Dashboard.Colors)
+}
+
+// watchConfig polls the config file and reloads the dashboard whenever it
+// changes on disk.
+func (ds *DashboardService) watchConfig() {
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ds.ctx.Done():
+			return
+		case <-ticker.C:
+			stamp, err := statConfig(ds.configPath)
+			if err != nil {
+				continue
+			}
+
+			ds.mu.RLock()
+			changed := stamp != ds.configStamp
+			ds.mu.RUnlock()
+
+			if changed {
+				log.Printf("Config file %s changed, reloading", ds.configPath)
+				ds.reloadConfig()
+			}
+		}
+	}
+}
+
+// reloadConfig loads the config file again and swaps in the new widget tree.
+// If the file is invalid the running dashboard is kept and the error is
+// reported through GetConfigError. The new tree is built without holding mu,
+// so the UI and the API keep working while widgets initialize.
+func (ds *DashboardService) reloadConfig() {
+	// The file watcher and the API may both ask for a reload
+	ds.reloadMu.Lock()
+	defer ds.reloadMu.Unlock()
+
+	stamp, _ := statConfig(ds.configPath)
+	cfg, err := config.LoadConfig(ds.configPath)
+
+	ds.mu.RLock()
+	previous := &widgetSnapshot{
+		manager: ds.widgetManager,
+		configs: ds.widgetConfigs,
+		cancels: ds.widgetCancels,
+	}
+	previousConfig, previousTheme, previousRoot := ds.config, ds.theme, ds.rootWidget
+	ds.mu.RUnlock()
+
+	if err != nil {
+		ds.reloadFailed(stamp, previousConfig, err)
+		return
+	}
+
+	if !sameIntegrations(cfg.Integrations, previousConfig.Integrations) {
+		log.Printf("Integration settings changed, restart to apply them")
+	}
+	if !reflect.DeepEqual(cfg.Dashboard.API, previousConfig.Dashboard.API) {
+		log.Printf("API settings changed, restart to apply them")
+	}
+
+	build := newTreeBuild(cfg, previousTheme, previous.manager.GetFactory(), previous)
+	if !sameTheme(previousConfig, cfg) {
+		// Widgets hold on to their theme, rebuild all of them
+		build.allowReuse = false
+		build.theme = cfg.Theme()
+	}
+
+	if err := ds.createWidgets(build); err != nil {
+		build.discard()
+		ds.reloadFailed(stamp, previousConfig, err)
+		return
+	}
+	keepPage(previousRoot, build.root)
+
+	ds.treeMu.Lock()
+	ds.mu.Lock()
+	ds.install(build)
+	ds.configStamp = stamp
+	ds.configErr = nil
+	ds.mu.Unlock()
+	ds.treeMu.Unlock()
+
+	// Widgets left out of the new tree are closed once it is in place, and
+	// their alerts end
+	build.commit()
+	ds.refreshAlerts()
+
+	ds.bus.Publish(events.ConfigReloaded{Title: cfg.Dashboard.Title})
+	log.Printf("Config reloaded successfully")
+}
+
+// reloadFailed keeps the running dashboard and reports err.
+func (ds *DashboardService) reloadFailed(stamp configStamp, running *config.Config, err error) {
+	log.Printf("Config reload failed, keeping previous dashboard: %v", err)
+
+	ds.mu.Lock()
+	ds.configStamp = stamp
+	ds.configErr = err
+	ds.mu.Unlock()
+
+	ds.invalidate()
+	ds.bus.Publish(events.ConfigReloaded{Title: running.Dashboard.Title, Error: err.Error()})
+}
+
+// install makes a built tree the running one and attaches the new children
+// of reused containers. The caller must hold treeMu and mu, so no frame and
+// no API request sees the tree half swapped.
+func (ds *DashboardService) install(build *treeBuild) {
+	ds.config = build.config
+	ds.theme = build.theme
+	ds.widgetManager = build.manager
+	ds.widgetConfigs = build.configs
+	ds.widgetCancels = build.cancels
+	ds.rootWidget = build.root
+	for _, update := range build.childUpdates {
+		update()
+	}
+}
+
+func (ds *DashboardService) invalidate() {
+	if ds.window != nil {
+		ds.window.Invalidate()
+	}
+}
@@ -0,0 +1,71 @@
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"image"
+	"time"
+
+	"github.com/mntndev/dash/pkg/render"
+)
+
+// screenshotTimeout is how long a screenshot waits for the window to draw a
+// frame, which it does not while hidden.
+const screenshotTimeout = 10 * time.Second
+
+// errNoFrame is returned for screenshots the window did not draw a frame for.
+var errNoFrame = errors.New("the window did not draw a frame for the screenshot")
+
+// screenshotRequest asks the UI goroutine to render the dashboard.
+type screenshotRequest struct {
+	opts  render.Options
+	reply chan screenshotResult
+}
+
+type screenshotResult struct {
+	img *image.RGBA
+	err error
+}
+
+// screenshot renders the dashboard. Laying out the widget tree changes its
+// state, so only the goroutine that draws the window may do it: the request
+// is rendered with the next frame. Without a window nothing else lays out
+// the tree and it is rendered right away.
+func (ds *DashboardService) screenshot(ctx context.Context, opts render.Options) (*image.RGBA, error) {
+	ds.screenshotMu.Lock()
+	defer ds.screenshotMu.Unlock()
+
+	if ds.window == nil {
+		return render.Render(ds.draw, opts)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, screenshotTimeout)
+	defer cancel()
+	req := screenshotRequest{opts: opts, reply: make(chan screenshotResult, 1)}
+	select {
+	case ds.screenshots <- req:
+	case <-ctx.Done():
+		return nil, errNoFrame
+	}
+	ds.window.Invalidate()
+	select {
+	case result := <-req.reply:
+		return result.img, result.err
+	case <-ctx.Done():
+		return nil, errNoFrame
+	}
+}
+
+// takeScreenshots renders the screenshots asked for since the last frame.
+// It is called by Layout, on the goroutine that draws the window.
+func (ds *DashboardService) takeScreenshots() {
+	for {
+		select {
+		case req := <-ds.screenshots:
+			img, err := render.Render(ds.draw, req.opts)
+			req.reply <- screenshotResult{img: img, err: err}
+		default:
+			return
+		}
+	}
+}
@@ -2,42 +2,70 @@ package dashboard
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"sort"
 	"sync"
 	"time"
 
 	"gioui.org/app"
+	"gioui.org/widget/material"
 	"github.com/mntndev/dash/pkg/config"
+	"github.com/mntndev/dash/pkg/events"
 	"github.com/mntndev/dash/pkg/integrations"
 	"github.com/mntndev/dash/pkg/widgets"
 )
 
 type DashboardService struct {
 	config        *config.Config
+	configPath    string
+	configErr     error
+	configStamp   configStamp
+	theme         *material.Theme
 	widgetManager *widgets.WidgetManager
-	haClient      *integrations.HomeAssistantClient
-	dexcomClient  *integrations.DexcomClient
-	eventEmitter  EventEmitter
+	widgetConfigs map[string]config.WidgetConfig
+	widgetCancels map[string]context.CancelFunc
+	bus           *events.Bus
+	alertsMu      sync.Mutex
+	alerts        map[string]*alertState
+	alertButtons  alertButtons
 	window        *app.Window
-	mu            sync.RWMutex
-	ctx           context.Context
-	cancel        context.CancelFunc
-	initialized   bool
-	rootWidget    widgets.Widget
+	// treeMu is held by the UI goroutine while it lays out the widget tree
+	// and by whoever swaps the tree, so containers never change mid frame.
+	// Take it before mu.
+	treeMu      sync.RWMutex
+	mu          sync.RWMutex
+	reloadMu    sync.Mutex
+	ctx         context.Context
+	cancel      context.CancelFunc
+	initialized bool
+	rootWidget  widgets.Widget
+
+	// Integrations have their own lock since widgets look them up while
+	// Initialize holds mu.
+	integrationRegistry *integrations.Registry
+	integrationsMu      sync.RWMutex
+	integrations        map[string]integrations.Integration
+	integrationErrs     []error
+
+	api    *http.Server
+	apiErr error
+	// screenshotMu lets one screenshot render at a time.
+	screenshotMu sync.Mutex
+	// screenshots passes screenshot requests to the UI goroutine.
+	screenshots chan screenshotRequest
+
+	// headless services render without a window. They only start the
+	// integrations their registry knows and do not watch the config file.
+	headless bool
 }
 
-type EventEmitter interface {
-	Emit(event string, data interface{})
-}
-
-type GioEventEmitter struct {
-	// For now, just log events - we'll expand this later
-}
-
-func (g *GioEventEmitter) Emit(event string, data interface{}) {
-	log.Printf("Event emitted: %s", event)
-}
+var (
+	ErrWidgetNotFound = errors.New("widget not found")
+	ErrNotTriggerable = errors.New("widget cannot be triggered")
+)
 
 // WidgetData represents the dynamic data of a widget.
 type WidgetData struct {
@@ -49,20 +77,50 @@ type WidgetData struct {
 
 // DashboardInfo contains the static dashboard information.
 type DashboardInfo struct {
-	Title      string                 `json:"title"`
-	Theme      string                 `json:"theme"`
-	RootWidget widgets.Widget         `json:"root_widget"`
+	Title string `json:"title"`
+	Theme string `json:"theme"`
+	// RootWidget is served as a WidgetNode tree by the API, widgets are not
+	// safe to encode while they update.
+	RootWidget widgets.Widget         `json:"-"`
 	Status     map[string]interface{} `json:"status"`
 }
 
 func NewDashboardService(window *app.Window) *DashboardService {
+	return newDashboardService(window, config.GetDefaultConfigPath())
+}
+
+// NewHeadlessService creates a dashboard to render without a window, e.g.
+// to a PNG. Integrations are created from registry, config sections it does
+// not know are ignored, so a render only talks to real services if asked
+// to. A nil registry starts no integrations.
+func NewHeadlessService(configPath string, registry *integrations.Registry) (*DashboardService, error) {
+	// Fail on a broken config instead of falling back to the default one
+	if _, err := config.LoadConfig(configPath); err != nil {
+		return nil, err
+	}
+
+	ds := newDashboardService(nil, configPath)
+	if registry == nil {
+		registry = integrations.NewRegistry()
+	}
+	ds.integrationRegistry = registry
+	ds.headless = true
+	return ds, nil
+}
+
+func newDashboardService(window *app.Window, configPath string) *DashboardService {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Load config with fallback to default
-	configPath := config.GetDefaultConfigPath()
+	stamp, _ := statConfig(configPath)
 	cfg, err := config.LoadConfig(configPath)
+	var configErr error
 	if err != nil {
 		log.Printf("Failed to load config: %v. Using default config.", err)
+		if !stamp.modTime.IsZero() {
+			// The file exists but is broken, surface that on screen
+			configErr = err
+		}
 		cfg = &config.Config{
 			Dashboard: config.DashboardConfig{
 				Title: "My Dashboard",
@@ -76,114 +134,196 @@ func NewDashboardService(window *app.Window) *DashboardService {
 		}
 	}
 
-	// Create a simple event emitter for Gio
-	eventEmitter := &GioEventEmitter{}
-
 	service := &DashboardService{
-		config:       cfg,
-		eventEmitter: eventEmitter,
-		window:       window,
-		ctx:          ctx,
-		cancel:       cancel,
+		config:        cfg,
+		configPath:    configPath,
+		configErr:     configErr,
+		configStamp:   stamp,
+		theme:         cfg.Theme(),
+		widgetConfigs: make(map[string]config.WidgetConfig),
+		widgetCancels: make(map[string]context.CancelFunc),
+		bus:           events.NewBus(),
+		alerts:        make(map[string]*alertState),
+		screenshots:   make(chan screenshotRequest, 1),
+		window:        window,
+		ctx:           ctx,
+		cancel:        cancel,
+
+		integrationRegistry: integrations.NewDefaultRegistry(),
 	}
 
 	return service
 }
 
 func (ds *DashboardService) Initialize() error {
+	ds.treeMu.Lock()
+	defer ds.treeMu.Unlock()
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
 
 	widgetFactory := widgets.NewDefaultWidgetFactory(ds)
-	widgetManager := widgets.NewWidgetManager(widgetFactory)
-	ds.widgetManager = widgetManager
-
-	if ds.config.Integrations.HomeAssistant != nil {
-		log.Printf("Initializing Home Assistant client...")
-		ds.haClient = integrations.NewHomeAssistantClient(ds.config.Integrations.HomeAssistant)
-		go func() {
-			log.Printf("Connecting to Home Assistant...")
-			if err := ds.haClient.Connect(); err != nil {
-				log.Printf("Failed to connect to Home Assistant: %v", err)
-			} else {
-				log.Printf("Successfully connected to Home Assistant")
-				if err := ds.haClient.SubscribeEvents("state_changed"); err != nil {
-					log.Printf("Failed to subscribe to state_changed events: %v", err)
-				}
-			}
-		}()
-	}
 
-	if ds.config.Integrations.Dexcom != nil {
-		log.Printf("Initializing Dexcom client...")
-		ds.dexcomClient = integrations.NewDexcomClient(ds.config.Integrations.Dexcom)
-	}
+	// Widgets may raise alerts as soon as they are initialized
+	go ds.watchAlerts(events.Subscribe[events.Alert](ds.bus, events.DefaultBuffer))
+
+	ds.startIntegrations()
 
 	log.Printf("Creating and initializing widgets...")
-	if err := ds.createWidgets(); err != nil {
+	build := newTreeBuild(ds.config, ds.theme, widgetFactory, nil)
+	if err := ds.createWidgets(build); err != nil {
+		build.discard()
 		return fmt.Errorf("failed to create widgets: %w", err)
 	}
-
-	if ds.rootWidget == nil {
-		return fmt.Errorf("root widget not found")
-	}
+	ds.install(build)
+	build.commit()
 	log.Printf("Widgets created and initialized successfully")
 
 	ds.initialized = true
+	ds.startAPI()
 
-	dashboardInfo := ds.getDashboardInfo()
-	ds.eventEmitter.Emit("dashboard_info", dashboardInfo)
+	if !ds.headless {
+		go ds.redrawOnEvents(events.SubscribeAll(ds.bus, events.DefaultBuffer))
+		go ds.watchConfig()
+	}
 
 	log.Printf("Dashboard service initialized successfully")
 	return nil
 }
 
-func (ds *DashboardService) createWidgets() error {
-	log.Printf("Creating root widget of type: %s", ds.config.Dashboard.Widget.Type)
-	log.Printf("Root widget has %d children", len(ds.config.Dashboard.Widget.Children))
+// startIntegrations creates and starts an integration for every section under
+// integrations:. A section that fails is reported in the status overlay and
+// skipped, the rest of the dashboard still runs.
+func (ds *DashboardService) startIntegrations() {
+	raw := ds.config.Integrations.Raw
+	supported := make(map[string]bool)
+	for _, name := range ds.integrationRegistry.GetSupportedNames() {
+		supported[name] = true
+	}
+
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		if ds.headless && !supported[name] {
+			log.Printf("Skipping integration %s", name)
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	started := make(map[string]integrations.Integration)
+	var errs []error
+	for _, name := range names {
+		log.Printf("Initializing integration %s...", name)
+		integration, err := ds.integrationRegistry.Create(name, raw[name])
+		if err != nil {
+			log.Printf("Failed to create integration %s: %v", name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			ds.bus.Publish(events.Error{Source: name, Message: err.Error()})
+			continue
+		}
+
+		// Subscribe before starting so no transition is missed
+		if notifier, ok := integration.(integrations.HealthNotifier); ok {
+			go ds.watchHealth(name, notifier.SubscribeHealth())
+		}
+
+		if err := integration.Start(); err != nil {
+			log.Printf("Failed to start integration %s: %v", name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			ds.bus.Publish(events.Error{Source: name, Message: err.Error()})
+			if err := integration.Stop(); err != nil {
+				log.Printf("Failed to stop integration %s: %v", name, err)
+			}
+			continue
+		}
+		started[name] = integration
+	}
+
+	ds.integrationsMu.Lock()
+	ds.integrations = started
+	ds.integrationErrs = errs
+	ds.integrationsMu.Unlock()
+}
+
+// createWidgets creates the widget tree of build.config into build. It does
+// not touch the running tree.
+func (ds *DashboardService) createWidgets(build *treeBuild) error {
+	log.Printf("Creating root widget of type: %s", build.config.Dashboard.Widget.Type)
+	log.Printf("Root widget has %d children", len(build.config.Dashboard.Widget.Children))
 
 	// Create root widget with depth-first approach
-	rootWidget, err := ds.createWidgetWithChildren(ds.config.Dashboard.Widget, "root")
+	rootWidget, err := ds.createWidgetWithChildren(build, build.config.Dashboard.Widget, config.RootWidgetPrefix)
 	if err != nil {
 		return fmt.Errorf("failed to create root widget: %w", err)
 	}
 
 	// Store the root widget
-	ds.rootWidget = rootWidget
+	build.root = rootWidget
 	log.Printf("Created widget hierarchy with root: %s", rootWidget.GetID())
 
 	return nil
 }
 
-func (ds *DashboardService) createWidgetWithChildren(widgetConfig config.WidgetConfig, idPrefix string) (widgets.Widget, error) {
-	widgetID := fmt.Sprintf("%s_%s", idPrefix, widgetConfig.Type)
-	log.Printf("Creating widget: %s (type: %s)", widgetID, widgetConfig.Type)
+func (ds *DashboardService) createWidgetWithChildren(build *treeBuild, widgetConfig config.WidgetConfig, idPrefix string) (widgets.Widget, error) {
+	// The config was checked for IDs that clash
+	widgetID := config.WidgetID(widgetConfig, idPrefix)
 
 	// First, create all child widgets depth-first
 	var childWidgets []widgets.Widget
 	for i, childConfig := range widgetConfig.Children {
-		childID := fmt.Sprintf("%s_child_%d", idPrefix, i)
-		childWidget, err := ds.createWidgetWithChildren(childConfig, childID)
+		childWidget, err := ds.createWidgetWithChildren(build, childConfig, config.ChildPrefix(widgetConfig, idPrefix, i))
 		if err != nil {
 			return nil, fmt.Errorf("failed to create child widget %d: %w", i, err)
 		}
 		childWidgets = append(childWidgets, childWidget)
 	}
+	build.children[widgetID] = childWidgets
+
+	// Keep the running widget if nothing about it changed
+	if widget, ok := build.reuse(widgetID, widgetConfig, childWidgets); ok {
+		log.Printf("Reusing widget: %s (type: %s)", widgetID, widgetConfig.Type)
+		build.manager.StoreWidget(widgetID, widget)
+		build.configs[widgetID] = widgetConfig
+		build.cancels[widgetID] = build.previous.cancels[widgetID]
+		return widget, nil
+	}
+
+	log.Printf("Creating widget: %s (type: %s)", widgetID, widgetConfig.Type)
 
 	// Create the parent widget with ID and children at creation time
-	widget, err := ds.widgetManager.GetFactory().Create(widgetConfig.Type, widgetID, widgetConfig.Config, childWidgets, ds.window, ds.config.Theme())
+	widget, err := build.manager.GetFactory().Create(widgetConfig.Type, widgetID, widgetConfig.Config, childWidgets, ds.window, build.theme)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create widget %s: %w", widgetID, err)
+		return ds.failedWidget(build, widgetID, widgetConfig, childWidgets, fmt.Errorf("failed to create widget: %w", err)), nil
 	}
 
+	if placer, ok := widget.(widgets.Placer); ok {
+		positions := make([]*config.Position, len(widgetConfig.Children))
+		for i, child := range widgetConfig.Children {
+			positions[i] = child.Position
+		}
+		if err := placer.SetPositions(positions); err != nil {
+			closeWidget(widgetID, widget)
+			return ds.failedWidget(build, widgetID, widgetConfig, childWidgets, fmt.Errorf("failed to place children: %w", err)), nil
+		}
+	}
+
+	// Each widget gets its own context so it can be stopped when a reload
+	// removes it from the tree
+	widgetCtx, cancel := context.WithCancel(ds.ctx)
+
 	// Initialize the widget immediately since it now has everything it needs
 	log.Printf("Initializing widget: %s (type: %s)", widgetID, widgetConfig.Type)
-	if err := widget.Init(ds.ctx); err != nil {
-		return nil, fmt.Errorf("failed to initialize widget %s: %w", widgetID, err)
+	if err := widget.Init(widgetCtx); err != nil {
+		cancel()
+		closeWidget(widgetID, widget)
+		return ds.failedWidget(build, widgetID, widgetConfig, childWidgets, fmt.Errorf("failed to initialize widget: %w", err)), nil
 	}
+	build.created = append(build.created, createdWidget{widget: widget, cancel: cancel})
 
 	// Store the widget in the manager so it can be found by TriggerWidget
-	ds.widgetManager.StoreWidget(widgetID, widget)
+	build.manager.StoreWidget(widgetID, widget)
+	build.configs[widgetID] = widgetConfig
+	build.cancels[widgetID] = cancel
 
 	if len(childWidgets) > 0 {
 		log.Printf("Created and initialized widget %s with %d children", widgetID, len(childWidgets))
@@ -194,6 +334,40 @@ func (ds *DashboardService) createWidgetWithChildren(widgetConfig config.WidgetC
 	return widget, nil
 }
 
+// failedWidget puts an error tile in place of a widget that could not be
+// created or initialized. The tile shows no children, so the children built
+// for it are stopped and closed.
+func (ds *DashboardService) failedWidget(build *treeBuild, widgetID string, widgetConfig config.WidgetConfig, children []widgets.Widget, err error) widgets.Widget {
+	log.Printf("Widget %s (type: %s) failed: %v", widgetID, widgetConfig.Type, err)
+	build.drop(children)
+	widget := widgets.NewErrorWidget(widgetID, widgetConfig.Type, err, ds.window, build.theme)
+	build.manager.StoreWidget(widgetID, widget)
+	build.configs[widgetID] = widgetConfig
+	ds.bus.Publish(events.Error{Source: widgetID, Message: widget.Err().Error()})
+	return widget
+}
+
+// widgetErrors returns the widgets that failed to be created, initialized or
+// laid out, ordered by ID. The caller must hold mu.
+func (ds *DashboardService) widgetErrors() []*widgets.WidgetError {
+	if ds.widgetManager == nil {
+		return nil
+	}
+
+	var errs []*widgets.WidgetError
+	for _, widget := range ds.widgetManager.GetAllWidgets() {
+		if failed, ok := widget.(*widgets.ErrorWidget); ok {
+			errs = append(errs, failed.Err())
+		} else if err, ok := widgets.LayoutError(widget); ok {
+			errs = append(errs, err)
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].ID < errs[j].ID
+	})
+	return errs
+}
+
 func (ds *DashboardService) getDashboardInfo() DashboardInfo {
 	status := make(map[string]interface{})
 
@@ -206,12 +380,21 @@ func (ds *DashboardService) getDashboardInfo() DashboardInfo {
 		}
 	}
 
-	if ds.haClient != nil {
-		status["home_assistant"] = ds.haClient.IsConnected()
+	ds.integrationsMu.RLock()
+	for name, integration := range ds.integrations {
+		health := integration.Health()
+		status[name] = health.Status == integrations.Healthy
+		for key, value := range health.Details {
+			status[name+"_"+key] = value
+		}
 	}
-	if ds.dexcomClient != nil {
-		status["dexcom"] = true // Always available for stateless API
+	ds.integrationsMu.RUnlock()
+
+	var dropped uint64
+	for _, count := range ds.bus.Dropped() {
+		dropped += count
 	}
+	status["events_dropped"] = dropped
 
 	return DashboardInfo{
 		Title:      ds.config.Dashboard.Title,
@@ -221,20 +404,48 @@ func (ds *DashboardService) getDashboardInfo() DashboardInfo {
 	}
 }
 
-func (ds *DashboardService) GetHAClient() *integrations.HomeAssistantClient {
-	ds.mu.RLock()
-	defer ds.mu.RUnlock()
-	return ds.haClient
+// watchHealth publishes the health changes an integration reports on its
+// own.
+func (ds *DashboardService) watchHealth(name string, updates <-chan integrations.Health) {
+	for health := range updates {
+		log.Printf("Integration %s is %s", name, health.Status)
+		ds.bus.Publish(events.IntegrationHealth{
+			Name:    name,
+			Status:  health.Status.String(),
+			Message: health.Message,
+		})
+	}
 }
 
-func (ds *DashboardService) GetDexcomClient() *integrations.DexcomClient {
-	ds.mu.RLock()
-	defer ds.mu.RUnlock()
-	return ds.dexcomClient
+// redrawOnEvents redraws the window for events that change the status
+// overlay. Widgets invalidate the window themselves when their data changes.
+func (ds *DashboardService) redrawOnEvents(sub *events.Subscription[events.Event]) {
+	for event := range sub.C() {
+		switch event.(type) {
+		case events.IntegrationHealth, events.Error:
+			ds.invalidate()
+		}
+	}
+}
+
+// GetIntegration returns the running integration configured under name, or
+// nil if there is none.
+func (ds *DashboardService) GetIntegration(name string) integrations.Integration {
+	ds.integrationsMu.RLock()
+	defer ds.integrationsMu.RUnlock()
+	return ds.integrations[name]
 }
 
-func (ds *DashboardService) Emit(event string, data interface{}) {
-	ds.eventEmitter.Emit(event, data)
+// GetIntegrationRegistry returns the registry integrations are created from.
+// Integrations registered before Initialize can be used in the config file.
+func (ds *DashboardService) GetIntegrationRegistry() *integrations.Registry {
+	return ds.integrationRegistry
+}
+
+// Events returns the bus the dashboard, its integrations and widgets publish
+// on.
+func (ds *DashboardService) Events() *events.Bus {
+	return ds.bus
 }
 
 func (ds *DashboardService) GetConfig() *config.Config {
@@ -245,15 +456,26 @@ func (ds *DashboardService) GetConfig() *config.Config {
 
 func (ds *DashboardService) Close() error {
 	ds.cancel()
+	ds.stopAPI()
 
-	if ds.haClient != nil {
-		if err := ds.haClient.Close(); err != nil {
-			log.Printf("Failed to close Home Assistant client: %v", err)
+	ds.mu.Lock()
+	if ds.widgetManager != nil {
+		for id, widget := range ds.widgetManager.GetAllWidgets() {
+			closeWidget(id, widget)
 		}
 	}
+	ds.mu.Unlock()
 
-	// Dexcom client is stateless, no cleanup needed
+	ds.integrationsMu.Lock()
+	for name, integration := range ds.integrations {
+		if err := integration.Stop(); err != nil {
+			log.Printf("Failed to stop integration %s: %v", name, err)
+		}
+	}
+	ds.integrations = nil
+	ds.integrationsMu.Unlock()
 
+	ds.bus.Close()
 	return nil
 }
 
@@ -263,3 +485,122 @@ func (ds *DashboardService) GetRootWidget() widgets.Widget {
 	defer ds.mu.RUnlock()
 	return ds.rootWidget
 }
+
+// GetWidget returns the widget with the given ID, either the id: from the
+// config or the one generated from its place in the tree.
+func (ds *DashboardService) GetWidget(id string) (widgets.Widget, bool) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	if ds.widgetManager == nil {
+		return nil, false
+	}
+	return ds.widgetManager.GetWidget(id)
+}
+
+// TriggerWidget runs the action of a widget, like toggling a switch, as if
+// the user had tapped it.
+func (ds *DashboardService) TriggerWidget(id string) error {
+	widget, exists := ds.GetWidget(id)
+	if !exists {
+		return fmt.Errorf("widget %s: %w", id, ErrWidgetNotFound)
+	}
+	triggerable, ok := widget.(widgets.Triggerable)
+	if !ok {
+		return fmt.Errorf("widget %s (type: %s): %w", id, widget.GetType(), ErrNotTriggerable)
+	}
+	return triggerable.Trigger()
+}
+
+// GetTheme returns the material theme built from the active config.
+func (ds *DashboardService) GetTheme() *material.Theme {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.theme
+}
+
+// GetConfigError returns the error from the most recent failed config load,
+// or nil when the running dashboard matches the config file.
+func (ds *DashboardService) GetConfigError() error {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.configErr
+}
+
+// StatusLevel is the severity of a status overlay message.
+type StatusLevel int
+
+const (
+	StatusWarning StatusLevel = iota
+	StatusError
+)
+
+// StatusMessage is a short notice shown in the status overlay.
+type StatusMessage struct {
+	Level StatusLevel `json:"level"`
+	Text  string      `json:"text"`
+}
+
+// GetStatusMessages returns the notices the status overlay should show, most
+// severe first. It is empty when everything is healthy.
+func (ds *DashboardService) GetStatusMessages() []StatusMessage {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	var messages []StatusMessage
+	if ds.configErr != nil {
+		messages = append(messages, StatusMessage{
+			Level: StatusError,
+			Text:  "Config error: " + ds.configErr.Error(),
+		})
+	}
+
+	if ds.apiErr != nil {
+		messages = append(messages, StatusMessage{
+			Level: StatusError,
+			Text:  "API error: " + ds.apiErr.Error(),
+		})
+	}
+
+	for _, err := range ds.widgetErrors() {
+		messages = append(messages, StatusMessage{
+			Level: StatusError,
+			Text:  "Widget error: " + err.Error(),
+		})
+	}
+
+	ds.integrationsMu.RLock()
+	defer ds.integrationsMu.RUnlock()
+
+	for _, err := range ds.integrationErrs {
+		messages = append(messages, StatusMessage{
+			Level: StatusError,
+			Text:  "Integration error: " + err.Error(),
+		})
+	}
+
+	names := make([]string, 0, len(ds.integrations))
+	for name := range ds.integrations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var warnings []StatusMessage
+	for _, name := range names {
+		health := ds.integrations[name].Health()
+		if health.Status == integrations.Healthy {
+			continue
+		}
+
+		text := health.Message
+		if text == "" {
+			text = fmt.Sprintf("%s: %s", name, health.Status)
+		}
+		if health.Status == integrations.Unhealthy {
+			messages = append(messages, StatusMessage{Level: StatusError, Text: text})
+		} else {
+			warnings = append(warnings, StatusMessage{Level: StatusWarning, Text: text})
+		}
+	}
+
+	return append(messages, warnings...)
+}
@@ -0,0 +1,681 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gioui.org/app"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/mntndev/dash/pkg/events"
+	"github.com/mntndev/dash/pkg/integrations"
+	"github.com/mntndev/dash/pkg/widgets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reloadConfigBefore = `dashboard:
+  title: "Reload Test"
+  theme: "dark"
+  widget:
+    type: "hstack"
+    children:
+      - type: "clock"
+        config:
+          format: "15:04"
+      - type: "clock"
+        config:
+          format: "15:04:05"
+`
+
+const reloadConfigAfter = `dashboard:
+  title: "Reload Test"
+  theme: "dark"
+  widget:
+    type: "hstack"
+    children:
+      - type: "clock"
+        config:
+          format: "15:04"
+      - type: "clock"
+        config:
+          format: "3:04PM"
+`
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestReloadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, reloadConfigBefore)
+
+	ds := newDashboardService(nil, path)
+	require.NoError(t, ds.Initialize())
+	defer ds.Close()
+
+	root := ds.GetRootWidget()
+	require.NotNil(t, root)
+	before := root.GetChildren()
+	require.Len(t, before, 2)
+
+	reloads := events.Subscribe[events.ConfigReloaded](ds.Events(), 8)
+	failures := events.Subscribe[events.Error](ds.Events(), 8)
+
+	t.Run("unchanged widgets are reused", func(t *testing.T) {
+		writeConfig(t, path, reloadConfigAfter)
+		ds.reloadConfig()
+
+		require.NoError(t, ds.GetConfigError())
+		assert.Same(t, root, ds.GetRootWidget())
+
+		after := ds.GetRootWidget().GetChildren()
+		require.Len(t, after, 2)
+		assert.Same(t, before[0], after[0])
+		assert.NotSame(t, before[1], after[1])
+
+		require.Len(t, reloads.C(), 1)
+		assert.Empty(t, (<-reloads.C()).Error)
+	})
+
+	t.Run("reused containers get their children between frames", func(t *testing.T) {
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			var ops op.Ops
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				ops.Reset()
+				ds.Layout(layout.Context{
+					Ops:         &ops,
+					Metric:      unit.Metric{PxPerDp: 1, PxPerSp: 1},
+					Constraints: layout.Exact(image.Pt(400, 300)),
+					Now:         time.Now(),
+				})
+			}
+		}()
+
+		for i := range 100 {
+			writeConfig(t, path, []string{reloadConfigBefore, reloadConfigAfter}[i%2])
+			ds.reloadConfig()
+			require.NoError(t, ds.GetConfigError())
+			assert.Same(t, root, ds.GetRootWidget())
+		}
+		close(stop)
+		<-done
+		for range len(reloads.C()) {
+			<-reloads.C()
+		}
+	})
+
+	t.Run("invalid config keeps running dashboard", func(t *testing.T) {
+		running := ds.GetRootWidget()
+		writeConfig(t, path, "dashboard: [unclosed")
+		ds.reloadConfig()
+
+		assert.Error(t, ds.GetConfigError())
+		assert.Same(t, running, ds.GetRootWidget())
+
+		require.Len(t, reloads.C(), 1)
+		assert.NotEmpty(t, (<-reloads.C()).Error)
+	})
+
+	t.Run("unknown widget type shows an error tile", func(t *testing.T) {
+		writeConfig(t, path, `dashboard:
+  title: "Reload Test"
+  widget:
+    type: "does_not_exist"
+`)
+		ds.reloadConfig()
+
+		require.NoError(t, ds.GetConfigError())
+		assert.IsType(t, &widgets.ErrorWidget{}, ds.GetRootWidget())
+		assert.Equal(t, "does_not_exist", ds.GetConfig().Dashboard.Widget.Type)
+
+		require.Len(t, failures.C(), 1)
+		assert.Equal(t, "root_does_not_exist", (<-failures.C()).Source)
+	})
+
+	t.Run("fixing the config clears the error", func(t *testing.T) {
+		writeConfig(t, path, reloadConfigBefore)
+		ds.reloadConfig()
+
+		assert.NoError(t, ds.GetConfigError())
+	})
+}
+
+// blockingFactory creates "blocking" widgets, clocks whose Init waits until
+// release is closed.
+type blockingFactory struct {
+	widgets.WidgetFactory
+	started chan struct{}
+	release chan struct{}
+}
+
+type blockingWidget struct {
+	widgets.Widget
+	factory *blockingFactory
+}
+
+func (f *blockingFactory) Create(widgetType, id string, config ast.Node, children []widgets.Widget, window *app.Window, theme *material.Theme) (widgets.Widget, error) {
+	if widgetType != "blocking" {
+		return f.WidgetFactory.Create(widgetType, id, config, children, window, theme)
+	}
+	clock, err := f.WidgetFactory.Create("clock", id, config, children, window, theme)
+	return &blockingWidget{Widget: clock, factory: f}, err
+}
+
+func (w *blockingWidget) Init(ctx context.Context) error {
+	close(w.factory.started)
+	<-w.factory.release
+	return w.Widget.Init(ctx)
+}
+
+func TestReloadDoesNotBlockReaders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, reloadConfigBefore)
+
+	ds := newDashboardService(nil, path)
+	require.NoError(t, ds.Initialize())
+	defer ds.Close()
+
+	factory := &blockingFactory{
+		WidgetFactory: ds.widgetManager.GetFactory(),
+		started:       make(chan struct{}),
+		release:       make(chan struct{}),
+	}
+	manager := widgets.NewWidgetManager(factory)
+	for id, widget := range ds.widgetManager.GetAllWidgets() {
+		manager.StoreWidget(id, widget)
+	}
+	ds.widgetManager = manager
+	running := ds.GetRootWidget()
+
+	writeConfig(t, path, strings.Replace(reloadConfigAfter, `"3:04PM"`, `"3:04PM"
+      - type: "blocking"`, 1))
+	done := make(chan struct{}, 2)
+	go func() {
+		ds.reloadConfig()
+		done <- struct{}{}
+	}()
+	<-factory.started
+
+	// The running dashboard is served while the new tree initializes
+	assert.Same(t, running, ds.GetRootWidget())
+	assert.NotNil(t, ds.GetTheme())
+	assert.NoError(t, ds.GetConfigError())
+
+	// A second reload waits for the first one
+	go func() {
+		ds.reloadConfig()
+		done <- struct{}{}
+	}()
+	select {
+	case <-done:
+		t.Fatal("reload finished before the widget was initialized")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(factory.release)
+	<-done
+	<-done
+	require.NoError(t, ds.GetConfigError())
+	assert.Len(t, ds.GetRootWidget().GetChildren(), 3)
+}
+
+// stubIntegration records its lifecycle for tests.
+type stubIntegration struct {
+	config  string
+	started bool
+	stopped bool
+}
+
+func (s *stubIntegration) Name() string {
+	return "custom"
+}
+
+func (s *stubIntegration) Start() error {
+	s.started = true
+	return nil
+}
+
+func (s *stubIntegration) Stop() error {
+	s.stopped = true
+	return nil
+}
+
+func (s *stubIntegration) Health() integrations.Health {
+	return integrations.Health{Status: integrations.Degraded, Message: "Custom: " + s.config}
+}
+
+func TestIntegrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, `dashboard:
+  title: "Integration Test"
+  widget:
+    type: "clock"
+integrations:
+  prometheus:
+    url: "http://localhost:9090"
+  custom:
+    greeting: "hello"
+  mqtt:
+    broker: "tcp://localhost:1883"
+`)
+
+	stub := &stubIntegration{}
+	ds := newDashboardService(nil, path)
+	ds.GetIntegrationRegistry().Register("custom", func(node ast.Node) (integrations.Integration, error) {
+		var cfg struct {
+			Greeting string `yaml:"greeting"`
+		}
+		if err := yaml.NodeToValue(node, &cfg); err != nil {
+			return nil, err
+		}
+		stub.config = cfg.Greeting
+		return stub, nil
+	})
+	require.NoError(t, ds.Initialize())
+
+	_, ok := ds.GetIntegration(integrations.PrometheusName).(*integrations.PrometheusClient)
+	assert.True(t, ok)
+	assert.Same(t, stub, ds.GetIntegration("custom"))
+	assert.True(t, stub.started)
+	assert.Nil(t, ds.GetIntegration("mqtt"))
+
+	// The unknown section is reported but does not stop the dashboard
+	messages := ds.GetStatusMessages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, StatusMessage{Level: StatusError, Text: "Integration error: mqtt: unsupported integration: mqtt"}, messages[0])
+	assert.Equal(t, StatusMessage{Level: StatusWarning, Text: "Custom: hello"}, messages[1])
+
+	status := ds.getDashboardInfo().Status
+	assert.Equal(t, true, status[integrations.PrometheusName])
+	assert.Equal(t, false, status["custom"])
+
+	require.NoError(t, ds.Close())
+	assert.True(t, stub.stopped)
+}
+
+const pagesConfig = `dashboard:
+  title: "Pages Test"
+  tab_bar: true
+  pages:
+    - name: "Lighting"
+      icon: "lights"
+      widget:
+        type: "clock"
+    - name: "Climate"
+      widget:
+        type: "clock"
+        config:
+          format: "15:04"
+`
+
+func TestPages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, pagesConfig)
+
+	ds := newDashboardService(nil, path)
+	require.NoError(t, ds.Initialize())
+	defer ds.Close()
+
+	pages := ds.GetPages()
+	require.Len(t, pages, 2)
+	assert.Equal(t, "Lighting", pages[0].Name)
+	assert.Equal(t, "lights", pages[0].Icon)
+
+	assert.Equal(t, 0, ds.CurrentPage())
+	require.NoError(t, ds.SetPage(1))
+	assert.Equal(t, 1, ds.CurrentPage())
+	assert.Error(t, ds.SetPage(2))
+
+	t.Run("reload keeps the current page", func(t *testing.T) {
+		root := ds.GetRootWidget()
+		writeConfig(t, path, strings.Replace(pagesConfig, "Climate", "Heating", 1))
+		ds.reloadConfig()
+
+		require.NoError(t, ds.GetConfigError())
+		assert.NotSame(t, root, ds.GetRootWidget())
+		assert.Equal(t, "Heating", ds.GetPages()[1].Name)
+		assert.Equal(t, 1, ds.CurrentPage())
+	})
+
+	t.Run("single root has no pages", func(t *testing.T) {
+		writeConfig(t, path, reloadConfigBefore)
+		ds.reloadConfig()
+
+		require.NoError(t, ds.GetConfigError())
+		assert.Nil(t, ds.GetPages())
+		assert.Equal(t, 0, ds.CurrentPage())
+		assert.Error(t, ds.SetPage(0))
+	})
+}
+
+const gridConfig = `dashboard:
+  title: "Grid Test"
+  widget:
+    type: "grid"
+    config:
+      columns: 3
+      rows: 2
+    children:
+      - type: "clock"
+        position: { x: 0, y: 0, width: 2, height: 2 }
+      - type: "clock"
+        config:
+          format: "15:04"
+        position: { x: 2, y: 0, width: 1, height: 1 }
+`
+
+func TestGrid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, gridConfig)
+
+	ds := newDashboardService(nil, path)
+	require.NoError(t, ds.Initialize())
+	defer ds.Close()
+
+	grid, ok := ds.GetRootWidget().(*widgets.GridWidget)
+	require.True(t, ok)
+	children := grid.GetChildren()
+
+	t.Run("moving a child rebuilds the grid", func(t *testing.T) {
+		writeConfig(t, path, strings.Replace(gridConfig, "x: 2, y: 0", "x: 2, y: 1", 1))
+		ds.reloadConfig()
+
+		require.NoError(t, ds.GetConfigError())
+		assert.NotSame(t, grid, ds.GetRootWidget())
+		assert.Same(t, children[1], ds.GetRootWidget().GetChildren()[1])
+	})
+
+	t.Run("overlapping children are rejected", func(t *testing.T) {
+		running := ds.GetRootWidget()
+		writeConfig(t, path, strings.Replace(gridConfig, "x: 2, y: 0", "x: 1, y: 0", 1))
+		ds.reloadConfig()
+
+		assert.ErrorContains(t, ds.GetConfigError(), "child 1 overlaps child 0")
+		assert.Same(t, running, ds.GetRootWidget())
+	})
+}
+
+const widgetIDConfig = `dashboard:
+  title: "ID Test"
+  widget:
+    type: "hstack"
+    children:
+      - type: "clock"
+        id: "kitchen_clock"
+`
+
+func TestWidgetIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, widgetIDConfig)
+
+	ds := newDashboardService(nil, path)
+	require.NoError(t, ds.Initialize())
+	defer ds.Close()
+
+	clock, ok := ds.GetWidget("kitchen_clock")
+	require.True(t, ok)
+	assert.Equal(t, "kitchen_clock", clock.GetID())
+
+	_, ok = ds.GetWidget("root_hstack")
+	assert.True(t, ok)
+
+	assert.ErrorContains(t, ds.TriggerWidget("kitchen_clock"), "cannot be triggered")
+	assert.ErrorContains(t, ds.TriggerWidget("missing"), "not found")
+
+	t.Run("inserting a widget keeps the id", func(t *testing.T) {
+		writeConfig(t, path, strings.Replace(widgetIDConfig, "    children:\n", "    children:\n      - type: \"clock\"\n", 1))
+		ds.reloadConfig()
+
+		require.NoError(t, ds.GetConfigError())
+		require.Len(t, ds.GetRootWidget().GetChildren(), 2)
+		after, ok := ds.GetWidget("kitchen_clock")
+		require.True(t, ok)
+		assert.Same(t, clock, after)
+	})
+
+	t.Run("ids clashing with generated ones are rejected", func(t *testing.T) {
+		writeConfig(t, path, strings.Replace(widgetIDConfig, "kitchen_clock", "root_child_1_clock", 1)+"      - type: \"clock\"\n")
+		ds.reloadConfig()
+
+		assert.ErrorContains(t, ds.GetConfigError(), `duplicate widget id "root_child_1_clock"`)
+	})
+}
+
+const widgetErrorConfig = `dashboard:
+  title: "Error Test"
+  widget:
+    type: "hstack"
+    children:
+      - type: "clock"
+      - type: "does_not_exist"
+`
+
+func TestWidgetErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, widgetErrorConfig)
+
+	ds := newDashboardService(nil, path)
+	require.NoError(t, ds.Initialize())
+	defer ds.Close()
+
+	children := ds.GetRootWidget().GetChildren()
+	require.Len(t, children, 2)
+	assert.IsType(t, &widgets.ClockWidget{}, children[0])
+	failed, ok := children[1].(*widgets.ErrorWidget)
+	require.True(t, ok)
+	assert.Equal(t, "root_child_1_does_not_exist", failed.GetID())
+	assert.ErrorContains(t, failed.Err(), "unsupported widget type")
+
+	assert.Equal(t, []StatusMessage{{
+		Level: StatusError,
+		Text:  "Widget error: does_not_exist (root_child_1_does_not_exist): failed to create widget: unsupported widget type: does_not_exist",
+	}}, ds.GetStatusMessages())
+
+	t.Run("fixing the widget clears the error", func(t *testing.T) {
+		writeConfig(t, path, strings.Replace(widgetErrorConfig, "does_not_exist", "clock", 1))
+		ds.reloadConfig()
+
+		require.NoError(t, ds.GetConfigError())
+		children := ds.GetRootWidget().GetChildren()
+		assert.IsType(t, &widgets.ClockWidget{}, children[1])
+		assert.Empty(t, ds.GetStatusMessages())
+	})
+}
+
+// trackingFactory creates "tracked" widgets, clocks that record whether
+// they were stopped and closed.
+type trackingFactory struct {
+	widgets.WidgetFactory
+	created []*trackedWidget
+}
+
+type trackedWidget struct {
+	widgets.Widget
+	ctx    context.Context
+	closed bool
+}
+
+func (f *trackingFactory) Create(widgetType, id string, config ast.Node, children []widgets.Widget, window *app.Window, theme *material.Theme) (widgets.Widget, error) {
+	if widgetType != "tracked" {
+		return f.WidgetFactory.Create(widgetType, id, config, children, window, theme)
+	}
+	clock, err := f.WidgetFactory.Create("clock", id, config, children, window, theme)
+	if err != nil {
+		return nil, err
+	}
+	widget := &trackedWidget{Widget: clock}
+	f.created = append(f.created, widget)
+	return widget, nil
+}
+
+func (w *trackedWidget) Init(ctx context.Context) error {
+	w.ctx = ctx
+	return w.Widget.Init(ctx)
+}
+
+func (w *trackedWidget) Close() error {
+	w.closed = true
+	return w.Widget.Close()
+}
+
+func TestFailedContainerClosesChildren(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, widgetErrorConfig)
+
+	ds := newDashboardService(nil, path)
+	require.NoError(t, ds.Initialize())
+	defer ds.Close()
+
+	factory := &trackingFactory{WidgetFactory: ds.widgetManager.GetFactory()}
+	ds.widgetManager = widgets.NewWidgetManager(factory)
+
+	writeConfig(t, path, `dashboard:
+  title: "Error Test"
+  widget:
+    type: "hstack"
+    children:
+      - type: "tracked"
+`)
+	ds.reloadConfig()
+	require.NoError(t, ds.GetConfigError())
+	require.Len(t, factory.created, 1)
+	require.NoError(t, factory.created[0].ctx.Err())
+
+	// The running child would be reused, the second one is new
+	writeConfig(t, path, `dashboard:
+  title: "Error Test"
+  widget:
+    type: "does_not_exist"
+    children:
+      - type: "tracked"
+      - type: "tracked"
+`)
+	ds.reloadConfig()
+	require.NoError(t, ds.GetConfigError())
+	assert.IsType(t, &widgets.ErrorWidget{}, ds.GetRootWidget())
+	require.Len(t, factory.created, 2)
+
+	for i, widget := range factory.created {
+		assert.True(t, widget.closed, "child %d", i)
+		assert.Error(t, widget.ctx.Err(), "child %d", i)
+		_, exists := ds.GetWidget(fmt.Sprintf("root_child_%d_tracked", i))
+		assert.False(t, exists, "child %d", i)
+	}
+}
+
+func TestHeadlessService(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, `dashboard:
+  title: "Headless Test"
+  widget:
+    type: "clock"
+integrations:
+  home_assistant:
+    url: "ws://192.0.2.1:8123/api/websocket"
+    token: "token"
+  custom: {}
+`)
+
+	registry := integrations.NewRegistry()
+	stub := &stubIntegration{}
+	registry.Register("custom", func(config ast.Node) (integrations.Integration, error) {
+		return stub, nil
+	})
+
+	ds, err := NewHeadlessService(path, registry)
+	require.NoError(t, err)
+	require.NoError(t, ds.Initialize())
+	defer ds.Close()
+
+	// Only the integration the registry knows is started
+	assert.True(t, stub.started)
+	assert.Nil(t, ds.GetIntegration(integrations.HomeAssistantName))
+	assert.NotNil(t, ds.GetRootWidget())
+
+	t.Run("broken config", func(t *testing.T) {
+		writeConfig(t, path, "dashboard: [unclosed")
+		_, err := NewHeadlessService(path, nil)
+		assert.Error(t, err)
+	})
+}
+
+const fixtureConfig = `dashboard:
+  title: "Fixture Test"
+  widget:
+    type: "hstack"
+    children:
+      - type: "clock"
+        id: "clock"
+        config:
+          format: "15:04"
+      - type: "prometheus.stat"
+        id: "load"
+        config:
+          query: "node_load1"
+      - type: "rss.list"
+        id: "news"
+`
+
+func TestLoadFixtures(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	writeConfig(t, path, fixtureConfig)
+
+	ds, err := NewHeadlessService(path, nil)
+	require.NoError(t, err)
+	require.NoError(t, ds.Initialize())
+	defer ds.Close()
+
+	fixtures := filepath.Join(dir, "fixtures.json")
+	writeConfig(t, fixtures, `{
+  "clock": {"time": "2025-10-13T09:41:00Z"},
+  "load": {"value": 0.42},
+  "news": {"items": [{"title": "Dash 1.0 released", "feed": "news"}]}
+}`)
+	require.NoError(t, ds.LoadFixtures(fixtures))
+
+	clock, _ := ds.GetWidget("clock")
+	assert.Equal(t, "09:41", clock.(widgets.DataProvider).GetData().(*widgets.ClockData).Display)
+
+	load, _ := ds.GetWidget("load")
+	data := load.(widgets.DataProvider).GetData().(*widgets.PrometheusData)
+	require.NotNil(t, data.Value)
+	assert.Equal(t, 0.42, *data.Value)
+	assert.Empty(t, data.Error)
+
+	news, _ := ds.GetWidget("news")
+	assert.Equal(t, []widgets.RSSItem{{Title: "Dash 1.0 released", Feed: "news"}}, news.(widgets.DataProvider).GetData().(*widgets.RSSData).Items)
+
+	t.Run("invalid fixtures", func(t *testing.T) {
+		for content, message := range map[string]string{
+			`{"missing": {}}`:          "widget not found",
+			`{"root_hstack": {}}`:      "hstack widgets do not take fixtures",
+			`{"load": {"value": "x"}}`: "invalid prometheus fixture",
+			`[]`:                       "failed to parse fixtures",
+		} {
+			writeConfig(t, fixtures, content)
+			assert.ErrorContains(t, ds.LoadFixtures(fixtures), message, content)
+		}
+		assert.Error(t, ds.LoadFixtures(filepath.Join(dir, "missing.json")))
+	})
+}
@@ -0,0 +1,223 @@
+// Package events is the in-process event bus of the dashboard. Publishers
+// never block: every subscriber has a buffer and events that do not fit are
+// dropped and counted.
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Topic names a kind of event.
+type Topic string
+
+// Event is anything published on the bus. Each event type belongs to one
+// topic.
+type Event interface {
+	Topic() Topic
+}
+
+// DefaultBuffer is a reasonable buffer size for subscribers that keep up
+// with the UI.
+const DefaultBuffer = 64
+
+// subscriber is the untyped side of a Subscription.
+type subscriber interface {
+	deliver(event Event)
+	close()
+}
+
+// Bus delivers events to subscribers by topic. A nil *Bus drops everything,
+// so components can publish without checking whether a bus exists.
+type Bus struct {
+	mu     sync.RWMutex
+	topics map[Topic]map[subscriber]bool
+	all    map[subscriber]bool
+	closed bool
+
+	dropMu sync.Mutex
+	drops  map[Topic]uint64
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		topics: make(map[Topic]map[subscriber]bool),
+		all:    make(map[subscriber]bool),
+		drops:  make(map[Topic]uint64),
+	}
+}
+
+// Publish hands event to every subscriber of its topic. Subscribers whose
+// buffer is full miss it.
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.topics[event.Topic()] {
+		sub.deliver(event)
+	}
+	for sub := range b.all {
+		sub.deliver(event)
+	}
+}
+
+// Dropped returns how many events each topic lost to full subscribers since
+// the bus was created.
+func (b *Bus) Dropped() map[Topic]uint64 {
+	if b == nil {
+		return nil
+	}
+
+	b.dropMu.Lock()
+	defer b.dropMu.Unlock()
+	drops := make(map[Topic]uint64, len(b.drops))
+	for topic, count := range b.drops {
+		drops[topic] = count
+	}
+	return drops
+}
+
+// Close closes the channel of every subscription. Later subscriptions start
+// closed and publishing does nothing.
+func (b *Bus) Close() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for _, subs := range b.topics {
+		for sub := range subs {
+			sub.close()
+		}
+	}
+	for sub := range b.all {
+		sub.close()
+	}
+	b.topics = make(map[Topic]map[subscriber]bool)
+	b.all = make(map[subscriber]bool)
+}
+
+func (b *Bus) dropped(topic Topic) {
+	b.dropMu.Lock()
+	defer b.dropMu.Unlock()
+	b.drops[topic]++
+}
+
+// add registers sub for topic, or for every topic if all is set.
+func (b *Bus) add(sub subscriber, topic Topic, all bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.close()
+		return
+	}
+	if all {
+		b.all[sub] = true
+		return
+	}
+	if b.topics[topic] == nil {
+		b.topics[topic] = make(map[subscriber]bool)
+	}
+	b.topics[topic][sub] = true
+}
+
+func (b *Bus) remove(sub subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	for _, subs := range b.topics {
+		if subs[sub] {
+			delete(subs, sub)
+			sub.close()
+		}
+	}
+	if b.all[sub] {
+		delete(b.all, sub)
+		sub.close()
+	}
+}
+
+// Subscription receives the events of one type, or of every type for
+// SubscribeAll.
+type Subscription[E Event] struct {
+	bus     *Bus
+	ch      chan E
+	dropped atomic.Uint64
+	once    sync.Once
+}
+
+// Subscribe returns a subscription to the events of type E with room for
+// buffer undelivered events. E must be a concrete event type, use
+// SubscribeAll for every event.
+func Subscribe[E Event](b *Bus, buffer int) *Subscription[E] {
+	var zero E
+	sub := &Subscription[E]{bus: b, ch: make(chan E, buffer)}
+	if b == nil {
+		sub.close()
+		return sub
+	}
+	b.add(sub, zero.Topic(), false)
+	return sub
+}
+
+// SubscribeAll returns a subscription to every event on the bus.
+func SubscribeAll(b *Bus, buffer int) *Subscription[Event] {
+	sub := &Subscription[Event]{bus: b, ch: make(chan Event, buffer)}
+	if b == nil {
+		sub.close()
+		return sub
+	}
+	b.add(sub, "", true)
+	return sub
+}
+
+// C returns the channel the events arrive on. It is closed when the
+// subscription or the bus is closed.
+func (s *Subscription[E]) C() <-chan E {
+	return s.ch
+}
+
+// Dropped returns how many events this subscription missed because its
+// buffer was full.
+func (s *Subscription[E]) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close stops delivery and closes the channel.
+func (s *Subscription[E]) Close() {
+	if s.bus == nil {
+		return
+	}
+	s.bus.remove(s)
+}
+
+// deliver is called with the bus read lock held, so close cannot run at the
+// same time.
+func (s *Subscription[E]) deliver(event Event) {
+	typed, ok := event.(E)
+	if !ok {
+		return
+	}
+	select {
+	case s.ch <- typed:
+	default:
+		s.dropped.Add(1)
+		s.bus.dropped(event.Topic())
+	}
+}
+
+func (s *Subscription[E]) close() {
+	s.once.Do(func() {
+		close(s.ch)
+	})
+}
@@ -0,0 +1,80 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBusDelivery(t *testing.T) {
+	bus := NewBus()
+	defer bus.Close()
+
+	pages := Subscribe[PageChanged](bus, 4)
+	all := SubscribeAll(bus, 4)
+
+	bus.Publish(PageChanged{Index: 1, Name: "Climate"})
+	bus.Publish(Error{Source: "clock", Message: "boom"})
+
+	require.Len(t, pages.C(), 1)
+	assert.Equal(t, PageChanged{Index: 1, Name: "Climate"}, <-pages.C())
+
+	require.Len(t, all.C(), 2)
+	assert.Equal(t, PageChangedTopic, (<-all.C()).Topic())
+	assert.Equal(t, ErrorTopic, (<-all.C()).Topic())
+}
+
+func TestBusDrops(t *testing.T) {
+	bus := NewBus()
+	defer bus.Close()
+
+	slow := Subscribe[WidgetData](bus, 1)
+	fast := Subscribe[WidgetData](bus, 8)
+
+	for i := range 3 {
+		bus.Publish(WidgetData{ID: "clock", Data: i})
+	}
+
+	assert.Equal(t, uint64(2), slow.Dropped())
+	assert.Zero(t, fast.Dropped())
+	assert.Equal(t, map[Topic]uint64{WidgetDataTopic: 2}, bus.Dropped())
+
+	// The oldest events are kept
+	assert.Equal(t, 0, (<-slow.C()).Data)
+	assert.Len(t, fast.C(), 3)
+}
+
+func TestBusClose(t *testing.T) {
+	bus := NewBus()
+	sub := Subscribe[ConfigReloaded](bus, 1)
+	other := SubscribeAll(bus, 1)
+
+	sub.Close()
+	_, ok := <-sub.C()
+	assert.False(t, ok)
+	sub.Close()
+
+	bus.Close()
+	_, ok = <-other.C()
+	assert.False(t, ok)
+
+	// Nothing is delivered after closing
+	bus.Publish(ConfigReloaded{Title: "late"})
+	late := Subscribe[ConfigReloaded](bus, 1)
+	_, ok = <-late.C()
+	assert.False(t, ok)
+	other.Close()
+}
+
+func TestNilBus(t *testing.T) {
+	var bus *Bus
+	bus.Publish(Error{Source: "clock"})
+	bus.Close()
+	assert.Nil(t, bus.Dropped())
+
+	sub := SubscribeAll(bus, 1)
+	_, ok := <-sub.C()
+	assert.False(t, ok)
+	sub.Close()
+}
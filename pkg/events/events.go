@@ -0,0 +1,90 @@
+package events
+
+import "time"
+
+const (
+	IntegrationHealthTopic Topic = "integration_health"
+	WidgetDataTopic        Topic = "widget_data"
+	ConfigReloadedTopic    Topic = "config_reloaded"
+	PageChangedTopic       Topic = "page_changed"
+	ErrorTopic             Topic = "error"
+	AlertTopic             Topic = "alert"
+	AlertAcknowledgedTopic Topic = "alert_acknowledged"
+)
+
+// IntegrationHealth is published when an integration connects, drops or
+// otherwise changes health.
+type IntegrationHealth struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+func (IntegrationHealth) Topic() Topic { return IntegrationHealthTopic }
+
+// WidgetData is published when a widget has new data to show.
+type WidgetData struct {
+	ID   string      `json:"id"`
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+func (WidgetData) Topic() Topic { return WidgetDataTopic }
+
+// ConfigReloaded is published after the config file was loaded again. Error
+// is set if the new config was rejected and the old one kept.
+type ConfigReloaded struct {
+	Title string `json:"title"`
+	Error string `json:"error,omitempty"`
+}
+
+func (ConfigReloaded) Topic() Topic { return ConfigReloadedTopic }
+
+// PageChanged is published when the dashboard shows another page.
+type PageChanged struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+}
+
+func (PageChanged) Topic() Topic { return PageChangedTopic }
+
+// Error is published when part of the dashboard fails, like a widget that
+// cannot be created or an integration that does not start.
+type Error struct {
+	// Source is the widget ID or integration name.
+	Source  string `json:"source"`
+	Message string `json:"message"`
+}
+
+func (Error) Topic() Topic { return ErrorTopic }
+
+// Alert is published when a condition that needs someone's attention starts,
+// changes or ends, like a low glucose reading. Condition is empty once it
+// ended.
+type Alert struct {
+	// ID identifies the alert across updates. The dashboard shows alerts of
+	// widgets, whose ID it is.
+	ID        string `json:"id"`
+	Condition string `json:"condition,omitempty"`
+	Urgent    bool   `json:"urgent,omitempty"`
+	Message   string `json:"message,omitempty"`
+	// Snooze is how long snoozing hides the alert.
+	Snooze time.Duration `json:"snooze,omitempty"`
+}
+
+func (Alert) Topic() Topic { return AlertTopic }
+
+// Active reports whether the condition still holds.
+func (a Alert) Active() bool {
+	return a.Condition != ""
+}
+
+// AlertAcknowledged is published when someone acknowledges or snoozes an
+// alert. Acknowledged alerts have no Until and stay hidden until their
+// condition changes.
+type AlertAcknowledged struct {
+	ID    string    `json:"id"`
+	Until time.Time `json:"until,omitzero"`
+}
+
+func (AlertAcknowledged) Topic() Topic { return AlertAcknowledgedTopic }
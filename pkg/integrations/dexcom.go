@@ -1,50 +1,174 @@
 package integrations
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
 	"github.com/mntndev/dash/pkg/config"
 	dexcomshare "github.com/tgiv014/dexcom-share"
 )
 
-type DexcomProvider interface {
-	GetDexcomClient() *DexcomClient
+const (
+	// dexcomReadingInterval is how often the sensor takes a reading.
+	dexcomReadingInterval = 5 * time.Minute
+	// dexcomUploadDelay is how long after its timestamp a reading usually
+	// shows up on Dexcom Share.
+	dexcomUploadDelay = 15 * time.Second
+	// dexcomLatePoll is how often Share is asked while a reading is overdue.
+	dexcomLatePoll       = time.Minute
+	dexcomRequestTimeout = 30 * time.Second
+	// DexcomHistory is how far back readings are fetched.
+	DexcomHistory = 3 * time.Hour
+)
+
+// errDexcomSessionExpired is returned by requests Dexcom Share rejects
+// because the session is no longer valid.
+var errDexcomSessionExpired = errors.New("dexcom share session expired")
+
+// dexcomSessionErrors are the error codes Dexcom Share answers with when the
+// session has to be renewed.
+var dexcomSessionErrors = map[string]bool{
+	"SessionIdNotFound": true,
+	"SessionNotValid":   true,
 }
 
+// DexcomClient keeps one Dexcom Share session and polls it for readings,
+// shortly after the sensor should have uploaded the next one. Widgets
+// subscribe to be told about new readings instead of fetching on their own.
 type DexcomClient struct {
-	config         *config.DexcomConfig
+	glucoseNotifier
+
+	config     *config.DexcomConfig
+	httpClient *http.Client
+	maxHistory int
+	ctx        context.Context
+	cancel     context.CancelFunc
+
+	// session is only used by the poll loop
+	session *dexcomshare.Client
+
 	mu             sync.RWMutex
 	lastEntry      *dexcomshare.GlucoseEntry
 	lastUpdate     time.Time
 	historicalData []dexcomshare.GlucoseEntry
-	maxHistory     int
+	lastErr        error
 }
 
 func NewDexcomClient(cfg *config.DexcomConfig) *DexcomClient {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &DexcomClient{
-		config:         cfg,
+		config: cfg,
+		httpClient: &http.Client{
+			Timeout:   dexcomRequestTimeout,
+			Transport: &dexcomTransport{base: http.DefaultTransport},
+		},
 		historicalData: make([]dexcomshare.GlucoseEntry, 0),
 		maxHistory:     36, // 3 hours of 5-minute readings
+		ctx:            ctx,
+		cancel:         cancel,
 	}
 }
 
-func (dc *DexcomClient) FetchGlucoseData() error {
-	client, err := dexcomshare.NewClient(dc.config.Username, dc.config.Password)
-	if err != nil {
-		return fmt.Errorf("failed to create Dexcom client: %w", err)
+func createDexcomClient(node ast.Node) (Integration, error) {
+	var cfg config.DexcomConfig
+	if node != nil {
+		if err := yaml.NodeToValue(node, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse dexcom config: %w", err)
+		}
 	}
+	return NewDexcomClient(&cfg), nil
+}
 
-	log.Printf("Making Dexcom API request...")
-	entries, err := client.ReadGlucose(180, dc.maxHistory) // 3 hours instead of 24
-	if err != nil {
-		return fmt.Errorf("failed to read glucose: %w", err)
+func (dc *DexcomClient) Name() string {
+	return DexcomName
+}
+
+// Start polls Dexcom Share in the background until Stop is called.
+func (dc *DexcomClient) Start() error {
+	go dc.run()
+	return nil
+}
+
+func (dc *DexcomClient) Stop() error {
+	dc.cancel()
+	dc.stop()
+	return nil
+}
+
+// Health is degraded when the last fetch failed.
+func (dc *DexcomClient) Health() Health {
+	dc.mu.RLock()
+	defer dc.mu.RUnlock()
+	return dc.health()
+}
+
+func (dc *DexcomClient) health() Health {
+	if dc.lastErr != nil {
+		return Health{Status: Degraded, Message: "Dexcom: " + dc.lastErr.Error()}
+	}
+	return Health{Status: Healthy}
+}
+
+// run fetches readings until the client is stopped, waiting for the next
+// reading after each one.
+func (dc *DexcomClient) run() {
+	pollGlucose(dc.ctx, "Dexcom", dc.refresh, dc.nextPoll)
+}
+
+// nextPoll returns how long to wait for the reading after the latest one.
+// Overdue readings are asked for every dexcomLatePoll.
+func (dc *DexcomClient) nextPoll(now time.Time) time.Duration {
+	dc.mu.RLock()
+	var taken time.Time
+	if dc.lastEntry != nil {
+		taken = DexcomReadingTime(*dc.lastEntry)
 	}
+	dc.mu.RUnlock()
+
+	if taken.IsZero() {
+		return dexcomLatePoll
+	}
+	return max(taken.Add(dexcomReadingInterval+dexcomUploadDelay).Sub(now), dexcomLatePoll)
+}
 
+// refresh fetches the readings and tells the subscribers about them.
+func (dc *DexcomClient) refresh() error {
+	err := dc.fetch()
+
+	dc.mu.Lock()
+	changed := (err == nil) != (dc.lastErr == nil)
+	dc.lastErr = err
+	health := dc.health()
+	dc.mu.Unlock()
+
+	if changed {
+		dc.notifyHealth(health)
+	}
+	if err == nil {
+		dc.notify()
+	}
+	return err
+}
+
+func (dc *DexcomClient) fetch() error {
+	entries, err := dc.readGlucose()
+	if err != nil {
+		return err
+	}
 	if len(entries) == 0 {
-		return fmt.Errorf("no glucose readings available")
+		return ErrNoGlucoseData
 	}
 
 	dc.mu.Lock()
@@ -56,12 +180,39 @@ func (dc *DexcomClient) FetchGlucoseData() error {
 	return nil
 }
 
+// readGlucose reads with the current session and logs in again if there is
+// none or Dexcom Share rejects it.
+func (dc *DexcomClient) readGlucose() ([]dexcomshare.GlucoseEntry, error) {
+	for {
+		renewed := false
+		if dc.session == nil {
+			session, err := dexcomshare.NewClient(dc.config.Username, dc.config.Password, dexcomshare.WithClient(dc.httpClient))
+			if err != nil {
+				return nil, fmt.Errorf("failed to log in to Dexcom Share: %w", err)
+			}
+			dc.session = session
+			renewed = true
+		}
+
+		entries, err := dc.session.ReadGlucose(int(DexcomHistory.Minutes()), dc.maxHistory)
+		if errors.Is(err, errDexcomSessionExpired) && !renewed {
+			log.Printf("Dexcom Share session expired, logging in again")
+			dc.session = nil
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read glucose: %w", err)
+		}
+		return entries, nil
+	}
+}
+
 func (dc *DexcomClient) GetLatestGlucose() (*dexcomshare.GlucoseEntry, time.Time, error) {
 	dc.mu.RLock()
 	defer dc.mu.RUnlock()
 
 	if dc.lastEntry == nil {
-		return nil, time.Time{}, fmt.Errorf("no glucose data available")
+		return nil, time.Time{}, ErrNoGlucoseData
 	}
 
 	entry := *dc.lastEntry
@@ -77,3 +228,97 @@ func (dc *DexcomClient) GetHistoricalGlucose() ([]dexcomshare.GlucoseEntry, erro
 	copy(historicalCopy, dc.historicalData)
 	return historicalCopy, nil
 }
+
+// Glucose returns the readings fetched last.
+func (dc *DexcomClient) Glucose() (GlucoseData, error) {
+	dc.mu.RLock()
+	defer dc.mu.RUnlock()
+
+	if dc.lastEntry == nil {
+		return GlucoseData{}, ErrNoGlucoseData
+	}
+	readings := make([]GlucoseReading, 0, len(dc.historicalData))
+	for _, entry := range dc.historicalData {
+		readings = append(readings, GlucoseReading{
+			Value: entry.Value,
+			Trend: entry.Trend,
+			Time:  DexcomReadingTime(entry),
+		})
+	}
+	return GlucoseData{Readings: readings, Updated: dc.lastUpdate}, nil
+}
+
+// dexcomTransport turns the errors Dexcom Share reports for an invalid
+// session into errDexcomSessionExpired. The dexcom-share package only
+// returns a generic error for failed requests.
+type dexcomTransport struct {
+	base http.RoundTripper
+}
+
+func (t *dexcomTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode == http.StatusOK {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var shareErr struct {
+		Code string `json:"Code"`
+	}
+	if json.Unmarshal(body, &shareErr) == nil && dexcomSessionErrors[shareErr.Code] {
+		return nil, errDexcomSessionExpired
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// DexcomReadingTime returns when a reading was taken, from its WT timestamp
+// or DT as a fallback. It returns the zero time if neither parses.
+func DexcomReadingTime(entry dexcomshare.GlucoseEntry) time.Time {
+	if taken := parseDexcomTimestamp(entry.WT); !taken.IsZero() {
+		return taken
+	}
+	return parseDexcomTimestamp(entry.DT)
+}
+
+func parseDexcomTimestamp(dateStr string) time.Time {
+	if dateStr == "" {
+		return time.Time{}
+	}
+
+	var timestampStr string
+
+	// Dexcom timestamps can come in different formats: "/Date(milliseconds)/" or "Date(milliseconds)"
+	switch {
+	case strings.HasPrefix(dateStr, "/Date(") && strings.HasSuffix(dateStr, ")/"):
+		// Format: /Date(milliseconds)/
+		timestampStr = strings.TrimPrefix(dateStr, "/Date(")
+		timestampStr = strings.TrimSuffix(timestampStr, ")/")
+	case strings.HasPrefix(dateStr, "Date(") && strings.HasSuffix(dateStr, ")"):
+		timestampStr = strings.TrimPrefix(dateStr, "Date(")
+		timestampStr = strings.TrimSuffix(timestampStr, ")")
+	default:
+		return time.Time{}
+	}
+
+	// Handle timezone offset if present (like "-0600")
+	if idx := strings.LastIndex(timestampStr, "+"); idx > 0 {
+		timestampStr = timestampStr[:idx]
+	} else if idx := strings.LastIndex(timestampStr, "-"); idx > 0 {
+		timestampStr = timestampStr[:idx]
+	}
+
+	// Parse milliseconds
+	milliseconds, err := strconv.ParseInt(timestampStr, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	// Convert to time.Time (Dexcom uses milliseconds since Unix epoch)
+	return time.Unix(milliseconds/1000, (milliseconds%1000)*1000000).UTC()
+}
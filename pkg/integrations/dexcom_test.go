@@ -0,0 +1,187 @@
+package integrations
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mntndev/dash/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dexcomshare "github.com/tgiv014/dexcom-share"
+)
+
+// fakeShare answers like Dexcom Share. Every login hands out a new session,
+// expire invalidates the current one.
+type fakeShare struct {
+	mu       sync.Mutex
+	logins   int
+	reads    int
+	session  string
+	failAuth bool
+	entries  []dexcomshare.GlucoseEntry
+}
+
+func (s *fakeShare) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/General/AuthenticatePublisherAccount"):
+		if s.failAuth {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprint(w, `{"Code":"AccountPasswordInvalid"}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `"account"`)
+	case strings.HasSuffix(r.URL.Path, "/General/LoginPublisherAccountById"):
+		s.logins++
+		s.session = fmt.Sprintf("session-%d", s.logins)
+		_ = json.NewEncoder(w).Encode(s.session)
+	case strings.HasSuffix(r.URL.Path, "/Publisher/ReadPublisherLatestGlucoseValues"):
+		s.reads++
+		if body["sessionId"] != s.session {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = fmt.Fprint(w, `{"Code":"SessionIdNotFound","Message":"Session ID not found"}`)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(s.entries)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *fakeShare) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.session = ""
+}
+
+func (s *fakeShare) counts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins, s.reads
+}
+
+// redirectTransport sends every request to the test server.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newTestDexcomClient(t *testing.T, share *fakeShare) *DexcomClient {
+	t.Helper()
+	server := httptest.NewServer(share)
+	t.Cleanup(server.Close)
+	target, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	client := NewDexcomClient(&config.DexcomConfig{Username: "user", Password: "secret"})
+	client.httpClient.Transport = &dexcomTransport{base: redirectTransport{target: target}}
+	t.Cleanup(func() { _ = client.Stop() })
+	return client
+}
+
+func dexcomEntry(value int, taken time.Time) dexcomshare.GlucoseEntry {
+	return dexcomshare.GlucoseEntry{
+		Value: value,
+		Trend: "Flat",
+		WT:    fmt.Sprintf("Date(%d)", taken.UnixMilli()),
+	}
+}
+
+func TestDexcomClientReusesSession(t *testing.T) {
+	share := &fakeShare{entries: []dexcomshare.GlucoseEntry{dexcomEntry(120, time.Now())}}
+	client := newTestDexcomClient(t, share)
+
+	first, second := client.Subscribe(), client.Subscribe()
+
+	require.NoError(t, client.refresh())
+	require.NoError(t, client.refresh())
+
+	logins, reads := share.counts()
+	assert.Equal(t, 1, logins)
+	assert.Equal(t, 2, reads)
+
+	// Every subscriber is told, updates are coalesced
+	assert.Len(t, first, 1)
+	assert.Len(t, second, 1)
+
+	latest, _, err := client.GetLatestGlucose()
+	require.NoError(t, err)
+	assert.Equal(t, 120, latest.Value)
+}
+
+func TestDexcomClientRenewsExpiredSession(t *testing.T) {
+	share := &fakeShare{entries: []dexcomshare.GlucoseEntry{dexcomEntry(120, time.Now())}}
+	client := newTestDexcomClient(t, share)
+
+	require.NoError(t, client.refresh())
+	share.expire()
+	require.NoError(t, client.refresh())
+
+	logins, reads := share.counts()
+	assert.Equal(t, 2, logins)
+	assert.Equal(t, 3, reads)
+	assert.Equal(t, Healthy, client.Health().Status)
+}
+
+func TestDexcomClientHealth(t *testing.T) {
+	share := &fakeShare{failAuth: true}
+	client := newTestDexcomClient(t, share)
+	updates := client.SubscribeHealth()
+
+	assert.Error(t, client.refresh())
+	assert.Equal(t, Degraded, client.Health().Status)
+	assert.Equal(t, Degraded, (<-updates).Status)
+
+	share.mu.Lock()
+	share.failAuth = false
+	share.entries = []dexcomshare.GlucoseEntry{dexcomEntry(95, time.Now())}
+	share.mu.Unlock()
+
+	require.NoError(t, client.refresh())
+	assert.Equal(t, Healthy, (<-updates).Status)
+
+	// Only changes are reported
+	require.NoError(t, client.refresh())
+	assert.Empty(t, updates)
+}
+
+func TestDexcomClientNextPoll(t *testing.T) {
+	client := NewDexcomClient(&config.DexcomConfig{})
+	now := time.Now()
+
+	assert.Equal(t, dexcomLatePoll, client.nextPoll(now), "no reading yet")
+
+	entry := dexcomEntry(120, now.Add(-time.Minute))
+	client.lastEntry = &entry
+	assert.Equal(t, 4*time.Minute+dexcomUploadDelay, client.nextPoll(now).Round(time.Second))
+
+	entry = dexcomEntry(120, now.Add(-10*time.Minute))
+	client.lastEntry = &entry
+	assert.Equal(t, dexcomLatePoll, client.nextPoll(now), "overdue reading")
+}
+
+func TestDexcomReadingTime(t *testing.T) {
+	taken := time.UnixMilli(1700000000000).UTC()
+	assert.Equal(t, taken, DexcomReadingTime(dexcomshare.GlucoseEntry{WT: "Date(1700000000000)"}))
+	assert.Equal(t, taken, DexcomReadingTime(dexcomshare.GlucoseEntry{WT: "/Date(1700000000000-0600)/"}))
+	assert.Equal(t, taken, DexcomReadingTime(dexcomshare.GlucoseEntry{WT: "bogus", DT: "Date(1700000000000)"}))
+	assert.True(t, DexcomReadingTime(dexcomshare.GlucoseEntry{}).IsZero())
+}
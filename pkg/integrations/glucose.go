@@ -0,0 +1,185 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	glucoseRetryMinDelay = 10 * time.Second
+	glucoseRetryMaxDelay = 5 * time.Minute
+)
+
+// ErrNoGlucoseData is returned by glucose sources that have no readings yet.
+var ErrNoGlucoseData = errors.New("no glucose data available")
+
+// GlucoseSource is implemented by integrations that provide continuous
+// glucose monitor readings, so glucose widgets work with any of them.
+type GlucoseSource interface {
+	Integration
+	// Glucose returns the readings fetched last.
+	Glucose() (GlucoseData, error)
+	// Subscribe returns a channel that receives a value whenever new
+	// readings were fetched. Updates are coalesced for slow readers. The
+	// channel is closed when the source stops.
+	Subscribe() <-chan struct{}
+	Unsubscribe(ch <-chan struct{})
+}
+
+// IsGlucoseSource reports whether the built-in integration configured under
+// name is a GlucoseSource.
+func IsGlucoseSource(name string) bool {
+	return name == DexcomName || name == NightscoutName
+}
+
+// GlucoseData is what a glucose source knows about the wearer.
+type GlucoseData struct {
+	// Readings come newest first.
+	Readings []GlucoseReading
+	// Updated is when the readings were fetched.
+	Updated time.Time
+	// Device is nil unless the source reports the state of the devices.
+	Device *GlucoseDevice
+}
+
+// GlucoseReading is one sensor reading.
+type GlucoseReading struct {
+	// Value is in mg/dL.
+	Value int
+	// Trend is the direction as reported by the source, a Dexcom trend name
+	// or number.
+	Trend string
+	Time  time.Time
+}
+
+// GlucoseDevice is the latest state reported by the uploader and pump. Fields
+// the devices do not report are nil.
+type GlucoseDevice struct {
+	Name string `json:"name,omitempty"`
+	// Battery is the uploader battery in percent.
+	Battery *int `json:"battery,omitempty"`
+	// IOB is the insulin on board in units.
+	IOB *float64 `json:"iob,omitempty"`
+	// COB is the carbs on board in grams.
+	COB  *float64  `json:"cob,omitempty"`
+	Time time.Time `json:"time"`
+}
+
+// glucoseNotifier tells subscribers of a glucose source about new readings
+// and health changes.
+type glucoseNotifier struct {
+	mu              sync.Mutex
+	stopped         bool
+	listeners       []chan struct{}
+	healthListeners []chan Health
+}
+
+// Subscribe returns a channel that receives a value whenever new readings
+// were fetched. Updates are coalesced for slow readers. The channel is
+// closed when the source stops.
+func (n *glucoseNotifier) Subscribe() <-chan struct{} {
+	ch := make(chan struct{}, 1)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stopped {
+		close(ch)
+		return ch
+	}
+	n.listeners = append(n.listeners, ch)
+	return ch
+}
+
+func (n *glucoseNotifier) Unsubscribe(ch <-chan struct{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for i, listener := range n.listeners {
+		if listener == ch {
+			close(listener)
+			n.listeners = append(n.listeners[:i], n.listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+// SubscribeHealth returns a channel that receives the health whenever
+// fetching starts or stops failing. The channel is closed when the source
+// stops.
+func (n *glucoseNotifier) SubscribeHealth() <-chan Health {
+	ch := make(chan Health, 10)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stopped {
+		close(ch)
+		return ch
+	}
+	n.healthListeners = append(n.healthListeners, ch)
+	return ch
+}
+
+func (n *glucoseNotifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, ch := range n.listeners {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (n *glucoseNotifier) notifyHealth(health Health) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, ch := range n.healthListeners {
+		select {
+		case ch <- health:
+		default:
+		}
+	}
+}
+
+// stop closes the channels of all subscribers.
+func (n *glucoseNotifier) stop() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.stopped = true
+	for _, ch := range n.listeners {
+		close(ch)
+	}
+	n.listeners = nil
+	for _, ch := range n.healthListeners {
+		close(ch)
+	}
+	n.healthListeners = nil
+}
+
+// pollGlucose calls refresh until ctx is done. After a successful fetch it
+// waits as long as next says, after a failure it backs off.
+func pollGlucose(ctx context.Context, name string, refresh func() error, next func(now time.Time) time.Duration) {
+	retry := glucoseRetryMinDelay
+
+	for {
+		wait := retry
+		if err := refresh(); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Failed to fetch %s readings, retrying in %s: %v", name, retry, err)
+			retry = min(retry*2, glucoseRetryMaxDelay)
+		} else {
+			retry = glucoseRetryMinDelay
+			wait = next(time.Now())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,531 @@
+// Package hatest provides a fake Home Assistant websocket server for tests.
+//
+// The server speaks enough of the websocket API for HomeAssistantClient:
+// authentication, get_states, call_service, subscribe_events,
+// subscribe_entities, unsubscribe_events, history/history_during_period and
+// ping. Tests change entity states through the server, which pushes them to
+// subscribed clients and records them in the entity's history.
+package hatest
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Version is the Home Assistant version reported during authentication.
+const Version = "2024.6.0"
+
+// State is an entity state as served by get_states.
+type State struct {
+	EntityID    string                 `json:"entity_id"`
+	State       string                 `json:"state"`
+	Attributes  map[string]interface{} `json:"attributes"`
+	LastChanged time.Time              `json:"last_changed"`
+	LastUpdated time.Time              `json:"last_updated"`
+}
+
+// ServiceCall records a call_service command received by the server.
+type ServiceCall struct {
+	Domain  string                 `json:"domain"`
+	Service string                 `json:"service"`
+	Data    map[string]interface{} `json:"service_data"`
+}
+
+// ServiceHandler decides the outcome of a service call. A non-nil error is
+// sent back to the client as a failed result.
+type ServiceHandler func(call ServiceCall) error
+
+// Server is a fake Home Assistant instance. Entity states and service calls
+// are kept in memory.
+type Server struct {
+	// Token is the access token clients must authenticate with.
+	Token string
+
+	httpServer *httptest.Server
+	upgrader   websocket.Upgrader
+
+	mu                  sync.Mutex
+	states              map[string]State
+	history             map[string][]State
+	conns               map[*conn]bool
+	calls               []ServiceCall
+	ignored             map[string]bool
+	noSubscribeEntities bool
+	serviceHandler      ServiceHandler
+}
+
+type conn struct {
+	ws         *websocket.Conn
+	writeMu    sync.Mutex
+	eventSubs  map[int]string
+	entitySubs map[int]map[string]bool
+}
+
+// NewServer starts a server that accepts token. It is closed when the test
+// finishes.
+func NewServer(t testing.TB, token string) *Server {
+	t.Helper()
+
+	s := &Server{
+		Token:   token,
+		states:  make(map[string]State),
+		history: make(map[string][]State),
+		conns:   make(map[*conn]bool),
+		ignored: make(map[string]bool),
+	}
+	s.httpServer = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// URL returns the websocket URL clients connect to.
+func (s *Server) URL() string {
+	return "ws" + strings.TrimPrefix(s.httpServer.URL, "http") + "/api/websocket"
+}
+
+// Close drops all connections and stops the server.
+func (s *Server) Close() {
+	s.DropConnections()
+	s.httpServer.Close()
+}
+
+// SetState creates or updates an entity and pushes the change to subscribed
+// clients.
+func (s *Server) SetState(entityID, state string, attributes map[string]interface{}) {
+	if attributes == nil {
+		attributes = make(map[string]interface{})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	oldState, existed := s.states[entityID]
+	newState := State{
+		EntityID:    entityID,
+		State:       state,
+		Attributes:  attributes,
+		LastChanged: now,
+		LastUpdated: now,
+	}
+	if existed && oldState.State == state {
+		newState.LastChanged = oldState.LastChanged
+	}
+	s.states[entityID] = newState
+	s.history[entityID] = append(s.history[entityID], newState)
+
+	var old *State
+	if existed {
+		old = &oldState
+	}
+	s.pushChange(entityID, old, &newState)
+}
+
+// AddHistory records a past state of entityID without changing its current
+// state. Entries must be added oldest first.
+func (s *Server) AddHistory(entityID, state string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history[entityID] = append(s.history[entityID], State{
+		EntityID:    entityID,
+		State:       state,
+		LastChanged: at.UTC(),
+		LastUpdated: at.UTC(),
+	})
+}
+
+// RemoveState deletes an entity and pushes the removal to subscribed clients.
+func (s *Server) RemoveState(entityID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	oldState, existed := s.states[entityID]
+	if !existed {
+		return
+	}
+	delete(s.states, entityID)
+	s.pushChange(entityID, &oldState, nil)
+}
+
+// Calls returns the service calls received so far.
+func (s *Server) Calls() []ServiceCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ServiceCall(nil), s.calls...)
+}
+
+// SetServiceHandler replaces the default handler, which accepts every call.
+func (s *Server) SetServiceHandler(handler ServiceHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serviceHandler = handler
+}
+
+// SetIgnored makes the server silently drop commands of msgType, so clients
+// waiting for their result run into timeouts.
+func (s *Server) SetIgnored(msgType string, ignored bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ignored[msgType] = ignored
+}
+
+// DisableSubscribeEntities makes the server answer subscribe_entities with
+// unknown_command, like Home Assistant releases before it was added.
+func (s *Server) DisableSubscribeEntities() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.noSubscribeEntities = true
+}
+
+// DropConnections closes every client connection, simulating a restart.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		if err := c.ws.Close(); err != nil {
+			log.Printf("hatest: failed to close connection: %v", err)
+		}
+		delete(s.conns, c)
+	}
+}
+
+// Connections returns the number of authenticated connections.
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// Watching reports whether any client receives changes of entityID, either
+// through subscribe_entities or a state_changed subscription.
+func (s *Server) Watching(entityID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		for _, eventType := range c.eventSubs {
+			if eventType == "state_changed" {
+				return true
+			}
+		}
+		for _, entityIDs := range c.entitySubs {
+			if entityIDs[entityID] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &conn{
+		ws:         ws,
+		eventSubs:  make(map[int]string),
+		entitySubs: make(map[int]map[string]bool),
+	}
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		_ = ws.Close()
+	}()
+
+	if !s.authenticate(c) {
+		return
+	}
+
+	for {
+		var msg map[string]json.RawMessage
+		if err := ws.ReadJSON(&msg); err != nil {
+			return
+		}
+		s.handleCommand(c, msg)
+	}
+}
+
+func (s *Server) authenticate(c *conn) bool {
+	if err := c.send(map[string]interface{}{"type": "auth_required", "ha_version": Version}); err != nil {
+		return false
+	}
+
+	var auth struct {
+		Type        string `json:"type"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := c.ws.ReadJSON(&auth); err != nil {
+		return false
+	}
+
+	if auth.Type != "auth" || auth.AccessToken != s.Token {
+		_ = c.send(map[string]interface{}{"type": "auth_invalid", "message": "Invalid access token"})
+		return false
+	}
+
+	s.mu.Lock()
+	s.conns[c] = true
+	s.mu.Unlock()
+
+	return c.send(map[string]interface{}{"type": "auth_ok", "ha_version": Version}) == nil
+}
+
+func (s *Server) handleCommand(c *conn, msg map[string]json.RawMessage) {
+	var id int
+	var msgType string
+	_ = json.Unmarshal(msg["id"], &id)
+	_ = json.Unmarshal(msg["type"], &msgType)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ignored[msgType] {
+		return
+	}
+
+	switch msgType {
+	case "ping":
+		_ = c.send(map[string]interface{}{"id": id, "type": "pong"})
+	case "get_states":
+		states := make([]State, 0, len(s.states))
+		for _, state := range s.states {
+			states = append(states, state)
+		}
+		c.result(id, states)
+	case "call_service":
+		var call ServiceCall
+		_ = json.Unmarshal(msg["domain"], &call.Domain)
+		_ = json.Unmarshal(msg["service"], &call.Service)
+		_ = json.Unmarshal(msg["service_data"], &call.Data)
+		s.calls = append(s.calls, call)
+		if s.serviceHandler != nil {
+			if err := s.serviceHandler(call); err != nil {
+				c.error(id, "service_validation_error", err.Error())
+				return
+			}
+		}
+		c.result(id, map[string]interface{}{"context": map[string]string{"id": "hatest"}})
+	case "subscribe_events":
+		var eventType string
+		_ = json.Unmarshal(msg["event_type"], &eventType)
+		c.eventSubs[id] = eventType
+		c.result(id, nil)
+	case "subscribe_entities":
+		s.subscribeEntities(c, id, msg)
+	case "history/history_during_period":
+		s.historyDuringPeriod(c, id, msg)
+	case "unsubscribe_events":
+		var subscription int
+		_ = json.Unmarshal(msg["subscription"], &subscription)
+		_, isEvent := c.eventSubs[subscription]
+		_, isEntity := c.entitySubs[subscription]
+		if !isEvent && !isEntity {
+			c.error(id, "not_found", "Subscription not found.")
+			return
+		}
+		delete(c.eventSubs, subscription)
+		delete(c.entitySubs, subscription)
+		c.result(id, nil)
+	default:
+		c.error(id, "unknown_command", "Unknown command.")
+	}
+}
+
+// subscribeEntities handles subscribe_entities. Must be called with s.mu held.
+func (s *Server) subscribeEntities(c *conn, id int, msg map[string]json.RawMessage) {
+	if s.noSubscribeEntities {
+		c.error(id, "unknown_command", "Unknown command.")
+		return
+	}
+
+	var entityIDs []string
+	_ = json.Unmarshal(msg["entity_ids"], &entityIDs)
+	watched := make(map[string]bool, len(entityIDs))
+	for _, entityID := range entityIDs {
+		watched[entityID] = true
+	}
+	c.entitySubs[id] = watched
+	c.result(id, nil)
+
+	added := make(map[string]interface{})
+	for entityID, state := range s.states {
+		if watched[entityID] {
+			added[entityID] = compress(state)
+		}
+	}
+	_ = c.event(id, map[string]interface{}{"a": added})
+}
+
+// historyDuringPeriod answers a minimal history request. Like Home Assistant
+// it starts each entity's history with the state it had at start_time. Must
+// be called with s.mu held.
+func (s *Server) historyDuringPeriod(c *conn, id int, msg map[string]json.RawMessage) {
+	var start, end time.Time
+	var entityIDs []string
+	_ = json.Unmarshal(msg["start_time"], &start)
+	_ = json.Unmarshal(msg["end_time"], &end)
+	_ = json.Unmarshal(msg["entity_ids"], &entityIDs)
+	if end.IsZero() {
+		end = time.Now()
+	}
+
+	result := make(map[string][]map[string]interface{})
+	for _, entityID := range entityIDs {
+		history := s.history[entityID]
+		var states []map[string]interface{}
+		for i, state := range history {
+			at := state.LastUpdated
+			if at.After(end) {
+				break
+			}
+			if at.Before(start) {
+				if i+1 < len(history) && !history[i+1].LastUpdated.After(start) {
+					continue
+				}
+				at = start
+			}
+			states = append(states, map[string]interface{}{
+				"s":  state.State,
+				"lu": unixSeconds(at),
+			})
+		}
+		if len(states) > 0 {
+			result[entityID] = states
+		}
+	}
+	c.result(id, result)
+}
+
+// pushChange sends a state change to every subscribed client. Must be called
+// with s.mu held.
+func (s *Server) pushChange(entityID string, oldState, newState *State) {
+	for c := range s.conns {
+		for subID, eventType := range c.eventSubs {
+			if eventType != "state_changed" {
+				continue
+			}
+			_ = c.event(subID, map[string]interface{}{
+				"event_type": "state_changed",
+				"data": map[string]interface{}{
+					"entity_id": entityID,
+					"old_state": oldState,
+					"new_state": newState,
+				},
+				"origin":     "LOCAL",
+				"time_fired": time.Now().UTC(),
+			})
+		}
+
+		for subID, watched := range c.entitySubs {
+			if !watched[entityID] {
+				continue
+			}
+			_ = c.event(subID, entityDiff(entityID, oldState, newState))
+		}
+	}
+}
+
+// entityDiff builds the compressed subscribe_entities event for a change.
+func entityDiff(entityID string, oldState, newState *State) map[string]interface{} {
+	switch {
+	case newState == nil:
+		return map[string]interface{}{"r": []string{entityID}}
+	case oldState == nil:
+		return map[string]interface{}{"a": map[string]interface{}{entityID: compress(*newState)}}
+	}
+
+	add := map[string]interface{}{}
+	if newState.State != oldState.State {
+		add["s"] = newState.State
+	}
+	if !newState.LastChanged.Equal(oldState.LastChanged) {
+		add["lc"] = unixSeconds(newState.LastChanged)
+	} else {
+		add["lu"] = unixSeconds(newState.LastUpdated)
+	}
+
+	changed := map[string]interface{}{}
+	var removed []string
+	for k, v := range newState.Attributes {
+		if old, exists := oldState.Attributes[k]; !exists || !jsonEqual(old, v) {
+			changed[k] = v
+		}
+	}
+	for k := range oldState.Attributes {
+		if _, exists := newState.Attributes[k]; !exists {
+			removed = append(removed, k)
+		}
+	}
+	if len(changed) > 0 {
+		add["a"] = changed
+	}
+
+	diff := map[string]interface{}{"+": add}
+	if len(removed) > 0 {
+		diff["-"] = map[string]interface{}{"a": removed}
+	}
+	return map[string]interface{}{"c": map[string]interface{}{entityID: diff}}
+}
+
+func compress(state State) map[string]interface{} {
+	compressed := map[string]interface{}{
+		"s":  state.State,
+		"a":  state.Attributes,
+		"lc": unixSeconds(state.LastChanged),
+	}
+	if !state.LastUpdated.Equal(state.LastChanged) {
+		compressed["lu"] = unixSeconds(state.LastUpdated)
+	}
+	return compressed
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
+
+func jsonEqual(a, b interface{}) bool {
+	aData, errA := json.Marshal(a)
+	bData, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aData) == string(bData)
+}
+
+func (c *conn) send(msg interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.ws.WriteJSON(msg)
+}
+
+func (c *conn) result(id int, result interface{}) {
+	_ = c.send(map[string]interface{}{
+		"id":      id,
+		"type":    "result",
+		"success": true,
+		"result":  result,
+	})
+}
+
+func (c *conn) error(id int, code, message string) {
+	_ = c.send(map[string]interface{}{
+		"id":      id,
+		"type":    "result",
+		"success": false,
+		"error":   map[string]string{"code": code, "message": message},
+	})
+}
+
+func (c *conn) event(id int, event interface{}) error {
+	return c.send(map[string]interface{}{
+		"id":    id,
+		"type":  "event",
+		"event": event,
+	})
+}
@@ -6,30 +6,77 @@ import (
 	"fmt"
 	"log"
 	"net/url"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
 	"github.com/gorilla/websocket"
 	"github.com/mntndev/dash/pkg/config"
 )
 
-type HAProvider interface {
-	GetHAClient() *HomeAssistantClient
+// Reconnect delays grow exponentially between these bounds while Home
+// Assistant is unreachable.
+const (
+	reconnectMinDelay = time.Second
+	reconnectMaxDelay = time.Minute
+)
+
+// Heartbeat defaults, used when the config does not set them.
+const (
+	defaultPingInterval  = 30 * time.Second
+	defaultPingMaxMissed = 3
+)
+
+// ConnectionState describes where the client is in its connection lifecycle.
+type ConnectionState int
+
+const (
+	StateDisconnected ConnectionState = iota
+	StateConnecting
+	StateConnected
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	default:
+		return "unknown"
+	}
 }
 
 type HomeAssistantClient struct {
-	config        *config.HomeAssistantConfig
-	conn          *websocket.Conn
-	connected     bool
-	authenticated bool
-	msgID         int
-	callbacks     map[int]func(HAMessage)
-	mu            sync.RWMutex
-	writeMu       sync.Mutex
-	ctx           context.Context
-	cancel        context.CancelFunc
-	eventChan     chan HAEvent
-	authChan      chan bool
+	config         *config.HomeAssistantConfig
+	conn           *websocket.Conn
+	connDone       chan struct{}
+	connected      bool
+	authenticated  bool
+	state          ConnectionState
+	stateListeners []chan ConnectionState
+	closed         bool
+	msgID          int
+	pending        map[int]chan commandResult
+	eventHandlers  map[int]func(json.RawMessage)
+	mu             sync.RWMutex
+	writeMu        sync.Mutex
+	ctx            context.Context
+	cancel         context.CancelFunc
+	eventChan      chan HAEvent
+	authChan       chan bool
+	pingInterval   time.Duration
+	pingMaxMissed  int
+	latency        time.Duration
+
+	// states is the authoritative copy of every watched entity's state, kept
+	// current from subscribe_entities diffs or state_changed events
+	states   map[string]HAEntityState
+	statesMu sync.RWMutex
 	*SubscriptionManager
 }
 
@@ -37,9 +84,9 @@ type HAMessage struct {
 	ID      int                    `json:"id,omitempty"`
 	Type    string                 `json:"type"`
 	Success bool                   `json:"success,omitempty"`
-	Result  interface{}            `json:"result,omitempty"`
+	Result  json.RawMessage        `json:"result,omitempty"`
 	Error   *HAError               `json:"error,omitempty"`
-	Event   *HAEvent               `json:"event,omitempty"`
+	Event   json.RawMessage        `json:"event,omitempty"`
 	Data    map[string]interface{} `json:",inline"`
 }
 
@@ -48,6 +95,10 @@ type HAError struct {
 	Message string `json:"message"`
 }
 
+func (e *HAError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
 type HAEvent struct {
 	EventType string                 `json:"event_type"`
 	Data      map[string]interface{} `json:"data"`
@@ -75,22 +126,114 @@ type SubscriptionManager struct {
 	mu            sync.RWMutex
 	ctx           context.Context
 	cancel        context.CancelFunc
+
+	// State of the subscribe_entities subscription on the current connection
+	syncMu      sync.Mutex
+	entitySubID int
+	entityIDs   []string
+	legacy      bool
+	resyncTimer *time.Timer
 }
 
 func NewHomeAssistantClient(cfg *config.HomeAssistantConfig) *HomeAssistantClient {
 	ctx, cancel := context.WithCancel(context.Background())
+
+	pingInterval := defaultPingInterval
+	if cfg.PingInterval != "" {
+		if interval, err := time.ParseDuration(cfg.PingInterval); err == nil && interval > 0 {
+			pingInterval = interval
+		} else {
+			log.Printf("Invalid Home Assistant ping_interval %q, using %s", cfg.PingInterval, defaultPingInterval)
+		}
+	}
+	pingMaxMissed := defaultPingMaxMissed
+	if cfg.PingMaxMissed > 0 {
+		pingMaxMissed = cfg.PingMaxMissed
+	}
+
 	client := &HomeAssistantClient{
-		config:    cfg,
-		callbacks: make(map[int]func(HAMessage)),
-		ctx:       ctx,
-		cancel:    cancel,
-		eventChan: make(chan HAEvent, 100),
-		authChan:  make(chan bool, 1),
+		config:        cfg,
+		pending:       make(map[int]chan commandResult),
+		eventHandlers: make(map[int]func(json.RawMessage)),
+		states:        make(map[string]HAEntityState),
+		ctx:           ctx,
+		cancel:        cancel,
+		eventChan:     make(chan HAEvent, 100),
+		authChan:      make(chan bool, 1),
+		pingInterval:  pingInterval,
+		pingMaxMissed: pingMaxMissed,
 	}
 	client.SubscriptionManager = NewSubscriptionManager(client)
 	return client
 }
 
+func createHomeAssistantClient(node ast.Node) (Integration, error) {
+	var cfg config.HomeAssistantConfig
+	if node != nil {
+		if err := yaml.NodeToValue(node, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse home_assistant config: %w", err)
+		}
+	}
+	return NewHomeAssistantClient(&cfg), nil
+}
+
+func (ha *HomeAssistantClient) Name() string {
+	return HomeAssistantName
+}
+
+// Start runs the connection loop in the background. The client connects and
+// whenever the connection drops it reconnects with exponential backoff until
+// Stop is called. After every successful connect all active subscriptions
+// are replayed and subscribers receive the current entity state.
+func (ha *HomeAssistantClient) Start() error {
+	go ha.run()
+	return nil
+}
+
+func (ha *HomeAssistantClient) run() {
+	delay := reconnectMinDelay
+
+	for {
+		ha.setState(StateConnecting)
+		log.Printf("Connecting to Home Assistant...")
+
+		if err := ha.Connect(); err != nil {
+			log.Printf("Failed to connect to Home Assistant: %v", err)
+		} else {
+			log.Printf("Successfully connected to Home Assistant")
+			delay = reconnectMinDelay
+			ha.setState(StateConnected)
+
+			ha.mu.RLock()
+			conn, done := ha.conn, ha.connDone
+			ha.mu.RUnlock()
+
+			// Ping from the start so a server that stops answering during
+			// the initial sync is dropped as well
+			go ha.heartbeat(conn, done)
+			ha.syncStates()
+
+			select {
+			case <-done:
+				log.Printf("Lost connection to Home Assistant")
+			case <-ha.ctx.Done():
+			}
+		}
+
+		ha.setState(StateDisconnected)
+
+		select {
+		case <-ha.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, reconnectMaxDelay)
+	}
+}
+
+// Connect makes a single attempt to connect and authenticate. Most callers
+// want Start, which keeps the connection alive.
 func (ha *HomeAssistantClient) Connect() error {
 	u, err := url.Parse(ha.config.URL)
 	if err != nil {
@@ -102,22 +245,256 @@ func (ha *HomeAssistantClient) Connect() error {
 		return fmt.Errorf("failed to connect: %w", err)
 	}
 
+	// Drop any auth result left over from a previous connection
+	select {
+	case <-ha.authChan:
+	default:
+	}
+
+	done := make(chan struct{})
+	ha.mu.Lock()
 	ha.conn = conn
+	ha.connDone = done
 	ha.connected = true
+	ha.mu.Unlock()
+
+	go ha.readMessages(conn, done)
 
-	go ha.readMessages()
+	var authErr error
 	select {
 	case success := <-ha.authChan:
 		if !success {
-			return fmt.Errorf("authentication failed")
+			authErr = fmt.Errorf("authentication failed")
 		}
-		return nil
 	case <-time.After(10 * time.Second):
-		return fmt.Errorf("authentication timeout")
+		authErr = fmt.Errorf("authentication timeout")
+	}
+
+	if authErr != nil {
+		if err := conn.Close(); err != nil {
+			log.Printf("Failed to close WebSocket connection: %v", err)
+		}
+		<-done
+		return authErr
 	}
+	return nil
+}
+
+// syncStates sets up state tracking on a fresh connection. Only entities with
+// subscribers are watched. Subscribers receive the fresh state of their
+// entity, so widgets catch up on anything that changed while the connection
+// was down.
+func (ha *HomeAssistantClient) syncStates() {
+	ha.SubscriptionManager.connectionReset()
+	ha.SubscriptionManager.syncEntities()
 }
 
-func (ha *HomeAssistantClient) authenticate(msg HAMessage) error {
+// syncAllStates subscribes to the state_changed firehose and reloads the
+// state cache from get_states. It is used with servers that do not support
+// subscribe_entities.
+func (ha *HomeAssistantClient) syncAllStates() {
+	// Subscribe first so no change slips in between the dump and the events
+	if err := ha.SubscribeEvents("state_changed"); err != nil {
+		log.Printf("Failed to subscribe to state_changed events: %v", err)
+		return
+	}
+
+	requested := time.Now()
+	states, err := ha.GetStates()
+	if err != nil {
+		log.Printf("Failed to load entity states: %v", err)
+		return
+	}
+
+	changed := ha.mergeStates(states, requested)
+	for i := range changed {
+		ha.SubscriptionManager.publish(StateChangeEvent{
+			EntityID: changed[i].EntityID,
+			NewState: &changed[i],
+		})
+	}
+}
+
+// mergeStates loads a get_states dump into the cache and returns the states
+// that were taken from it. Events may have been handled while the dump was
+// on its way, so cached states updated later than the dumped ones are kept.
+// Entities missing from the dump are dropped unless they were updated after
+// it was requested.
+func (ha *HomeAssistantClient) mergeStates(states []HAEntityState, requested time.Time) []HAEntityState {
+	ha.statesMu.Lock()
+	defer ha.statesMu.Unlock()
+
+	dumped := make(map[string]bool, len(states))
+	changed := make([]HAEntityState, 0, len(states))
+	for _, state := range states {
+		dumped[state.EntityID] = true
+		if cached, exists := ha.states[state.EntityID]; exists && cached.LastUpdated.After(state.LastUpdated) {
+			continue
+		}
+		ha.states[state.EntityID] = state
+		changed = append(changed, state)
+	}
+	for entityID, cached := range ha.states {
+		if !dumped[entityID] && cached.LastUpdated.Before(requested) {
+			delete(ha.states, entityID)
+		}
+	}
+	return changed
+}
+
+// GetState returns the cached state of entityID.
+func (ha *HomeAssistantClient) GetState(entityID string) (HAEntityState, bool) {
+	ha.statesMu.RLock()
+	defer ha.statesMu.RUnlock()
+	state, exists := ha.states[entityID]
+	return state, exists
+}
+
+func (ha *HomeAssistantClient) updateState(entityID string, state *HAEntityState) {
+	ha.statesMu.Lock()
+	defer ha.statesMu.Unlock()
+	if state == nil {
+		delete(ha.states, entityID)
+		return
+	}
+	ha.states[entityID] = *state
+}
+
+// heartbeat pings Home Assistant until done is closed. When too many pings in
+// a row go unanswered the connection is closed, which hands over to the
+// reconnect loop.
+func (ha *HomeAssistantClient) heartbeat(conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(ha.pingInterval)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-done:
+			return
+		case <-ha.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(ha.ctx, ha.pingInterval)
+		start := time.Now()
+		_, err := ha.SendCommand(ctx, "ping", nil)
+		cancel()
+
+		if err != nil {
+			missed++
+			log.Printf("Home Assistant ping failed (%d/%d): %v", missed, ha.pingMaxMissed, err)
+			if missed >= ha.pingMaxMissed {
+				log.Printf("Home Assistant stopped answering pings, dropping connection")
+				if err := conn.Close(); err != nil {
+					log.Printf("Failed to close WebSocket connection: %v", err)
+				}
+				return
+			}
+			continue
+		}
+
+		missed = 0
+		ha.mu.Lock()
+		ha.latency = time.Since(start)
+		ha.mu.Unlock()
+	}
+}
+
+// Latency returns the round-trip time of the last answered ping, or zero if
+// none was answered on the current connection yet.
+func (ha *HomeAssistantClient) Latency() time.Duration {
+	ha.mu.RLock()
+	defer ha.mu.RUnlock()
+	return ha.latency
+}
+
+// readTimeout is how long the connection may stay silent before it is
+// considered dead. Pings guarantee traffic well within this window.
+func (ha *HomeAssistantClient) readTimeout() time.Duration {
+	return ha.pingInterval * time.Duration(ha.pingMaxMissed+1)
+}
+
+// ConnectionState returns the current state of the connection loop.
+func (ha *HomeAssistantClient) ConnectionState() ConnectionState {
+	ha.mu.RLock()
+	defer ha.mu.RUnlock()
+	return ha.state
+}
+
+// Health reports the connection state. The client retries on its own, so a
+// lost connection only degrades it.
+func (ha *HomeAssistantClient) Health() Health {
+	state := ha.ConnectionState()
+	health := Health{
+		Details: map[string]interface{}{
+			"state":      state.String(),
+			"latency_ms": ha.Latency().Milliseconds(),
+		},
+	}
+
+	switch state {
+	case StateConnected:
+		health.Status = Healthy
+	case StateConnecting:
+		health.Status = Degraded
+		health.Message = "Home Assistant: connecting…"
+	default:
+		health.Status = Degraded
+		health.Message = "Home Assistant: disconnected, retrying…"
+	}
+	return health
+}
+
+// SubscribeHealth returns a channel that receives the health after every
+// connection state transition. The channel is closed when the client stops.
+func (ha *HomeAssistantClient) SubscribeHealth() <-chan Health {
+	states := ha.SubscribeConnectionState()
+	ch := make(chan Health, cap(states))
+	go func() {
+		defer close(ch)
+		for range states {
+			ch <- ha.Health()
+		}
+	}()
+	return ch
+}
+
+// SubscribeConnectionState returns a channel that receives every connection
+// state transition. The channel is closed when the client is stopped.
+func (ha *HomeAssistantClient) SubscribeConnectionState() <-chan ConnectionState {
+	ch := make(chan ConnectionState, 10)
+
+	ha.mu.Lock()
+	defer ha.mu.Unlock()
+	if ha.closed {
+		close(ch)
+		return ch
+	}
+	ha.stateListeners = append(ha.stateListeners, ch)
+	return ch
+}
+
+func (ha *HomeAssistantClient) setState(state ConnectionState) {
+	ha.mu.Lock()
+	defer ha.mu.Unlock()
+
+	if ha.state == state || ha.closed {
+		return
+	}
+	ha.state = state
+
+	for _, ch := range ha.stateListeners {
+		select {
+		case ch <- state:
+		default:
+			// Listener is not keeping up, it can read ConnectionState instead
+		}
+	}
+}
+
+func (ha *HomeAssistantClient) authenticate(conn *websocket.Conn, msg HAMessage) error {
 	if msg.Type != "auth_required" {
 		return fmt.Errorf("expected auth_required, got %s", msg.Type)
 	}
@@ -128,7 +505,7 @@ func (ha *HomeAssistantClient) authenticate(msg HAMessage) error {
 	}
 
 	ha.writeMu.Lock()
-	err := ha.conn.WriteJSON(authMsg)
+	err := conn.WriteJSON(authMsg)
 	ha.writeMu.Unlock()
 
 	if err != nil {
@@ -138,17 +515,19 @@ func (ha *HomeAssistantClient) authenticate(msg HAMessage) error {
 	return nil
 }
 
-func (ha *HomeAssistantClient) readMessages() {
+func (ha *HomeAssistantClient) readMessages(conn *websocket.Conn, done chan struct{}) {
 	defer func() {
 		ha.mu.Lock()
 		ha.connected = false
 		ha.authenticated = false
+		ha.latency = 0
+		ha.eventHandlers = make(map[int]func(json.RawMessage))
+		ha.failPending(ErrDisconnected)
 		ha.mu.Unlock()
-		if ha.conn != nil {
-			if err := ha.conn.Close(); err != nil {
-				log.Printf("Failed to close WebSocket connection: %v", err)
-			}
+		if err := conn.Close(); err != nil {
+			log.Printf("Failed to close WebSocket connection: %v", err)
 		}
+		close(done)
 	}()
 
 	for {
@@ -156,9 +535,13 @@ func (ha *HomeAssistantClient) readMessages() {
 		case <-ha.ctx.Done():
 			return
 		default:
+			if err := conn.SetReadDeadline(time.Now().Add(ha.readTimeout())); err != nil {
+				return
+			}
+
 			var msg HAMessage
-			if err := ha.conn.ReadJSON(&msg); err != nil {
-				if !ha.authenticated {
+			if err := conn.ReadJSON(&msg); err != nil {
+				if !ha.IsConnected() {
 					select {
 					case ha.authChan <- false:
 					default:
@@ -167,14 +550,14 @@ func (ha *HomeAssistantClient) readMessages() {
 				return
 			}
 
-			ha.handleMessage(msg)
+			ha.handleMessage(conn, msg)
 		}
 	}
 }
 
-func (ha *HomeAssistantClient) handleMessage(msg HAMessage) {
+func (ha *HomeAssistantClient) handleMessage(conn *websocket.Conn, msg HAMessage) {
 	if msg.Type == "auth_required" {
-		if err := ha.authenticate(msg); err != nil {
+		if err := ha.authenticate(conn, msg); err != nil {
 			select {
 			case ha.authChan <- false:
 			default:
@@ -202,131 +585,131 @@ func (ha *HomeAssistantClient) handleMessage(msg HAMessage) {
 		return
 	}
 
-	if msg.ID > 0 {
+	if msg.Type == "result" || msg.Type == "pong" {
+		ha.resolve(msg)
+		return
+	}
+
+	if msg.Type == "event" && msg.Event != nil {
 		ha.mu.RLock()
-		callback, exists := ha.callbacks[msg.ID]
+		handler, exists := ha.eventHandlers[msg.ID]
 		ha.mu.RUnlock()
 
 		if exists {
-			callback(msg)
-			ha.mu.Lock()
-			delete(ha.callbacks, msg.ID)
-			ha.mu.Unlock()
+			handler(msg.Event)
+			return
+		}
+
+		var event HAEvent
+		if err := json.Unmarshal(msg.Event, &event); err != nil {
+			log.Printf("Failed to parse event: %v", err)
+			return
 		}
-	}
 
-	if msg.Type == "event" && msg.Event != nil {
 		select {
-		case ha.eventChan <- *msg.Event:
+		case ha.eventChan <- event:
 		default:
 			// Event channel full, drop event silently
 		}
 	}
 }
 
-func (ha *HomeAssistantClient) sendMessage(msgType string, data map[string]interface{}) (int, error) {
+// sendMessage sends a command without waiting for its result.
+func (ha *HomeAssistantClient) sendMessage(msgType string, payload interface{}) (int, error) {
+	id := ha.nextMessageID()
+	if err := ha.writeMessage(id, msgType, payload); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (ha *HomeAssistantClient) nextMessageID() int {
+	ha.mu.Lock()
+	defer ha.mu.Unlock()
+	ha.msgID++
+	return ha.msgID
+}
+
+// writeMessage sends a command with a pre-allocated ID, so handlers for its
+// responses can be registered before the first response can arrive. The
+// fields of payload, which must encode to a JSON object, are sent alongside
+// id and type.
+func (ha *HomeAssistantClient) writeMessage(id int, msgType string, payload interface{}) error {
 	ha.mu.RLock()
-	connected := ha.connected
-	authenticated := ha.authenticated
+	connected := ha.connected && ha.authenticated
+	conn := ha.conn
 	ha.mu.RUnlock()
 
 	if !connected {
-		return 0, fmt.Errorf("not connected")
+		return ErrNotConnected
 	}
 
-	if !authenticated {
-		return 0, fmt.Errorf("not authenticated")
-	}
-
-	ha.mu.Lock()
-	ha.msgID++
-	id := ha.msgID
-	ha.mu.Unlock()
-
-	msg := map[string]interface{}{
-		"id":   id,
-		"type": msgType,
+	msg := make(map[string]json.RawMessage)
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s payload: %w", msgType, err)
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return fmt.Errorf("%s payload must be a JSON object: %w", msgType, err)
+		}
 	}
 
-	for k, v := range data {
-		msg[k] = v
+	msg["id"] = json.RawMessage(strconv.Itoa(id))
+	typeData, err := json.Marshal(msgType)
+	if err != nil {
+		return fmt.Errorf("failed to encode message type: %w", err)
 	}
+	msg["type"] = typeData
 
 	ha.writeMu.Lock()
-	err := ha.conn.WriteJSON(msg)
+	err = conn.WriteJSON(msg)
 	ha.writeMu.Unlock()
 
 	if err != nil {
-		return 0, fmt.Errorf("failed to send message: %w", err)
+		return fmt.Errorf("failed to send message: %w", err)
 	}
 
-	return id, nil
+	return nil
 }
 
 func (ha *HomeAssistantClient) GetStates() ([]HAEntityState, error) {
-	id, err := ha.sendMessage("get_states", nil)
+	ctx, cancel := context.WithTimeout(ha.ctx, defaultCommandTimeout)
+	defer cancel()
+
+	result, err := ha.SendCommand(ctx, "get_states", nil)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("get_states failed: %w", err)
 	}
 
-	resultChan := make(chan HAMessage, 1)
-	ha.mu.Lock()
-	ha.callbacks[id] = func(msg HAMessage) {
-		resultChan <- msg
+	var states []HAEntityState
+	if err := json.Unmarshal(result, &states); err != nil {
+		return nil, fmt.Errorf("failed to parse states: %w", err)
 	}
-	ha.mu.Unlock()
 
-	select {
-	case msg := <-resultChan:
-		if !msg.Success {
-			return nil, fmt.Errorf("get_states failed: %v", msg.Error)
-		}
-
-		var states []HAEntityState
-		statesData, err := json.Marshal(msg.Result)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal states data: %w", err)
-		}
-		if err := json.Unmarshal(statesData, &states); err != nil {
-			return nil, fmt.Errorf("failed to parse states: %w", err)
-		}
-
-		return states, nil
-	case <-time.After(10 * time.Second):
-		return nil, fmt.Errorf("timeout waiting for states")
-	}
+	return states, nil
 }
 
 func (ha *HomeAssistantClient) CallService(domain, service string, data map[string]interface{}) error {
-	id, err := ha.sendMessage("call_service", map[string]interface{}{
+	ctx, cancel := context.WithTimeout(ha.ctx, defaultCommandTimeout)
+	defer cancel()
+
+	_, err := ha.SendCommand(ctx, "call_service", map[string]interface{}{
 		"domain":       domain,
 		"service":      service,
 		"service_data": data,
 	})
 	if err != nil {
-		return err
-	}
-
-	resultChan := make(chan HAMessage, 1)
-	ha.mu.Lock()
-	ha.callbacks[id] = func(msg HAMessage) {
-		resultChan <- msg
-	}
-	ha.mu.Unlock()
-
-	select {
-	case msg := <-resultChan:
-		if !msg.Success {
-			return fmt.Errorf("service call failed: %v", msg.Error)
-		}
-		return nil
-	case <-time.After(10 * time.Second):
-		return fmt.Errorf("timeout waiting for service call")
+		return fmt.Errorf("service call failed: %w", err)
 	}
+	return nil
 }
 
 func (ha *HomeAssistantClient) SubscribeEvents(eventType string) error {
-	_, err := ha.sendMessage("subscribe_events", map[string]interface{}{
+	ctx, cancel := context.WithTimeout(ha.ctx, defaultCommandTimeout)
+	defer cancel()
+
+	_, err := ha.SendCommand(ctx, "subscribe_events", map[string]interface{}{
 		"event_type": eventType,
 	})
 	return err
@@ -336,13 +719,23 @@ func (ha *HomeAssistantClient) GetEventChannel() <-chan HAEvent {
 	return ha.eventChan
 }
 
-func (ha *HomeAssistantClient) Close() error {
+func (ha *HomeAssistantClient) Stop() error {
 	ha.cancel()
 	if ha.SubscriptionManager != nil {
 		ha.SubscriptionManager.Close()
 	}
-	if ha.conn != nil {
-		return ha.conn.Close()
+
+	ha.mu.Lock()
+	ha.closed = true
+	for _, ch := range ha.stateListeners {
+		close(ch)
+	}
+	ha.stateListeners = nil
+	conn := ha.conn
+	ha.mu.Unlock()
+
+	if conn != nil {
+		return conn.Close()
 	}
 	return nil
 }
@@ -365,20 +758,23 @@ func NewSubscriptionManager(haClient *HomeAssistantClient) *SubscriptionManager
 	return sm
 }
 
+// Subscribe registers interest in state changes of entityID. If the state is
+// already cached it is delivered right away as the first event. The
+// subscription works while disconnected and survives reconnects.
 func (sm *SubscriptionManager) Subscribe(entityID string) (<-chan StateChangeEvent, error) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
 	ch := make(chan StateChangeEvent, 10)
-
 	if _, exists := sm.subscriptions[entityID]; !exists {
-		if err := sm.haClient.SubscribeEvents("state_changed"); err != nil {
-			close(ch)
-			return nil, fmt.Errorf("failed to subscribe to state_changed events: %w", err)
-		}
+		sm.scheduleResync()
 	}
-
 	sm.subscriptions[entityID] = append(sm.subscriptions[entityID], ch)
+
+	if state, exists := sm.haClient.GetState(entityID); exists {
+		ch <- StateChangeEvent{EntityID: entityID, NewState: &state}
+	}
+
 	return ch, nil
 }
 
@@ -397,6 +793,7 @@ func (sm *SubscriptionManager) Unsubscribe(entityID string, ch <-chan StateChang
 
 		if len(sm.subscriptions[entityID]) == 0 {
 			delete(sm.subscriptions, entityID)
+			sm.scheduleResync()
 		}
 	}
 }
@@ -452,17 +849,20 @@ func (sm *SubscriptionManager) processStateChangeEvent(event HAEvent) {
 		}
 	}
 
-	stateEvent := StateChangeEvent{
+	sm.haClient.updateState(entityID, newState)
+
+	sm.publish(StateChangeEvent{
 		EntityID: entityID,
 		NewState: newState,
 		OldState: oldState,
-	}
+	})
+}
 
+func (sm *SubscriptionManager) publish(stateEvent StateChangeEvent) {
 	sm.mu.RLock()
-	subscribers := sm.subscriptions[entityID]
-	sm.mu.RUnlock()
+	defer sm.mu.RUnlock()
 
-	for _, ch := range subscribers {
+	for _, ch := range sm.subscriptions[stateEvent.EntityID] {
 		select {
 		case ch <- stateEvent:
 		default:
@@ -0,0 +1,106 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// defaultCommandTimeout bounds the commands issued by the client's own
+// helpers such as GetStates and CallService.
+const defaultCommandTimeout = 10 * time.Second
+
+var (
+	// ErrNotConnected is returned for commands sent while the client has no
+	// authenticated connection.
+	ErrNotConnected = errors.New("not connected to Home Assistant")
+
+	// ErrDisconnected is returned for commands whose connection dropped
+	// before their result arrived.
+	ErrDisconnected = errors.New("connection to Home Assistant lost")
+)
+
+type commandResult struct {
+	msg HAMessage
+	err error
+}
+
+// SendCommand sends a websocket command and waits for its result. The fields
+// of payload, which must encode to a JSON object, are sent alongside the id
+// and type. A command rejected by Home Assistant returns a *HAError. The wait
+// ends early when ctx is done or the connection drops.
+func (ha *HomeAssistantClient) SendCommand(ctx context.Context, msgType string, payload interface{}) (json.RawMessage, error) {
+	_, result, err := ha.sendCommand(ctx, msgType, payload, nil)
+	return result, err
+}
+
+// sendCommand is SendCommand for subscriptions: events carrying the
+// command's ID are routed to eventHandler until the connection drops. It
+// also returns the command ID.
+func (ha *HomeAssistantClient) sendCommand(ctx context.Context, msgType string, payload interface{}, eventHandler func(json.RawMessage)) (int, json.RawMessage, error) {
+	id := ha.nextMessageID()
+	resultChan := make(chan commandResult, 1)
+
+	ha.mu.Lock()
+	ha.pending[id] = resultChan
+	if eventHandler != nil {
+		ha.eventHandlers[id] = eventHandler
+	}
+	ha.mu.Unlock()
+
+	if err := ha.writeMessage(id, msgType, payload); err != nil {
+		ha.forgetCommand(id)
+		return 0, nil, err
+	}
+
+	select {
+	case res := <-resultChan:
+		if res.err != nil {
+			ha.forgetCommand(id)
+			return 0, nil, res.err
+		}
+		if res.msg.Type == "result" && !res.msg.Success {
+			ha.forgetCommand(id)
+			if res.msg.Error != nil {
+				return 0, nil, res.msg.Error
+			}
+			return 0, nil, &HAError{Code: "unknown_error", Message: msgType + " failed"}
+		}
+		return id, res.msg.Result, nil
+	case <-ctx.Done():
+		ha.forgetCommand(id)
+		return 0, nil, fmt.Errorf("%s: %w", msgType, ctx.Err())
+	}
+}
+
+// forgetCommand drops the result and event routing of a command.
+func (ha *HomeAssistantClient) forgetCommand(id int) {
+	ha.mu.Lock()
+	defer ha.mu.Unlock()
+	delete(ha.pending, id)
+	delete(ha.eventHandlers, id)
+}
+
+// resolve hands a result message to the command waiting for it. Results of
+// commands that already gave up are dropped.
+func (ha *HomeAssistantClient) resolve(msg HAMessage) {
+	ha.mu.Lock()
+	resultChan, exists := ha.pending[msg.ID]
+	delete(ha.pending, msg.ID)
+	ha.mu.Unlock()
+
+	if exists {
+		resultChan <- commandResult{msg: msg}
+	}
+}
+
+// failPending ends every command still waiting for a result. Must be called
+// with ha.mu held.
+func (ha *HomeAssistantClient) failPending(err error) {
+	for id, resultChan := range ha.pending {
+		resultChan <- commandResult{err: err}
+		delete(ha.pending, id)
+	}
+}
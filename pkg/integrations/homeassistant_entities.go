@@ -0,0 +1,257 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"time"
+)
+
+// resyncDelay batches subscription changes, e.g. while a dashboard with many
+// widgets starts up, into a single subscribe_entities command.
+const resyncDelay = 100 * time.Millisecond
+
+// compressedEntityEvent is the payload of a subscribe_entities event. Home
+// Assistant sends full states for new entities, diffs for changed ones and
+// the IDs of removed ones.
+type compressedEntityEvent struct {
+	Added   map[string]compressedState     `json:"a"`
+	Changed map[string]compressedStateDiff `json:"c"`
+	Removed []string                       `json:"r"`
+}
+
+type compressedState struct {
+	State       string                 `json:"s"`
+	Attributes  map[string]interface{} `json:"a"`
+	LastChanged float64                `json:"lc"`
+	LastUpdated float64                `json:"lu"`
+}
+
+type compressedStateDiff struct {
+	Additions *compressedStatePatch `json:"+,omitempty"`
+	Removals  *compressedStatePatch `json:"-,omitempty"`
+}
+
+// compressedStatePatch holds the changed fields of a state. In the removal
+// part of a diff only the attribute names are sent.
+type compressedStatePatch struct {
+	State       *string         `json:"s,omitempty"`
+	Attributes  json.RawMessage `json:"a,omitempty"`
+	LastChanged *float64        `json:"lc,omitempty"`
+	LastUpdated *float64        `json:"lu,omitempty"`
+}
+
+func (cs compressedState) toEntityState(entityID string) HAEntityState {
+	lastUpdated := cs.LastUpdated
+	if lastUpdated == 0 {
+		// Home Assistant leaves out lu when it equals lc
+		lastUpdated = cs.LastChanged
+	}
+
+	attributes := cs.Attributes
+	if attributes == nil {
+		attributes = make(map[string]interface{})
+	}
+
+	return HAEntityState{
+		EntityID:    entityID,
+		State:       cs.State,
+		Attributes:  attributes,
+		LastChanged: unixSeconds(cs.LastChanged),
+		LastUpdated: unixSeconds(lastUpdated),
+	}
+}
+
+// apply returns a copy of state with the diff applied.
+func (diff compressedStateDiff) apply(state HAEntityState) (HAEntityState, error) {
+	attributes := make(map[string]interface{}, len(state.Attributes))
+	for k, v := range state.Attributes {
+		attributes[k] = v
+	}
+	state.Attributes = attributes
+
+	if add := diff.Additions; add != nil {
+		if add.State != nil {
+			state.State = *add.State
+		}
+		if add.LastChanged != nil {
+			state.LastChanged = unixSeconds(*add.LastChanged)
+			state.LastUpdated = state.LastChanged
+		}
+		if add.LastUpdated != nil {
+			state.LastUpdated = unixSeconds(*add.LastUpdated)
+		}
+		if len(add.Attributes) > 0 {
+			var changed map[string]interface{}
+			if err := json.Unmarshal(add.Attributes, &changed); err != nil {
+				return state, fmt.Errorf("failed to parse changed attributes: %w", err)
+			}
+			for k, v := range changed {
+				attributes[k] = v
+			}
+		}
+	}
+
+	if remove := diff.Removals; remove != nil && len(remove.Attributes) > 0 {
+		var removed []string
+		if err := json.Unmarshal(remove.Attributes, &removed); err != nil {
+			return state, fmt.Errorf("failed to parse removed attributes: %w", err)
+		}
+		for _, k := range removed {
+			delete(attributes, k)
+		}
+	}
+
+	return state, nil
+}
+
+func unixSeconds(seconds float64) time.Time {
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9)).UTC()
+}
+
+// subscribeEntities sends subscribe_entities for entityIDs and routes the
+// resulting events to handler. It returns the subscription ID.
+func (ha *HomeAssistantClient) subscribeEntities(entityIDs []string, handler func(json.RawMessage)) (int, error) {
+	ctx, cancel := context.WithTimeout(ha.ctx, defaultCommandTimeout)
+	defer cancel()
+
+	id, _, err := ha.sendCommand(ctx, "subscribe_entities", map[string]interface{}{
+		"entity_ids": entityIDs,
+	}, handler)
+	return id, err
+}
+
+// unsubscribe ends the subscription created by the command with the given ID.
+func (ha *HomeAssistantClient) unsubscribe(subscriptionID int) {
+	ha.mu.Lock()
+	delete(ha.eventHandlers, subscriptionID)
+	ha.mu.Unlock()
+
+	_, err := ha.sendMessage("unsubscribe_events", map[string]interface{}{
+		"subscription": subscriptionID,
+	})
+	if err != nil {
+		log.Printf("Failed to unsubscribe from subscription %d: %v", subscriptionID, err)
+	}
+}
+
+// connectionReset forgets the subscription state of the previous connection.
+func (sm *SubscriptionManager) connectionReset() {
+	sm.syncMu.Lock()
+	defer sm.syncMu.Unlock()
+	sm.entitySubID = 0
+	sm.entityIDs = nil
+	sm.legacy = false
+}
+
+// scheduleResync updates the subscribe_entities subscription shortly after
+// the set of watched entities changed. Must be called with sm.mu held.
+func (sm *SubscriptionManager) scheduleResync() {
+	if sm.resyncTimer != nil {
+		sm.resyncTimer.Reset(resyncDelay)
+		return
+	}
+	sm.resyncTimer = time.AfterFunc(resyncDelay, sm.syncEntities)
+}
+
+// syncEntities points the subscribe_entities subscription at the entities
+// that currently have subscribers. Servers that do not know the command are
+// switched over to the state_changed firehose.
+func (sm *SubscriptionManager) syncEntities() {
+	sm.syncMu.Lock()
+	defer sm.syncMu.Unlock()
+
+	if sm.legacy || !sm.haClient.IsConnected() {
+		return
+	}
+
+	sm.mu.RLock()
+	entityIDs := make([]string, 0, len(sm.subscriptions))
+	for entityID := range sm.subscriptions {
+		entityIDs = append(entityIDs, entityID)
+	}
+	sm.mu.RUnlock()
+	slices.Sort(entityIDs)
+
+	if slices.Equal(entityIDs, sm.entityIDs) {
+		return
+	}
+
+	if sm.entitySubID != 0 {
+		sm.haClient.unsubscribe(sm.entitySubID)
+		sm.entitySubID = 0
+	}
+	sm.entityIDs = entityIDs
+
+	if len(entityIDs) == 0 {
+		return
+	}
+
+	subID, err := sm.haClient.subscribeEntities(entityIDs, sm.handleEntityEvent)
+	if err != nil {
+		sm.entityIDs = nil
+		var haErr *HAError
+		if errors.As(err, &haErr) && haErr.Code == "unknown_command" {
+			log.Printf("Home Assistant does not support subscribe_entities, falling back to state_changed")
+			sm.legacy = true
+			go sm.haClient.syncAllStates()
+			return
+		}
+		log.Printf("Failed to subscribe to entities: %v", err)
+		return
+	}
+
+	sm.entitySubID = subID
+}
+
+// handleEntityEvent applies a subscribe_entities event to the state cache and
+// notifies subscribers.
+func (sm *SubscriptionManager) handleEntityEvent(raw json.RawMessage) {
+	var event compressedEntityEvent
+	if err := json.Unmarshal(raw, &event); err != nil {
+		log.Printf("Failed to parse entity event: %v", err)
+		return
+	}
+
+	ha := sm.haClient
+
+	for entityID, added := range event.Added {
+		newState := added.toEntityState(entityID)
+		sm.applyState(entityID, &newState)
+	}
+
+	for entityID, diff := range event.Changed {
+		oldState, exists := ha.GetState(entityID)
+		if !exists {
+			continue
+		}
+		newState, err := diff.apply(oldState)
+		if err != nil {
+			log.Printf("Failed to apply state diff for %s: %v", entityID, err)
+			continue
+		}
+		sm.applyState(entityID, &newState)
+	}
+
+	for _, entityID := range event.Removed {
+		sm.applyState(entityID, nil)
+	}
+}
+
+func (sm *SubscriptionManager) applyState(entityID string, newState *HAEntityState) {
+	stateEvent := StateChangeEvent{
+		EntityID: entityID,
+		NewState: newState,
+	}
+	if oldState, exists := sm.haClient.GetState(entityID); exists {
+		stateEvent.OldState = &oldState
+	}
+
+	sm.haClient.updateState(entityID, newState)
+	sm.publish(stateEvent)
+}
@@ -0,0 +1,54 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// HAHistoryPoint is one recorded state of an entity.
+type HAHistoryPoint struct {
+	State string
+	Time  time.Time
+}
+
+// historyState is a state in a minimal history/history_during_period
+// response. lc is only sent when it differs from lu.
+type historyState struct {
+	State       string  `json:"s"`
+	LastUpdated float64 `json:"lu"`
+}
+
+// GetHistory returns the recorded states of entityID between start and end,
+// oldest first. Attributes are not fetched.
+func (ha *HomeAssistantClient) GetHistory(entityID string, start, end time.Time) ([]HAHistoryPoint, error) {
+	ctx, cancel := context.WithTimeout(ha.ctx, defaultCommandTimeout)
+	defer cancel()
+
+	result, err := ha.SendCommand(ctx, "history/history_during_period", map[string]interface{}{
+		"start_time":               start.UTC().Format(time.RFC3339Nano),
+		"end_time":                 end.UTC().Format(time.RFC3339Nano),
+		"entity_ids":               []string{entityID},
+		"minimal_response":         true,
+		"no_attributes":            true,
+		"significant_changes_only": false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("history request failed: %w", err)
+	}
+
+	var history map[string][]historyState
+	if err := json.Unmarshal(result, &history); err != nil {
+		return nil, fmt.Errorf("failed to parse history: %w", err)
+	}
+
+	points := make([]HAHistoryPoint, 0, len(history[entityID]))
+	for _, state := range history[entityID] {
+		points = append(points, HAHistoryPoint{
+			State: state.State,
+			Time:  unixSeconds(state.LastUpdated),
+		})
+	}
+	return points, nil
+}
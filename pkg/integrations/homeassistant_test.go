@@ -0,0 +1,386 @@
+package integrations
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mntndev/dash/pkg/config"
+	"github.com/mntndev/dash/pkg/integrations/hatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "test-token"
+
+// eventTimeout is generous because reconnects wait for reconnectMinDelay.
+const eventTimeout = 5 * time.Second
+
+func newTestClient(t *testing.T, server *hatest.Server, cfg config.HomeAssistantConfig) *HomeAssistantClient {
+	t.Helper()
+	cfg.URL = server.URL()
+	if cfg.Token == "" {
+		cfg.Token = testToken
+	}
+	client := NewHomeAssistantClient(&cfg)
+	t.Cleanup(func() { _ = client.Stop() })
+	return client
+}
+
+// startTestClient starts the connection loop and waits until it is connected.
+func startTestClient(t *testing.T, server *hatest.Server, cfg config.HomeAssistantConfig) *HomeAssistantClient {
+	t.Helper()
+	client := newTestClient(t, server, cfg)
+	require.NoError(t, client.Start())
+	require.Eventually(t, client.IsConnected, eventTimeout, 10*time.Millisecond)
+	return client
+}
+
+// waitForEvent reads events from ch until one matches.
+func waitForEvent(t *testing.T, ch <-chan StateChangeEvent, match func(StateChangeEvent) bool) StateChangeEvent {
+	t.Helper()
+	timeout := time.After(eventTimeout)
+	for {
+		select {
+		case event, ok := <-ch:
+			require.True(t, ok, "subscription closed")
+			if match(event) {
+				return event
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for state change")
+		}
+	}
+}
+
+func hasState(state string) func(StateChangeEvent) bool {
+	return func(event StateChangeEvent) bool {
+		return event.NewState != nil && event.NewState.State == state
+	}
+}
+
+func TestHomeAssistantAuth(t *testing.T) {
+	server := hatest.NewServer(t, testToken)
+
+	t.Run("valid token", func(t *testing.T) {
+		client := newTestClient(t, server, config.HomeAssistantConfig{})
+		require.NoError(t, client.Connect())
+		assert.True(t, client.IsConnected())
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		client := newTestClient(t, server, config.HomeAssistantConfig{Token: "wrong"})
+		err := client.Connect()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "authentication failed")
+		assert.False(t, client.IsConnected())
+	})
+}
+
+func TestHomeAssistantCommands(t *testing.T) {
+	server := hatest.NewServer(t, testToken)
+	server.SetState("light.kitchen", "on", map[string]interface{}{"brightness": 255.0})
+	server.SetState("switch.fan", "off", nil)
+
+	client := newTestClient(t, server, config.HomeAssistantConfig{})
+	require.NoError(t, client.Connect())
+
+	t.Run("get_states", func(t *testing.T) {
+		states, err := client.GetStates()
+		require.NoError(t, err)
+		require.Len(t, states, 2)
+
+		byID := make(map[string]HAEntityState)
+		for _, state := range states {
+			byID[state.EntityID] = state
+		}
+		assert.Equal(t, "on", byID["light.kitchen"].State)
+		assert.Equal(t, 255.0, byID["light.kitchen"].Attributes["brightness"])
+		assert.Equal(t, "off", byID["switch.fan"].State)
+	})
+
+	t.Run("call_service", func(t *testing.T) {
+		require.NoError(t, client.CallService("switch", "toggle", map[string]interface{}{"entity_id": "switch.fan"}))
+
+		calls := server.Calls()
+		require.Len(t, calls, 1)
+		assert.Equal(t, "switch", calls[0].Domain)
+		assert.Equal(t, "toggle", calls[0].Service)
+		assert.Equal(t, "switch.fan", calls[0].Data["entity_id"])
+	})
+
+	t.Run("failed call_service", func(t *testing.T) {
+		server.SetServiceHandler(func(call hatest.ServiceCall) error {
+			return errors.New("entity is unavailable")
+		})
+		defer server.SetServiceHandler(nil)
+
+		err := client.CallService("switch", "toggle", map[string]interface{}{"entity_id": "switch.fan"})
+		var haErr *HAError
+		require.ErrorAs(t, err, &haErr)
+		assert.Equal(t, "service_validation_error", haErr.Code)
+		assert.Equal(t, "entity is unavailable", haErr.Message)
+	})
+
+	t.Run("unknown command", func(t *testing.T) {
+		_, err := client.SendCommand(context.Background(), "does_not_exist", nil)
+		var haErr *HAError
+		require.ErrorAs(t, err, &haErr)
+		assert.Equal(t, "unknown_command", haErr.Code)
+	})
+}
+
+func TestHomeAssistantCommandTimeout(t *testing.T) {
+	server := hatest.NewServer(t, testToken)
+	server.SetIgnored("call_service", true)
+
+	client := newTestClient(t, server, config.HomeAssistantConfig{})
+	require.NoError(t, client.Connect())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.SendCommand(ctx, "call_service", map[string]interface{}{
+		"domain":  "light",
+		"service": "toggle",
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+	assert.Empty(t, client.pending)
+}
+
+func TestHomeAssistantCommandDisconnect(t *testing.T) {
+	server := hatest.NewServer(t, testToken)
+	server.SetIgnored("call_service", true)
+
+	client := newTestClient(t, server, config.HomeAssistantConfig{})
+	require.NoError(t, client.Connect())
+
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := client.SendCommand(context.Background(), "call_service", map[string]interface{}{
+			"domain":  "light",
+			"service": "toggle",
+		})
+		errChan <- err
+	}()
+
+	require.Eventually(t, func() bool {
+		client.mu.RLock()
+		defer client.mu.RUnlock()
+		return len(client.pending) == 1
+	}, eventTimeout, 10*time.Millisecond)
+
+	server.DropConnections()
+
+	select {
+	case err := <-errChan:
+		assert.ErrorIs(t, err, ErrDisconnected)
+	case <-time.After(eventTimeout):
+		t.Fatal("command did not fail after disconnect")
+	}
+
+	_, err := client.SendCommand(context.Background(), "ping", nil)
+	assert.ErrorIs(t, err, ErrNotConnected)
+}
+
+func TestSubscriptionManager(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		name := "subscribe_entities"
+		if legacy {
+			name = "state_changed fallback"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			server := hatest.NewServer(t, testToken)
+			if legacy {
+				server.DisableSubscribeEntities()
+			}
+			server.SetState("light.kitchen", "off", map[string]interface{}{
+				"friendly_name": "Kitchen",
+				"effect":        "none",
+			})
+
+			client := startTestClient(t, server, config.HomeAssistantConfig{})
+
+			ch, err := client.Subscribe("light.kitchen")
+			require.NoError(t, err)
+
+			event := waitForEvent(t, ch, hasState("off"))
+			assert.Equal(t, "Kitchen", event.NewState.Attributes["friendly_name"])
+			require.Eventually(t, func() bool { return server.Watching("light.kitchen") }, eventTimeout, 10*time.Millisecond)
+
+			server.SetState("light.kitchen", "on", map[string]interface{}{
+				"friendly_name": "Kitchen",
+				"brightness":    128.0,
+			})
+			event = waitForEvent(t, ch, hasState("on"))
+			assert.Equal(t, "Kitchen", event.NewState.Attributes["friendly_name"])
+			assert.Equal(t, 128.0, event.NewState.Attributes["brightness"])
+			assert.NotContains(t, event.NewState.Attributes, "effect")
+			require.NotNil(t, event.OldState)
+			assert.Equal(t, "off", event.OldState.State)
+
+			cached, ok := client.GetState("light.kitchen")
+			require.True(t, ok)
+			assert.Equal(t, "on", cached.State)
+
+			server.RemoveState("light.kitchen")
+			waitForEvent(t, ch, func(event StateChangeEvent) bool { return event.NewState == nil })
+			_, ok = client.GetState("light.kitchen")
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestSubscriptionManagerUnsubscribe(t *testing.T) {
+	server := hatest.NewServer(t, testToken)
+	server.SetState("sensor.temperature", "21.5", nil)
+
+	client := startTestClient(t, server, config.HomeAssistantConfig{})
+
+	ch, err := client.Subscribe("sensor.temperature")
+	require.NoError(t, err)
+	waitForEvent(t, ch, hasState("21.5"))
+	require.Eventually(t, func() bool { return server.Watching("sensor.temperature") }, eventTimeout, 10*time.Millisecond)
+
+	client.Unsubscribe("sensor.temperature", ch)
+	_, ok := <-ch
+	assert.False(t, ok, "channel should be closed")
+	assert.Eventually(t, func() bool { return !server.Watching("sensor.temperature") }, eventTimeout, 10*time.Millisecond)
+}
+
+func TestHomeAssistantReconnect(t *testing.T) {
+	server := hatest.NewServer(t, testToken)
+	server.SetState("switch.fan", "off", nil)
+
+	client := newTestClient(t, server, config.HomeAssistantConfig{})
+	states := client.SubscribeConnectionState()
+	require.NoError(t, client.Start())
+	require.Eventually(t, client.IsConnected, eventTimeout, 10*time.Millisecond)
+
+	ch, err := client.Subscribe("switch.fan")
+	require.NoError(t, err)
+	waitForEvent(t, ch, hasState("off"))
+	require.Eventually(t, func() bool { return server.Watching("switch.fan") }, eventTimeout, 10*time.Millisecond)
+
+	server.DropConnections()
+	waitForConnectionState(t, states, StateDisconnected)
+
+	// Changes made while the client is away arrive after the reconnect
+	server.SetState("switch.fan", "on", nil)
+	waitForConnectionState(t, states, StateConnected)
+	waitForEvent(t, ch, hasState("on"))
+}
+
+func TestHomeAssistantHeartbeat(t *testing.T) {
+	server := hatest.NewServer(t, testToken)
+
+	client := newTestClient(t, server, config.HomeAssistantConfig{
+		PingInterval:  "20ms",
+		PingMaxMissed: 2,
+	})
+	states := client.SubscribeConnectionState()
+	require.NoError(t, client.Start())
+	waitForConnectionState(t, states, StateConnected)
+
+	require.Eventually(t, func() bool { return client.Latency() > 0 }, eventTimeout, 10*time.Millisecond)
+
+	// A server that stops answering pings is dropped
+	server.SetIgnored("ping", true)
+	waitForConnectionState(t, states, StateDisconnected)
+}
+
+func TestHomeAssistantHeartbeatDuringSync(t *testing.T) {
+	server := hatest.NewServer(t, testToken)
+	server.SetIgnored("subscribe_entities", true)
+	server.SetIgnored("ping", true)
+
+	client := newTestClient(t, server, config.HomeAssistantConfig{
+		PingInterval:  "20ms",
+		PingMaxMissed: 2,
+	})
+	_, err := client.Subscribe("light.desk")
+	require.NoError(t, err)
+	states := client.SubscribeConnectionState()
+	require.NoError(t, client.Start())
+	waitForConnectionState(t, states, StateConnected)
+
+	// The initial sync never gets an answer, well before it times out the
+	// connection is dropped
+	start := time.Now()
+	waitForConnectionState(t, states, StateDisconnected)
+	assert.Less(t, time.Since(start), defaultCommandTimeout/2)
+}
+
+func waitForConnectionState(t *testing.T, states <-chan ConnectionState, want ConnectionState) {
+	t.Helper()
+	timeout := time.After(eventTimeout)
+	for {
+		select {
+		case state := <-states:
+			if state == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for connection state %s", want)
+		}
+	}
+}
+
+func TestHomeAssistantHistory(t *testing.T) {
+	server := hatest.NewServer(t, testToken)
+	now := time.Now()
+	server.AddHistory("sensor.temperature", "19.0", now.Add(-3*time.Hour))
+	server.AddHistory("sensor.temperature", "20.5", now.Add(-90*time.Minute))
+	server.AddHistory("sensor.temperature", "unavailable", now.Add(-time.Hour))
+	server.SetState("sensor.temperature", "21.0", nil)
+
+	client := newTestClient(t, server, config.HomeAssistantConfig{})
+	require.NoError(t, client.Connect())
+
+	history, err := client.GetHistory("sensor.temperature", now.Add(-2*time.Hour), time.Now())
+	require.NoError(t, err)
+	require.Len(t, history, 4)
+
+	// The state at the start of the period comes first
+	assert.Equal(t, "19.0", history[0].State)
+	assert.WithinDuration(t, now.Add(-2*time.Hour), history[0].Time, time.Millisecond)
+	assert.Equal(t, "20.5", history[1].State)
+	assert.Equal(t, "unavailable", history[2].State)
+	assert.Equal(t, "21.0", history[3].State)
+
+	history, err = client.GetHistory("sensor.unknown", now.Add(-time.Hour), time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestHomeAssistantMergeStates(t *testing.T) {
+	client := NewHomeAssistantClient(&config.HomeAssistantConfig{})
+	requested := time.Now()
+	before, after := requested.Add(-time.Minute), requested.Add(time.Second)
+
+	// Events handled while get_states was on its way
+	client.updateState("light.desk", &HAEntityState{EntityID: "light.desk", State: "on", LastUpdated: after})
+	client.updateState("sensor.new", &HAEntityState{EntityID: "sensor.new", State: "1", LastUpdated: after})
+	client.updateState("sensor.removed", &HAEntityState{EntityID: "sensor.removed", State: "1", LastUpdated: before})
+
+	changed := client.mergeStates([]HAEntityState{
+		{EntityID: "light.desk", State: "off", LastUpdated: before},
+		{EntityID: "switch.fan", State: "on", LastUpdated: before},
+	}, requested)
+
+	require.Len(t, changed, 1)
+	assert.Equal(t, "switch.fan", changed[0].EntityID)
+
+	state, ok := client.GetState("light.desk")
+	require.True(t, ok)
+	assert.Equal(t, "on", state.State, "the newer event wins over the dump")
+	_, ok = client.GetState("sensor.new")
+	assert.True(t, ok, "created after the dump was requested")
+	_, ok = client.GetState("sensor.removed")
+	assert.False(t, ok, "gone from Home Assistant")
+}
@@ -0,0 +1,111 @@
+package integrations
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/goccy/go-yaml/ast"
+)
+
+// Keys of the built-in integrations under integrations: in the config file.
+const (
+	HomeAssistantName = "home_assistant"
+	DexcomName        = "dexcom"
+	NightscoutName    = "nightscout"
+	PrometheusName    = "prometheus"
+	RSSName           = "rss"
+)
+
+// Integration is a connection to an external service that widgets read from.
+// The dashboard creates one per key under integrations:, starts it before the
+// widgets and stops it on shutdown.
+type Integration interface {
+	// Name is the config key the integration was created from.
+	Name() string
+	Start() error
+	Stop() error
+	Health() Health
+}
+
+// HealthNotifier is implemented by integrations whose health changes on its
+// own, like a connection that drops. The channel receives the new health on
+// every change and is closed when the integration stops.
+type HealthNotifier interface {
+	SubscribeHealth() <-chan Health
+}
+
+type HealthStatus int
+
+const (
+	Healthy HealthStatus = iota
+	// Degraded integrations still work but are retrying or partly failing.
+	Degraded
+	Unhealthy
+)
+
+func (s HealthStatus) String() string {
+	switch s {
+	case Healthy:
+		return "healthy"
+	case Degraded:
+		return "degraded"
+	case Unhealthy:
+		return "unhealthy"
+	default:
+		return "unknown"
+	}
+}
+
+// Health describes the state of an integration for the status overlay.
+type Health struct {
+	Status HealthStatus
+	// Message is shown on screen when the integration is not healthy.
+	Message string
+	// Details are extra values for the dashboard status, keyed by name.
+	Details map[string]interface{}
+}
+
+// Factory creates an integration from its section of the config file.
+type Factory func(config ast.Node) (Integration, error)
+
+type Registry struct {
+	factories map[string]Factory
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: make(map[string]Factory),
+	}
+}
+
+// NewDefaultRegistry returns a registry with the built-in integrations.
+func NewDefaultRegistry() *Registry {
+	registry := NewRegistry()
+	registry.Register(HomeAssistantName, createHomeAssistantClient)
+	registry.Register(DexcomName, createDexcomClient)
+	registry.Register(NightscoutName, createNightscoutClient)
+	registry.Register(PrometheusName, createPrometheusClient)
+	registry.Register(RSSName, createRSSClient)
+	return registry
+}
+
+func (r *Registry) Register(name string, factory Factory) {
+	r.factories[name] = factory
+}
+
+func (r *Registry) Create(name string, config ast.Node) (Integration, error) {
+	factory, exists := r.factories[name]
+	if !exists {
+		return nil, fmt.Errorf("unsupported integration: %s", name)
+	}
+	return factory(config)
+}
+
+func (r *Registry) GetSupportedNames() []string {
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
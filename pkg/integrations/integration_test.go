@@ -0,0 +1,83 @@
+package integrations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseNode(t *testing.T, src string) ast.Node {
+	t.Helper()
+	file, err := parser.ParseBytes([]byte(src), 0)
+	require.NoError(t, err)
+	return file.Docs[0].Body
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	registry := NewDefaultRegistry()
+	assert.Equal(t, []string{DexcomName, HomeAssistantName, NightscoutName, PrometheusName, RSSName}, registry.GetSupportedNames())
+
+	t.Run("home assistant", func(t *testing.T) {
+		integration, err := registry.Create(HomeAssistantName, parseNode(t, "url: ws://localhost:8123/api/websocket\ntoken: abc\nping_max_missed: 5"))
+		require.NoError(t, err)
+		client, ok := integration.(*HomeAssistantClient)
+		require.True(t, ok)
+		assert.Equal(t, HomeAssistantName, client.Name())
+		assert.Equal(t, "abc", client.config.Token)
+		assert.Equal(t, 5, client.pingMaxMissed)
+
+		// Not started yet, so not connected
+		assert.Equal(t, Degraded, client.Health().Status)
+		assert.Equal(t, "disconnected", client.Health().Details["state"])
+	})
+
+	t.Run("prometheus", func(t *testing.T) {
+		integration, err := registry.Create(PrometheusName, parseNode(t, "url: http://localhost:9090"))
+		require.NoError(t, err)
+		client, ok := integration.(*PrometheusClient)
+		require.True(t, ok)
+		assert.Equal(t, "http://localhost:9090", client.config.URL)
+		assert.Equal(t, Healthy, client.Health().Status)
+	})
+
+	t.Run("rss takes a list of feeds", func(t *testing.T) {
+		integration, err := registry.Create(RSSName, parseNode(t, "- url: https://example.com/a.xml\n- url: https://example.com/b.xml\n  name: b"))
+		require.NoError(t, err)
+		client, ok := integration.(*RSSClient)
+		require.True(t, ok)
+		require.Len(t, client.feeds, 2)
+		assert.Equal(t, "b", client.feeds[1].config.Name)
+	})
+
+	t.Run("nightscout", func(t *testing.T) {
+		integration, err := registry.Create(NightscoutName, parseNode(t, "url: https://ns.example.com\ntoken: dash-1234\npoll_interval: 2m"))
+		require.NoError(t, err)
+		client, ok := integration.(*NightscoutClient)
+		require.True(t, ok)
+		assert.Equal(t, 2*time.Minute, client.pollInterval)
+		assert.Implements(t, (*GlucoseSource)(nil), client)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := registry.Create(RSSName, parseNode(t, "url: https://example.com/a.xml"))
+		assert.Error(t, err)
+
+		_, err = registry.Create(NightscoutName, parseNode(t, "token: dash-1234"))
+		assert.ErrorContains(t, err, "url is required")
+	})
+
+	t.Run("glucose sources", func(t *testing.T) {
+		assert.True(t, IsGlucoseSource(DexcomName))
+		assert.True(t, IsGlucoseSource(NightscoutName))
+		assert.False(t, IsGlucoseSource(PrometheusName))
+	})
+
+	t.Run("unknown integration", func(t *testing.T) {
+		_, err := registry.Create("mqtt", nil)
+		assert.ErrorContains(t, err, "unsupported integration: mqtt")
+	})
+}
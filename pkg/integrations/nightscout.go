@@ -0,0 +1,328 @@
+package integrations
+
+import (
+	"context"
+	"crypto/sha1" // #nosec G505 - Nightscout expects the API secret as SHA-1
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/mntndev/dash/pkg/config"
+)
+
+const (
+	defaultNightscoutPoll    = time.Minute
+	nightscoutRequestTimeout = 30 * time.Second
+	// nightscoutMaxEntries covers the history of sensors that read every
+	// minute.
+	nightscoutMaxEntries = 180
+)
+
+// NightscoutClient polls a Nightscout site for sensor readings and the
+// status of the uploader and pump.
+type NightscoutClient struct {
+	glucoseNotifier
+	config       *config.NightscoutConfig
+	httpClient   *http.Client
+	pollInterval time.Duration
+	ctx          context.Context
+	cancel       context.CancelFunc
+
+	mu      sync.RWMutex
+	data    GlucoseData
+	lastErr error
+}
+
+// nightscoutEntry is an entry of /api/v1/entries. Only sgv entries are
+// sensor readings.
+type nightscoutEntry struct {
+	Type      string `json:"type"`
+	SGV       int    `json:"sgv"`
+	Date      int64  `json:"date"`
+	Direction string `json:"direction"`
+	Trend     *int   `json:"trend"`
+}
+
+// nightscoutDeviceStatus holds the parts of /api/v1/devicestatus the
+// dashboard shows. Loop and OpenAPS report insulin and carbs in different
+// places.
+type nightscoutDeviceStatus struct {
+	Device          string `json:"device"`
+	CreatedAt       string `json:"created_at"`
+	UploaderBattery *int   `json:"uploaderBattery"`
+	Uploader        *struct {
+		Battery *int `json:"battery"`
+	} `json:"uploader"`
+	Loop *struct {
+		IOB *struct {
+			IOB *float64 `json:"iob"`
+		} `json:"iob"`
+		COB *struct {
+			COB *float64 `json:"cob"`
+		} `json:"cob"`
+	} `json:"loop"`
+	OpenAPS *struct {
+		IOB       json.RawMessage `json:"iob"`
+		Suggested *struct {
+			COB *float64 `json:"COB"`
+		} `json:"suggested"`
+	} `json:"openaps"`
+}
+
+func NewNightscoutClient(cfg *config.NightscoutConfig) *NightscoutClient {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &NightscoutClient{
+		config:       cfg,
+		httpClient:   &http.Client{Timeout: nightscoutRequestTimeout},
+		pollInterval: defaultNightscoutPoll,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+
+	if cfg.PollInterval != "" {
+		if parsed, err := time.ParseDuration(cfg.PollInterval); err == nil && parsed > 0 {
+			client.pollInterval = parsed
+		} else {
+			log.Printf("Invalid poll_interval %q for Nightscout, using %s", cfg.PollInterval, defaultNightscoutPoll)
+		}
+	}
+	return client
+}
+
+func createNightscoutClient(node ast.Node) (Integration, error) {
+	var cfg config.NightscoutConfig
+	if node != nil {
+		if err := yaml.NodeToValue(node, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse nightscout config: %w", err)
+		}
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("nightscout url is required")
+	}
+	return NewNightscoutClient(&cfg), nil
+}
+
+func (nc *NightscoutClient) Name() string {
+	return NightscoutName
+}
+
+// Start polls Nightscout in the background until Stop is called.
+func (nc *NightscoutClient) Start() error {
+	go pollGlucose(nc.ctx, "Nightscout", nc.refresh, func(time.Time) time.Duration {
+		return nc.pollInterval
+	})
+	return nil
+}
+
+func (nc *NightscoutClient) Stop() error {
+	nc.cancel()
+	nc.stop()
+	return nil
+}
+
+// Health is degraded when the last fetch failed. The uploader battery is
+// reported when known.
+func (nc *NightscoutClient) Health() Health {
+	nc.mu.RLock()
+	defer nc.mu.RUnlock()
+	return nc.health()
+}
+
+func (nc *NightscoutClient) health() Health {
+	if nc.lastErr != nil {
+		return Health{Status: Degraded, Message: "Nightscout: " + nc.lastErr.Error()}
+	}
+	health := Health{Status: Healthy}
+	if device := nc.data.Device; device != nil && device.Battery != nil {
+		health.Details = map[string]interface{}{"uploader_battery": *device.Battery}
+	}
+	return health
+}
+
+// Glucose returns the readings fetched last.
+func (nc *NightscoutClient) Glucose() (GlucoseData, error) {
+	nc.mu.RLock()
+	defer nc.mu.RUnlock()
+	if len(nc.data.Readings) == 0 {
+		return GlucoseData{}, ErrNoGlucoseData
+	}
+	return nc.data, nil
+}
+
+// refresh fetches the readings and tells the subscribers about them.
+func (nc *NightscoutClient) refresh() error {
+	err := nc.fetch()
+
+	nc.mu.Lock()
+	changed := (err == nil) != (nc.lastErr == nil)
+	nc.lastErr = err
+	health := nc.health()
+	nc.mu.Unlock()
+
+	if changed {
+		nc.notifyHealth(health)
+	}
+	if err == nil {
+		nc.notify()
+	}
+	return err
+}
+
+func (nc *NightscoutClient) fetch() error {
+	readings, err := nc.readEntries()
+	if err != nil {
+		return err
+	}
+	if len(readings) == 0 {
+		return ErrNoGlucoseData
+	}
+
+	// Sites without a closed loop or uploader have no device status
+	device, err := nc.readDeviceStatus()
+	if err != nil {
+		log.Printf("Failed to read Nightscout device status: %v", err)
+	}
+
+	nc.mu.Lock()
+	nc.data = GlucoseData{Readings: readings, Updated: time.Now(), Device: device}
+	nc.mu.Unlock()
+	return nil
+}
+
+func (nc *NightscoutClient) readEntries() ([]GlucoseReading, error) {
+	params := url.Values{}
+	params.Set("count", strconv.Itoa(nightscoutMaxEntries))
+	params.Set("find[type]", "sgv")
+	params.Set("find[date][$gte]", strconv.FormatInt(time.Now().Add(-DexcomHistory).UnixMilli(), 10))
+
+	var entries []nightscoutEntry
+	if err := nc.get("/api/v1/entries.json", params, &entries); err != nil {
+		return nil, fmt.Errorf("failed to read entries: %w", err)
+	}
+
+	readings := make([]GlucoseReading, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type != "sgv" || entry.SGV <= 0 {
+			continue
+		}
+		trend := entry.Direction
+		if trend == "" && entry.Trend != nil {
+			trend = strconv.Itoa(*entry.Trend)
+		}
+		readings = append(readings, GlucoseReading{
+			Value: entry.SGV,
+			Trend: trend,
+			Time:  time.UnixMilli(entry.Date).UTC(),
+		})
+	}
+	return readings, nil
+}
+
+// readDeviceStatus returns the latest device status, or nil if there is
+// none.
+func (nc *NightscoutClient) readDeviceStatus() (*GlucoseDevice, error) {
+	params := url.Values{}
+	params.Set("count", "1")
+
+	var statuses []nightscoutDeviceStatus
+	if err := nc.get("/api/v1/devicestatus.json", params, &statuses); err != nil {
+		return nil, err
+	}
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+
+	status := statuses[0]
+	device := &GlucoseDevice{Name: status.Device, Battery: status.UploaderBattery}
+	if created, err := time.Parse(time.RFC3339, status.CreatedAt); err == nil {
+		device.Time = created
+	}
+	if device.Battery == nil && status.Uploader != nil {
+		device.Battery = status.Uploader.Battery
+	}
+	if loop := status.Loop; loop != nil {
+		if loop.IOB != nil {
+			device.IOB = loop.IOB.IOB
+		}
+		if loop.COB != nil {
+			device.COB = loop.COB.COB
+		}
+	}
+	if openAPS := status.OpenAPS; openAPS != nil {
+		if device.IOB == nil {
+			device.IOB = parseOpenAPSIOB(openAPS.IOB)
+		}
+		if device.COB == nil && openAPS.Suggested != nil {
+			device.COB = openAPS.Suggested.COB
+		}
+	}
+	return device, nil
+}
+
+// parseOpenAPSIOB reads the insulin on board, which OpenAPS reports as an
+// object or as a list of predictions starting with the current one.
+func parseOpenAPSIOB(raw json.RawMessage) *float64 {
+	var iob struct {
+		IOB *float64 `json:"iob"`
+	}
+	if json.Unmarshal(raw, &iob) == nil {
+		return iob.IOB
+	}
+	var predictions []struct {
+		IOB *float64 `json:"iob"`
+	}
+	if json.Unmarshal(raw, &predictions) == nil && len(predictions) > 0 {
+		return predictions[0].IOB
+	}
+	return nil
+}
+
+func (nc *NightscoutClient) get(path string, params url.Values, out interface{}) error {
+	if nc.config.Token != "" {
+		params.Set("token", nc.config.Token)
+	}
+	endpoint := strings.TrimSuffix(nc.config.URL, "/") + path + "?" + params.Encode()
+	req, err := http.NewRequestWithContext(nc.ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if nc.config.APISecret != "" && nc.config.Token == "" {
+		req.Header.Set("api-secret", hashNightscoutSecret(nc.config.APISecret))
+	}
+
+	res, err := nc.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("nightscout request failed: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("nightscout returned %s", res.Status)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read nightscout response: %w", err)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse nightscout response: %w", err)
+	}
+	return nil
+}
+
+// hashNightscoutSecret returns the API secret the way Nightscout expects it
+// in the api-secret header.
+func hashNightscoutSecret(secret string) string {
+	sum := sha1.Sum([]byte(secret)) // #nosec G401
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,153 @@
+package integrations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mntndev/dash/pkg/config"
+	"github.com/mntndev/dash/pkg/integrations/nstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestNightscoutClient(t *testing.T, cfg config.NightscoutConfig) *NightscoutClient {
+	t.Helper()
+	client := NewNightscoutClient(&cfg)
+	t.Cleanup(func() { _ = client.Stop() })
+	return client
+}
+
+func TestNightscoutClientReadings(t *testing.T) {
+	server := nstest.NewServer(t, "", "")
+	now := time.Now().Truncate(time.Millisecond)
+	server.AddReading(110, "Flat", now.Add(-10*time.Minute))
+	server.AddReading(118, "FortyFiveUp", now.Add(-5*time.Minute))
+	server.AddReading(131, "SingleUp", now)
+	server.AddReading(90, "Flat", now.Add(-4*time.Hour))
+	server.AddEntry(nstest.Entry{Type: "mbg", Date: now.UnixMilli()})
+	server.AddEntry(nstest.Entry{Type: "sgv", SGV: 125, Date: now.Add(-time.Minute).UnixMilli(), Trend: 4})
+
+	client := newTestNightscoutClient(t, config.NightscoutConfig{URL: server.URL() + "/"})
+	updates := client.Subscribe()
+	require.NoError(t, client.refresh())
+	assert.Len(t, updates, 1)
+
+	data, err := client.Glucose()
+	require.NoError(t, err)
+	require.Len(t, data.Readings, 4, "older and non-sensor entries are left out")
+	assert.Equal(t, GlucoseReading{Value: 131, Trend: "SingleUp", Time: now.UTC()}, data.Readings[0])
+	assert.Equal(t, "4", data.Readings[1].Trend, "the numeric trend if there is no direction")
+	assert.Equal(t, 110, data.Readings[3].Value)
+	assert.Nil(t, data.Device, "no device status")
+	assert.Equal(t, Healthy, client.Health().Status)
+}
+
+func TestNightscoutClientDeviceStatus(t *testing.T) {
+	server := nstest.NewServer(t, "", "")
+	now := time.Now().Truncate(time.Second)
+	server.AddReading(120, "Flat", now)
+
+	client := newTestNightscoutClient(t, config.NightscoutConfig{URL: server.URL()})
+
+	server.AddDeviceStatus(map[string]interface{}{
+		"device":   "loop://iPhone",
+		"uploader": map[string]interface{}{"battery": 64},
+		"loop": map[string]interface{}{
+			"iob": map[string]interface{}{"iob": 1.25},
+			"cob": map[string]interface{}{"cob": 18},
+		},
+	}, now)
+	require.NoError(t, client.refresh())
+	data, err := client.Glucose()
+	require.NoError(t, err)
+	require.NotNil(t, data.Device)
+	assert.Equal(t, "loop://iPhone", data.Device.Name)
+	assert.Equal(t, 64, *data.Device.Battery)
+	assert.Equal(t, 1.25, *data.Device.IOB)
+	assert.Equal(t, 18.0, *data.Device.COB)
+	assert.True(t, now.Equal(data.Device.Time))
+	assert.Equal(t, 64, client.Health().Details["uploader_battery"])
+
+	// The latest status wins, OpenAPS reports IOB as a list
+	server.AddDeviceStatus(map[string]interface{}{
+		"device":          "openaps://rig",
+		"uploaderBattery": 80,
+		"openaps": map[string]interface{}{
+			"iob":       []interface{}{map[string]interface{}{"iob": 0.5}, map[string]interface{}{"iob": 0.4}},
+			"suggested": map[string]interface{}{"COB": 7},
+		},
+	}, now)
+	require.NoError(t, client.refresh())
+	data, err = client.Glucose()
+	require.NoError(t, err)
+	assert.Equal(t, "openaps://rig", data.Device.Name)
+	assert.Equal(t, 80, *data.Device.Battery)
+	assert.Equal(t, 0.5, *data.Device.IOB)
+	assert.Equal(t, 7.0, *data.Device.COB)
+}
+
+func TestNightscoutClientAuth(t *testing.T) {
+	server := nstest.NewServer(t, "super-secret-1", "dash-1234")
+	server.AddReading(120, "Flat", time.Now())
+
+	tests := []struct {
+		name string
+		cfg  config.NightscoutConfig
+		ok   bool
+	}{
+		{name: "api secret", cfg: config.NightscoutConfig{APISecret: "super-secret-1"}, ok: true},
+		{name: "token", cfg: config.NightscoutConfig{Token: "dash-1234"}, ok: true},
+		{name: "wrong secret", cfg: config.NightscoutConfig{APISecret: "guess"}},
+		{name: "none", cfg: config.NightscoutConfig{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.URL = server.URL()
+			err := newTestNightscoutClient(t, tt.cfg).refresh()
+			if tt.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, "401")
+			}
+		})
+	}
+}
+
+func TestNightscoutClientHealth(t *testing.T) {
+	server := nstest.NewServer(t, "", "")
+	client := newTestNightscoutClient(t, config.NightscoutConfig{URL: server.URL()})
+	health := client.SubscribeHealth()
+
+	assert.ErrorIs(t, client.refresh(), ErrNoGlucoseData)
+	assert.Equal(t, Degraded, (<-health).Status)
+	_, err := client.Glucose()
+	assert.ErrorIs(t, err, ErrNoGlucoseData)
+
+	server.AddReading(120, "Flat", time.Now())
+	require.NoError(t, client.refresh())
+	assert.Equal(t, Healthy, (<-health).Status)
+
+	// Readings fetched before are kept while the site is down
+	server.SetFailing(true)
+	assert.Error(t, client.refresh())
+	assert.Equal(t, Degraded, client.Health().Status)
+	data, err := client.Glucose()
+	require.NoError(t, err)
+	assert.Len(t, data.Readings, 1)
+}
+
+func TestNightscoutClientPolls(t *testing.T) {
+	server := nstest.NewServer(t, "", "")
+	server.AddReading(120, "Flat", time.Now())
+	client := newTestNightscoutClient(t, config.NightscoutConfig{URL: server.URL(), PollInterval: "20ms"})
+	updates := client.Subscribe()
+
+	require.NoError(t, client.Start())
+	<-updates
+	<-updates
+	assert.GreaterOrEqual(t, server.Requests("/api/v1/entries"), 2)
+
+	require.NoError(t, client.Stop())
+	_, open := <-updates
+	assert.False(t, open, "stopping closes the subscriptions")
+}
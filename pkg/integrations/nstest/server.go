@@ -0,0 +1,205 @@
+// Package nstest provides a fake Nightscout server for tests.
+//
+// The server answers the parts of the Nightscout REST API v1 that
+// NightscoutClient uses: /api/v1/entries and /api/v1/devicestatus, with the
+// count, find[type] and find[date][$gte] parameters. Requests authenticate
+// with the SHA-1 of the API secret in the api-secret header or with a token
+// query parameter.
+package nstest
+
+import (
+	"crypto/sha1" // #nosec G505 - Nightscout expects the API secret as SHA-1
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Entry is a sensor reading as served by /api/v1/entries.
+type Entry struct {
+	ID         string `json:"_id"`
+	Type       string `json:"type"`
+	SGV        int    `json:"sgv"`
+	Date       int64  `json:"date"`
+	DateString string `json:"dateString"`
+	Direction  string `json:"direction,omitempty"`
+	Trend      int    `json:"trend,omitempty"`
+	Device     string `json:"device"`
+}
+
+// trends are the numeric trends Nightscout stores next to the direction.
+var trends = map[string]int{
+	"DoubleUp":          1,
+	"SingleUp":          2,
+	"FortyFiveUp":       3,
+	"Flat":              4,
+	"FortyFiveDown":     5,
+	"SingleDown":        6,
+	"DoubleDown":        7,
+	"NOT COMPUTABLE":    8,
+	"RATE OUT OF RANGE": 9,
+}
+
+// Server is a fake Nightscout site. Entries and device statuses are kept in
+// memory.
+type Server struct {
+	// APISecret and Token are the credentials clients may use. If both are
+	// empty every request is allowed, like a site readable by anyone.
+	APISecret string
+	Token     string
+
+	httpServer *httptest.Server
+
+	mu       sync.Mutex
+	entries  []Entry
+	statuses []map[string]interface{}
+	requests map[string]int
+	failing  bool
+}
+
+// NewServer starts a server that accepts apiSecret and token. It is closed
+// when the test finishes.
+func NewServer(t testing.TB, apiSecret, token string) *Server {
+	t.Helper()
+
+	s := &Server{
+		APISecret: apiSecret,
+		Token:     token,
+		requests:  make(map[string]int),
+	}
+	s.httpServer = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// URL returns the base URL of the site.
+func (s *Server) URL() string {
+	return s.httpServer.URL
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	s.httpServer.Close()
+}
+
+// AddReading records a sensor reading with a direction such as "Flat" or
+// "SingleUp".
+func (s *Server) AddReading(sgv int, direction string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, Entry{
+		ID:         strconv.Itoa(len(s.entries) + 1),
+		Type:       "sgv",
+		SGV:        sgv,
+		Date:       at.UnixMilli(),
+		DateString: at.UTC().Format(time.RFC3339),
+		Direction:  direction,
+		Trend:      trends[direction],
+		Device:     "nstest",
+	})
+}
+
+// AddEntry records an entry as is, e.g. a calibration or one without a
+// direction.
+func (s *Server) AddEntry(entry Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entry)
+}
+
+// AddDeviceStatus records a device status, free-form like the ones uploaders
+// and closed loop systems post. created_at is set to at.
+func (s *Server) AddDeviceStatus(status map[string]interface{}, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status["created_at"] = at.UTC().Format(time.RFC3339)
+	s.statuses = append(s.statuses, status)
+}
+
+// SetFailing makes every request fail with 500 Internal Server Error.
+func (s *Server) SetFailing(failing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+}
+
+// Requests returns how many authorized requests were made for path.
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"status": 401, "message": "Unauthorized"})
+		return
+	}
+	if s.failing {
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"status": 500, "message": "Internal Server Error"})
+		return
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, ".json")
+	s.requests[path]++
+	query := r.URL.Query()
+	count := 10
+	if n, err := strconv.Atoi(query.Get("count")); err == nil && n > 0 {
+		count = n
+	}
+
+	switch path {
+	case "/api/v1/entries", "/api/v1/entries/sgv":
+		entryType := query.Get("find[type]")
+		if path == "/api/v1/entries/sgv" {
+			entryType = "sgv"
+		}
+		since, _ := strconv.ParseInt(query.Get("find[date][$gte]"), 10, 64)
+
+		entries := make([]Entry, 0, len(s.entries))
+		for _, entry := range s.entries {
+			if (entryType == "" || entry.Type == entryType) && entry.Date >= since {
+				entries = append(entries, entry)
+			}
+		}
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date > entries[j].Date })
+		writeJSON(w, http.StatusOK, entries[:min(count, len(entries))])
+	case "/api/v1/devicestatus":
+		statuses := make([]map[string]interface{}, 0, count)
+		for i := len(s.statuses) - 1; i >= 0 && len(statuses) < count; i-- {
+			statuses = append(statuses, s.statuses[i])
+		}
+		writeJSON(w, http.StatusOK, statuses)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.APISecret == "" && s.Token == "" {
+		return true
+	}
+	if s.Token != "" && r.URL.Query().Get("token") == s.Token {
+		return true
+	}
+	if s.APISecret != "" {
+		sum := sha1.Sum([]byte(s.APISecret)) // #nosec G401
+		return r.Header.Get("api-secret") == hex.EncodeToString(sum[:])
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
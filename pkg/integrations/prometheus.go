@@ -0,0 +1,280 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/mntndev/dash/pkg/config"
+)
+
+const prometheusRequestTimeout = 30 * time.Second
+
+// PrometheusClient runs PromQL queries against the Prometheus HTTP API.
+type PrometheusClient struct {
+	config     *config.PrometheusConfig
+	httpClient *http.Client
+
+	mu      sync.RWMutex
+	lastErr error
+}
+
+// PrometheusSample is one series of an instant query result.
+type PrometheusSample struct {
+	Labels map[string]string
+	Time   time.Time
+	Value  float64
+}
+
+// PrometheusSeries is one series of a range query result.
+type PrometheusSeries struct {
+	Labels map[string]string
+	Points []PrometheusPoint
+}
+
+type PrometheusPoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// PrometheusError is an error reported by the Prometheus API.
+type PrometheusError struct {
+	Type    string
+	Message string
+}
+
+func (e *PrometheusError) Error() string {
+	return fmt.Sprintf("prometheus %s: %s", e.Type, e.Message)
+}
+
+type prometheusResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// prometheusValue is a [timestamp, "value"] pair.
+type prometheusValue [2]interface{}
+
+func NewPrometheusClient(cfg *config.PrometheusConfig) *PrometheusClient {
+	return &PrometheusClient{
+		config:     cfg,
+		httpClient: &http.Client{Timeout: prometheusRequestTimeout},
+	}
+}
+
+func createPrometheusClient(node ast.Node) (Integration, error) {
+	var cfg config.PrometheusConfig
+	if node != nil {
+		if err := yaml.NodeToValue(node, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse prometheus config: %w", err)
+		}
+	}
+	return NewPrometheusClient(&cfg), nil
+}
+
+func (pc *PrometheusClient) Name() string {
+	return PrometheusName
+}
+
+// Start does nothing, queries are made by the widgets on their own schedule.
+func (pc *PrometheusClient) Start() error {
+	return nil
+}
+
+func (pc *PrometheusClient) Stop() error {
+	return nil
+}
+
+// Health is degraded when the last request did not reach Prometheus. Query
+// errors such as bad PromQL are the widget's problem and do not count.
+func (pc *PrometheusClient) Health() Health {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	if pc.lastErr != nil {
+		return Health{Status: Degraded, Message: "Prometheus: " + pc.lastErr.Error()}
+	}
+	return Health{Status: Healthy}
+}
+
+func (pc *PrometheusClient) setLastErr(err error) {
+	pc.mu.Lock()
+	pc.lastErr = err
+	pc.mu.Unlock()
+}
+
+// Query evaluates an instant query at the given time. Scalar results are
+// returned as a single sample without labels.
+func (pc *PrometheusClient) Query(ctx context.Context, query string, at time.Time) ([]PrometheusSample, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	if !at.IsZero() {
+		params.Set("time", formatPrometheusTime(at))
+	}
+
+	resp, err := pc.get(ctx, "/api/v1/query", params)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.Data.ResultType {
+	case "vector":
+		var vector []struct {
+			Metric map[string]string `json:"metric"`
+			Value  prometheusValue   `json:"value"`
+		}
+		if err := json.Unmarshal(resp.Data.Result, &vector); err != nil {
+			return nil, fmt.Errorf("failed to parse vector: %w", err)
+		}
+
+		samples := make([]PrometheusSample, 0, len(vector))
+		for _, v := range vector {
+			t, value, err := v.Value.parse()
+			if err != nil {
+				return nil, err
+			}
+			samples = append(samples, PrometheusSample{Labels: v.Metric, Time: t, Value: value})
+		}
+		return samples, nil
+	case "scalar":
+		var scalar prometheusValue
+		if err := json.Unmarshal(resp.Data.Result, &scalar); err != nil {
+			return nil, fmt.Errorf("failed to parse scalar: %w", err)
+		}
+		t, value, err := scalar.parse()
+		if err != nil {
+			return nil, err
+		}
+		return []PrometheusSample{{Labels: map[string]string{}, Time: t, Value: value}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported result type %q", resp.Data.ResultType)
+	}
+}
+
+// QueryRange evaluates a query over a time range at the given resolution.
+func (pc *PrometheusClient) QueryRange(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]PrometheusSeries, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", formatPrometheusTime(start))
+	params.Set("end", formatPrometheusTime(end))
+	params.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+
+	resp, err := pc.get(ctx, "/api/v1/query_range", params)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Data.ResultType != "matrix" {
+		return nil, fmt.Errorf("unsupported result type %q", resp.Data.ResultType)
+	}
+
+	var matrix []struct {
+		Metric map[string]string `json:"metric"`
+		Values []prometheusValue `json:"values"`
+	}
+	if err := json.Unmarshal(resp.Data.Result, &matrix); err != nil {
+		return nil, fmt.Errorf("failed to parse matrix: %w", err)
+	}
+
+	series := make([]PrometheusSeries, 0, len(matrix))
+	for _, m := range matrix {
+		points := make([]PrometheusPoint, 0, len(m.Values))
+		for _, v := range m.Values {
+			t, value, err := v.parse()
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, PrometheusPoint{Time: t, Value: value})
+		}
+		series = append(series, PrometheusSeries{Labels: m.Metric, Points: points})
+	}
+	return series, nil
+}
+
+// get runs a request and remembers whether Prometheus could be reached.
+func (pc *PrometheusClient) get(ctx context.Context, path string, params url.Values) (*prometheusResponse, error) {
+	resp, err := pc.request(ctx, path, params)
+
+	var promErr *PrometheusError
+	switch {
+	case err == nil || errors.As(err, &promErr):
+		pc.setLastErr(nil)
+	case ctx.Err() == nil:
+		pc.setLastErr(err)
+	}
+	return resp, err
+}
+
+func (pc *PrometheusClient) request(ctx context.Context, path string, params url.Values) (*prometheusResponse, error) {
+	endpoint := strings.TrimSuffix(pc.config.URL, "/") + path + "?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	switch {
+	case pc.config.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+pc.config.BearerToken)
+	case pc.config.Username != "" || pc.config.Password != "":
+		req.SetBasicAuth(pc.config.Username, pc.config.Password)
+	}
+
+	res, err := pc.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("prometheus request failed: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prometheus response: %w", err)
+	}
+
+	var resp prometheusResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		// Auth proxies and the like answer with plain text or HTML
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("prometheus returned %s", res.Status)
+		}
+		return nil, fmt.Errorf("failed to parse prometheus response: %w", err)
+	}
+
+	if resp.Status != "success" {
+		return nil, &PrometheusError{Type: resp.ErrorType, Message: resp.Error}
+	}
+	return &resp, nil
+}
+
+func (v prometheusValue) parse() (time.Time, float64, error) {
+	ts, ok := v[0].(float64)
+	if !ok {
+		return time.Time{}, 0, fmt.Errorf("invalid sample timestamp %v", v[0])
+	}
+	raw, ok := v[1].(string)
+	if !ok {
+		return time.Time{}, 0, fmt.Errorf("invalid sample value %v", v[1])
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("invalid sample value: %w", err)
+	}
+	return unixSeconds(ts), value, nil
+}
+
+func formatPrometheusTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', 3, 64)
+}
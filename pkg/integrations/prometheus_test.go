@@ -0,0 +1,166 @@
+package integrations
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mntndev/dash/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPrometheusServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPrometheusQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		expected []PrometheusSample
+	}{
+		{
+			name:     "vector",
+			response: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"instance":"nas"},"value":[1700000000.5,"0.25"]},{"metric":{"instance":"router"},"value":[1700000000.5,"12"]}]}}`,
+			expected: []PrometheusSample{
+				{Labels: map[string]string{"instance": "nas"}, Time: time.Unix(1700000000, 5e8).UTC(), Value: 0.25},
+				{Labels: map[string]string{"instance": "router"}, Time: time.Unix(1700000000, 5e8).UTC(), Value: 12},
+			},
+		},
+		{
+			name:     "scalar",
+			response: `{"status":"success","data":{"resultType":"scalar","result":[1700000000,"42"]}}`,
+			expected: []PrometheusSample{
+				{Labels: map[string]string{}, Time: time.Unix(1700000000, 0).UTC(), Value: 42},
+			},
+		},
+		{
+			name:     "empty vector",
+			response: `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+			expected: []PrometheusSample{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newPrometheusServer(t, func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/v1/query", r.URL.Path)
+				assert.Equal(t, "up", r.URL.Query().Get("query"))
+				_, _ = w.Write([]byte(tt.response))
+			})
+
+			client := NewPrometheusClient(&config.PrometheusConfig{URL: server.URL})
+			samples, err := client.Query(context.Background(), "up", time.Time{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, samples)
+		})
+	}
+}
+
+func TestPrometheusQueryRange(t *testing.T) {
+	server := newPrometheusServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/query_range", r.URL.Path)
+		query := r.URL.Query()
+		assert.Equal(t, "rate(node_cpu_seconds_total[5m])", query.Get("query"))
+		assert.Equal(t, "1700000000.000", query.Get("start"))
+		assert.Equal(t, "1700003600.000", query.Get("end"))
+		assert.Equal(t, "60", query.Get("step"))
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"cpu":"0"},"values":[[1700000000,"1.5"],[1700000060,"2"]]},
+			{"metric":{"cpu":"1"},"values":[[1700000000,"0.5"]]}
+		]}}`))
+	})
+
+	client := NewPrometheusClient(&config.PrometheusConfig{URL: server.URL + "/"})
+	start := time.Unix(1700000000, 0)
+	series, err := client.QueryRange(context.Background(), "rate(node_cpu_seconds_total[5m])", start, start.Add(time.Hour), time.Minute)
+	require.NoError(t, err)
+	require.Len(t, series, 2)
+
+	assert.Equal(t, map[string]string{"cpu": "0"}, series[0].Labels)
+	assert.Equal(t, []PrometheusPoint{
+		{Time: time.Unix(1700000000, 0).UTC(), Value: 1.5},
+		{Time: time.Unix(1700000060, 0).UTC(), Value: 2},
+	}, series[0].Points)
+	assert.Len(t, series[1].Points, 1)
+}
+
+func TestPrometheusErrors(t *testing.T) {
+	t.Run("query error", func(t *testing.T) {
+		server := newPrometheusServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error at char 4"}`))
+		})
+
+		client := NewPrometheusClient(&config.PrometheusConfig{URL: server.URL})
+		_, err := client.Query(context.Background(), "up{", time.Time{})
+		var promErr *PrometheusError
+		require.ErrorAs(t, err, &promErr)
+		assert.Equal(t, "bad_data", promErr.Type)
+		assert.Equal(t, "parse error at char 4", promErr.Message)
+	})
+
+	t.Run("non-JSON response", func(t *testing.T) {
+		server := newPrometheusServer(t, func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		})
+
+		client := NewPrometheusClient(&config.PrometheusConfig{URL: server.URL})
+		_, err := client.Query(context.Background(), "up", time.Time{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "401")
+	})
+}
+
+func TestPrometheusAuth(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.PrometheusConfig
+		check  func(t *testing.T, r *http.Request)
+	}{
+		{
+			name:   "basic auth",
+			config: config.PrometheusConfig{Username: "admin", Password: "secret"},
+			check: func(t *testing.T, r *http.Request) {
+				username, password, ok := r.BasicAuth()
+				assert.True(t, ok)
+				assert.Equal(t, "admin", username)
+				assert.Equal(t, "secret", password)
+			},
+		},
+		{
+			name:   "bearer token",
+			config: config.PrometheusConfig{BearerToken: "token123"},
+			check: func(t *testing.T, r *http.Request) {
+				assert.Equal(t, "Bearer token123", r.Header.Get("Authorization"))
+			},
+		},
+		{
+			name:   "no auth",
+			config: config.PrometheusConfig{},
+			check: func(t *testing.T, r *http.Request) {
+				assert.Empty(t, r.Header.Get("Authorization"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newPrometheusServer(t, func(w http.ResponseWriter, r *http.Request) {
+				tt.check(t, r)
+				_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+			})
+
+			cfg := tt.config
+			cfg.URL = server.URL
+			_, err := NewPrometheusClient(&cfg).Query(context.Background(), "up", time.Time{})
+			require.NoError(t, err)
+		})
+	}
+}
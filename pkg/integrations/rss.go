@@ -0,0 +1,467 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/mntndev/dash/pkg/config"
+)
+
+const (
+	defaultRSSRefresh = 15 * time.Minute
+	rssRequestTimeout = 30 * time.Second
+	// maxFeedItems caps the items kept per feed.
+	maxFeedItems = 100
+)
+
+// FeedItem is a headline from an RSS or Atom feed.
+type FeedItem struct {
+	GUID      string
+	Title     string
+	Link      string
+	Published time.Time
+	// Feed is the configured name of the feed, or its title if it has none.
+	Feed string
+}
+
+// RSSClient polls the configured feeds, each on its own interval, and keeps
+// their items in memory. Feeds are fetched with conditional requests, so
+// unchanged feeds cost a 304.
+type RSSClient struct {
+	feeds      []*feed
+	httpClient *http.Client
+	ctx        context.Context
+	cancel     context.CancelFunc
+
+	mu        sync.RWMutex
+	listeners []chan struct{}
+}
+
+type feed struct {
+	config   config.RSSConfig
+	interval time.Duration
+
+	// Guarded by RSSClient.mu
+	title        string
+	etag         string
+	lastModified string
+	items        []FeedItem
+	err          error
+}
+
+func NewRSSClient(cfgs []config.RSSConfig) *RSSClient {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &RSSClient{
+		httpClient: &http.Client{Timeout: rssRequestTimeout},
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	for _, cfg := range cfgs {
+		interval := defaultRSSRefresh
+		if cfg.RefreshInterval != "" {
+			if parsed, err := time.ParseDuration(cfg.RefreshInterval); err == nil && parsed > 0 {
+				interval = parsed
+			} else {
+				log.Printf("Invalid refresh_interval %q for feed %s, using %s", cfg.RefreshInterval, cfg.URL, defaultRSSRefresh)
+			}
+		}
+		client.feeds = append(client.feeds, &feed{config: cfg, interval: interval})
+	}
+
+	return client
+}
+
+func createRSSClient(node ast.Node) (Integration, error) {
+	var cfgs []config.RSSConfig
+	if node != nil {
+		if err := yaml.NodeToValue(node, &cfgs); err != nil {
+			return nil, fmt.Errorf("failed to parse rss config: %w", err)
+		}
+	}
+	return NewRSSClient(cfgs), nil
+}
+
+func (rc *RSSClient) Name() string {
+	return RSSName
+}
+
+// Start polls every feed in the background until Stop is called.
+func (rc *RSSClient) Start() error {
+	for _, f := range rc.feeds {
+		go rc.poll(f)
+	}
+	return nil
+}
+
+func (rc *RSSClient) Stop() error {
+	rc.cancel()
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for _, ch := range rc.listeners {
+		close(ch)
+	}
+	rc.listeners = nil
+	return nil
+}
+
+// Health is degraded while any feed fails to fetch. The other feeds keep
+// working and the failing one is retried on its next refresh.
+func (rc *RSSClient) Health() Health {
+	if err := rc.Err(nil); err != nil {
+		return Health{Status: Degraded, Message: "RSS: " + err.Error()}
+	}
+	return Health{Status: Healthy}
+}
+
+func (rc *RSSClient) poll(f *feed) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := rc.refresh(rc.ctx, f); err != nil && rc.ctx.Err() == nil {
+			log.Printf("Failed to fetch feed %s: %v", f.config.URL, err)
+		}
+
+		select {
+		case <-rc.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh fetches a feed and merges new items into the cache.
+func (rc *RSSClient) refresh(ctx context.Context, f *feed) error {
+	err := rc.fetch(ctx, f)
+
+	rc.mu.Lock()
+	f.err = err
+	rc.mu.Unlock()
+
+	if err == nil {
+		rc.notify()
+	}
+	return err
+}
+
+func (rc *RSSClient) fetch(ctx context.Context, f *feed) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.config.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+
+	rc.mu.RLock()
+	if f.etag != "" {
+		req.Header.Set("If-None-Match", f.etag)
+	}
+	if f.lastModified != "" {
+		req.Header.Set("If-Modified-Since", f.lastModified)
+	}
+	rc.mu.RUnlock()
+
+	res, err := rc.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read feed: %w", err)
+	}
+
+	title, items, err := parseFeed(body)
+	if err != nil {
+		return err
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	f.etag = res.Header.Get("ETag")
+	f.lastModified = res.Header.Get("Last-Modified")
+	f.title = title
+	f.items = mergeFeedItems(f.items, items, f.name())
+	return nil
+}
+
+// name is how the feed is labelled on its items. Must be called with
+// RSSClient.mu held.
+func (f *feed) name() string {
+	if f.config.Name != "" {
+		return f.config.Name
+	}
+	if f.title != "" {
+		return f.title
+	}
+	return f.config.URL
+}
+
+func (f *feed) matches(selectors []string) bool {
+	if len(selectors) == 0 {
+		return true
+	}
+	return slices.Contains(selectors, f.config.URL) || (f.config.Name != "" && slices.Contains(selectors, f.config.Name))
+}
+
+// mergeFeedItems adds fetched items to the cached ones. Items are matched by
+// GUID, so an item that was edited keeps its place and picks up the changes.
+func mergeFeedItems(cached, fetched []FeedItem, feedName string) []FeedItem {
+	byGUID := make(map[string]int, len(cached))
+	merged := slices.Clone(cached)
+	for i, item := range merged {
+		byGUID[item.GUID] = i
+	}
+
+	for _, item := range fetched {
+		item.Feed = feedName
+		if i, exists := byGUID[item.GUID]; exists {
+			if item.Published.IsZero() {
+				item.Published = merged[i].Published
+			}
+			merged[i] = item
+			continue
+		}
+		byGUID[item.GUID] = len(merged)
+		merged = append(merged, item)
+	}
+
+	sortFeedItems(merged)
+	if len(merged) > maxFeedItems {
+		merged = merged[:maxFeedItems]
+	}
+	return merged
+}
+
+// sortFeedItems orders items newest first. Undated items go last.
+func sortFeedItems(items []FeedItem) {
+	slices.SortStableFunc(items, func(a, b FeedItem) int {
+		return b.Published.Compare(a.Published)
+	})
+}
+
+// Items returns the newest items of the selected feeds, newest first. Feeds
+// are selected by name or URL; no selectors means all feeds. A limit of zero
+// or less returns every item.
+func (rc *RSSClient) Items(feeds []string, limit int) []FeedItem {
+	rc.mu.RLock()
+	var items []FeedItem
+	for _, f := range rc.feeds {
+		if f.matches(feeds) {
+			items = append(items, f.items...)
+		}
+	}
+	rc.mu.RUnlock()
+
+	sortFeedItems(items)
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+// Err returns the error of the last fetch of the selected feeds, if any
+// failed.
+func (rc *RSSClient) Err(feeds []string) error {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	for _, f := range rc.feeds {
+		if f.matches(feeds) && f.err != nil {
+			return fmt.Errorf("%s: %w", f.name(), f.err)
+		}
+	}
+	return nil
+}
+
+// Subscribe returns a channel that receives a value whenever a feed was
+// fetched. Updates are coalesced for slow readers. The channel is closed
+// when the client is stopped.
+func (rc *RSSClient) Subscribe() <-chan struct{} {
+	ch := make(chan struct{}, 1)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.ctx.Err() != nil {
+		close(ch)
+		return ch
+	}
+	rc.listeners = append(rc.listeners, ch)
+	return ch
+}
+
+func (rc *RSSClient) Unsubscribe(ch <-chan struct{}) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for i, listener := range rc.listeners {
+		if listener == ch {
+			close(listener)
+			rc.listeners = append(rc.listeners[:i], rc.listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+func (rc *RSSClient) notify() {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	for _, ch := range rc.listeners {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+type rssDocument struct {
+	Channel struct {
+		Title string `xml:"title"`
+		Items []struct {
+			Title   string `xml:"title"`
+			Link    string `xml:"link"`
+			GUID    string `xml:"guid"`
+			PubDate string `xml:"pubDate"`
+			Date    string `xml:"http://purl.org/dc/elements/1.1/ date"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+type atomDocument struct {
+	Title   string `xml:"title"`
+	Entries []struct {
+		Title string `xml:"title"`
+		ID    string `xml:"id"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+	} `xml:"entry"`
+}
+
+// parseFeed parses an RSS 2.0 or Atom document.
+func parseFeed(data []byte) (string, []FeedItem, error) {
+	root, err := rootElement(data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch root.Local {
+	case "rss":
+		var doc rssDocument
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return "", nil, fmt.Errorf("failed to parse RSS feed: %w", err)
+		}
+
+		items := make([]FeedItem, 0, len(doc.Channel.Items))
+		for _, it := range doc.Channel.Items {
+			published := it.PubDate
+			if published == "" {
+				published = it.Date
+			}
+			items = append(items, FeedItem{
+				GUID:      itemGUID(it.GUID, it.Link, it.Title),
+				Title:     strings.TrimSpace(it.Title),
+				Link:      strings.TrimSpace(it.Link),
+				Published: parseFeedTime(published),
+			})
+		}
+		return strings.TrimSpace(doc.Channel.Title), items, nil
+	case "feed":
+		var doc atomDocument
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return "", nil, fmt.Errorf("failed to parse Atom feed: %w", err)
+		}
+
+		items := make([]FeedItem, 0, len(doc.Entries))
+		for _, entry := range doc.Entries {
+			var link string
+			for _, l := range entry.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					link = l.Href
+					break
+				}
+			}
+			published := entry.Published
+			if published == "" {
+				published = entry.Updated
+			}
+			items = append(items, FeedItem{
+				GUID:      itemGUID(entry.ID, link, entry.Title),
+				Title:     strings.TrimSpace(entry.Title),
+				Link:      strings.TrimSpace(link),
+				Published: parseFeedTime(published),
+			})
+		}
+		return strings.TrimSpace(doc.Title), items, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported feed format <%s>", root.Local)
+	}
+}
+
+func rootElement(data []byte) (xml.Name, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}, fmt.Errorf("failed to parse feed: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+// itemGUID identifies an item. Feeds without GUIDs fall back to the link and
+// then the title.
+func itemGUID(guid, link, title string) string {
+	for _, candidate := range []string{guid, link, title} {
+		if candidate = strings.TrimSpace(candidate); candidate != "" {
+			return candidate
+		}
+	}
+	return ""
+}
+
+var feedTimeLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC822Z,
+	time.RFC822,
+}
+
+// parseFeedTime parses the date formats seen in the wild, returning the zero
+// time for anything else.
+func parseFeedTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
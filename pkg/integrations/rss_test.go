@@ -0,0 +1,181 @@
+package integrations
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mntndev/dash/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readFeedFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return data
+}
+
+func TestParseFeed(t *testing.T) {
+	t.Run("rss", func(t *testing.T) {
+		title, items, err := parseFeed(readFeedFixture(t, "feed.rss.xml"))
+		require.NoError(t, err)
+		assert.Equal(t, "Homelab News", title)
+		require.Len(t, items, 3)
+
+		assert.Equal(t, "news-3", items[0].GUID)
+		assert.Equal(t, "Kernel 6.18 released", items[0].Title)
+		assert.Equal(t, "https://news.example.com/kernel-6-18", items[0].Link)
+		assert.True(t, items[0].Published.Equal(time.Date(2025, 10, 13, 9, 30, 0, 0, time.UTC)))
+
+		assert.True(t, items[1].Published.Equal(time.Date(2025, 8, 12, 18, 0, 0, 0, time.UTC)))
+
+		// Without a GUID the link identifies the item
+		assert.Equal(t, "https://news.example.com/no-guid", items[2].GUID)
+		assert.Equal(t, "Item without GUID", items[2].Title)
+		assert.True(t, items[2].Published.Equal(time.Date(2025, 8, 1, 8, 0, 0, 0, time.UTC)))
+	})
+
+	t.Run("atom", func(t *testing.T) {
+		title, items, err := parseFeed(readFeedFixture(t, "feed.atom.xml"))
+		require.NoError(t, err)
+		assert.Equal(t, "Project Releases", title)
+		require.Len(t, items, 2)
+
+		assert.Equal(t, "tag:example.org,2025:v2.0.0", items[0].GUID)
+		assert.Equal(t, "https://example.org/releases/v2.0.0", items[0].Link)
+		assert.True(t, items[0].Published.Equal(time.Date(2025, 10, 14, 12, 0, 0, 0, time.UTC)))
+
+		// Entries without published fall back to updated
+		assert.Equal(t, "https://example.org/releases/v1.9.0", items[1].Link)
+		assert.True(t, items[1].Published.Equal(time.Date(2025, 9, 1, 8, 0, 0, 0, time.UTC)))
+	})
+
+	t.Run("unsupported document", func(t *testing.T) {
+		_, _, err := parseFeed([]byte(`<html><body>Not a feed</body></html>`))
+		assert.Error(t, err)
+	})
+}
+
+func TestRSSClientConditionalRequests(t *testing.T) {
+	feedData := readFeedFixture(t, "feed.rss.xml")
+	var requests, notModified atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			assert.Equal(t, "Mon, 13 Oct 2025 09:30:00 GMT", r.Header.Get("If-Modified-Since"))
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 13 Oct 2025 09:30:00 GMT")
+		_, _ = w.Write(feedData)
+	}))
+	defer server.Close()
+
+	client := NewRSSClient([]config.RSSConfig{{Name: "news", URL: server.URL}})
+	defer func() { _ = client.Stop() }()
+	f := client.feeds[0]
+
+	require.NoError(t, client.refresh(context.Background(), f))
+	require.NoError(t, client.refresh(context.Background(), f))
+
+	assert.Equal(t, int32(2), requests.Load())
+	assert.Equal(t, int32(1), notModified.Load())
+
+	// The cached items survive the 304
+	items := client.Items(nil, 0)
+	require.Len(t, items, 3)
+	assert.Equal(t, "news", items[0].Feed)
+}
+
+func TestRSSClientDeduplicates(t *testing.T) {
+	var version atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if version.Load() == 0 {
+			_, _ = w.Write([]byte(`<rss><channel><title>Feed</title>
+				<item><title>First</title><guid>1</guid><pubDate>Mon, 13 Oct 2025 09:00:00 +0000</pubDate></item>
+			</channel></rss>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<rss><channel><title>Feed</title>
+			<item><title>Second</title><guid>2</guid><pubDate>Mon, 13 Oct 2025 10:00:00 +0000</pubDate></item>
+			<item><title>First (updated)</title><guid>1</guid><pubDate>Mon, 13 Oct 2025 09:00:00 +0000</pubDate></item>
+		</channel></rss>`))
+	}))
+	defer server.Close()
+
+	client := NewRSSClient([]config.RSSConfig{{URL: server.URL}})
+	defer func() { _ = client.Stop() }()
+	f := client.feeds[0]
+
+	require.NoError(t, client.refresh(context.Background(), f))
+	version.Store(1)
+	require.NoError(t, client.refresh(context.Background(), f))
+
+	items := client.Items(nil, 0)
+	require.Len(t, items, 2)
+	assert.Equal(t, "Second", items[0].Title)
+	assert.Equal(t, "First (updated)", items[1].Title)
+	assert.Equal(t, "Feed", items[1].Feed)
+}
+
+func TestRSSClientItems(t *testing.T) {
+	rssData := readFeedFixture(t, "feed.rss.xml")
+	atomData := readFeedFixture(t, "feed.atom.xml")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rss":
+			_, _ = w.Write(rssData)
+		case "/atom":
+			_, _ = w.Write(atomData)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewRSSClient([]config.RSSConfig{
+		{Name: "news", URL: server.URL + "/rss", RefreshInterval: "1h"},
+		{URL: server.URL + "/atom", RefreshInterval: "1h"},
+		{Name: "broken", URL: server.URL + "/missing", RefreshInterval: "1h"},
+	})
+	defer func() { _ = client.Stop() }()
+
+	updates := client.Subscribe()
+	require.NoError(t, client.Start())
+
+	require.Eventually(t, func() bool {
+		return len(client.Items(nil, 0)) == 5 && client.Err([]string{"broken"}) != nil
+	}, eventTimeout, 10*time.Millisecond)
+
+	select {
+	case <-updates:
+	default:
+		t.Fatal("expected an update notification")
+	}
+
+	all := client.Items(nil, 0)
+	assert.Equal(t, "v2.0.0", all[0].Title)
+	assert.Equal(t, "Project Releases", all[0].Feed)
+	assert.Equal(t, "Kernel 6.18 released", all[1].Title)
+
+	assert.Len(t, client.Items([]string{"news"}, 0), 3)
+	assert.Len(t, client.Items([]string{server.URL + "/atom"}, 0), 2)
+	assert.Len(t, client.Items(nil, 2), 2)
+
+	assert.NoError(t, client.Err([]string{"news"}))
+	assert.ErrorContains(t, client.Err(nil), "404")
+
+	client.Unsubscribe(updates)
+	_, ok := <-updates
+	assert.False(t, ok)
+}
@@ -0,0 +1,96 @@
+// Package render draws widgets into images without a window, for previews
+// and golden image tests.
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gioui.org/gpu/headless"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/unit"
+)
+
+// ErrUnavailable is returned when the system has no GPU or driver for
+// headless rendering.
+var ErrUnavailable = errors.New("headless rendering unavailable")
+
+// Options sets the size of a render.
+type Options struct {
+	// Size is the image size in pixels.
+	Size image.Point
+	// Scale is the number of pixels per dp, 1 if zero.
+	Scale float32
+}
+
+// ParseSize reads a size like "800x480".
+func ParseSize(s string) (image.Point, error) {
+	width, height, ok := strings.Cut(s, "x")
+	if !ok {
+		return image.Point{}, fmt.Errorf("invalid size %q, want WIDTHxHEIGHT", s)
+	}
+	w, err := strconv.Atoi(width)
+	if err != nil || w <= 0 {
+		return image.Point{}, fmt.Errorf("invalid width in size %q", s)
+	}
+	h, err := strconv.Atoi(height)
+	if err != nil || h <= 0 {
+		return image.Point{}, fmt.Errorf("invalid height in size %q", s)
+	}
+	return image.Pt(w, h), nil
+}
+
+// Render lays out w once and returns what it drew.
+func Render(w layout.Widget, opts Options) (*image.RGBA, error) {
+	if opts.Size.X <= 0 || opts.Size.Y <= 0 {
+		return nil, fmt.Errorf("invalid render size %v", opts.Size)
+	}
+	scale := opts.Scale
+	if scale == 0 {
+		scale = 1
+	}
+
+	window, err := headless.NewWindow(opts.Size.X, opts.Size.Y)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer window.Release()
+
+	var ops op.Ops
+	gtx := layout.Context{
+		Ops:         &ops,
+		Metric:      unit.Metric{PxPerDp: scale, PxPerSp: scale},
+		Constraints: layout.Exact(opts.Size),
+		Now:         time.Now(),
+	}
+	w(gtx)
+
+	if err := window.Frame(&ops); err != nil {
+		return nil, fmt.Errorf("failed to render frame: %w", err)
+	}
+	img := image.NewRGBA(image.Rectangle{Max: opts.Size})
+	if err := window.Screenshot(img); err != nil {
+		return nil, fmt.Errorf("failed to read frame: %w", err)
+	}
+	return img, nil
+}
+
+// WritePNG saves img as a PNG file.
+func WritePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	return f.Close()
+}
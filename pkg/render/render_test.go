@@ -0,0 +1,65 @@
+package render
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSize(t *testing.T) {
+	size, err := ParseSize("800x480")
+	require.NoError(t, err)
+	assert.Equal(t, image.Pt(800, 480), size)
+
+	for _, invalid := range []string{"", "800", "800x", "x480", "0x480", "800x-1", "axb"} {
+		_, err := ParseSize(invalid)
+		assert.Error(t, err, "size %q", invalid)
+	}
+}
+
+func TestRender(t *testing.T) {
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	img, err := Render(func(gtx layout.Context) layout.Dimensions {
+		// A 10dp square, 20px at scale 2
+		size := image.Pt(gtx.Dp(unit.Dp(10)), gtx.Dp(unit.Dp(10)))
+		defer clip.Rect{Max: size}.Push(gtx.Ops).Pop()
+		paint.Fill(gtx.Ops, red)
+		return layout.Dimensions{Size: size}
+	}, Options{Size: image.Pt(40, 30), Scale: 2})
+	if errors.Is(err, ErrUnavailable) {
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+
+	assert.Equal(t, image.Pt(40, 30), img.Bounds().Size())
+	assert.Equal(t, color.RGBA{R: 0xff, A: 0xff}, img.RGBAAt(19, 19))
+	assert.NotEqual(t, color.RGBA{R: 0xff, A: 0xff}, img.RGBAAt(20, 20))
+
+	path := filepath.Join(t.TempDir(), "render.png")
+	require.NoError(t, WritePNG(path, img))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	decoded, err := png.Decode(f)
+	require.NoError(t, err)
+	assert.Equal(t, img.Bounds(), decoded.Bounds())
+}
+
+func TestRenderInvalidSize(t *testing.T) {
+	_, err := Render(func(gtx layout.Context) layout.Dimensions {
+		return layout.Dimensions{}
+	}, Options{})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrUnavailable)
+}
@@ -0,0 +1,104 @@
+// Package rendertest compares renders against golden images in tests.
+package rendertest
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gioui.org/layout"
+	"github.com/mntndev/dash/pkg/render"
+)
+
+// UpdateEnv is the environment variable that makes CompareGolden write the
+// golden images instead of comparing against them.
+const UpdateEnv = "DASH_UPDATE_GOLDEN"
+
+// channelTolerance is how far a color channel may be off before a pixel
+// counts as different. GPUs do not antialias exactly alike.
+const channelTolerance = 8
+
+// maxDiffRatio is the share of pixels that may differ.
+const maxDiffRatio = 0.005
+
+// Render renders w and skips the test if the system cannot render headless.
+func Render(t testing.TB, w layout.Widget, opts render.Options) *image.RGBA {
+	t.Helper()
+	img, err := render.Render(w, opts)
+	if errors.Is(err, render.ErrUnavailable) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+// CompareGolden fails the test if img differs from the PNG at path. Run the
+// tests with DASH_UPDATE_GOLDEN=1 to write the golden images.
+func CompareGolden(t testing.TB, path string, img image.Image) {
+	t.Helper()
+
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := render.WritePNG(path, img); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("no golden image, run with %s=1 to create it: %v", UpdateEnv, err)
+	}
+	defer f.Close()
+	golden, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("failed to decode golden image %s: %v", path, err)
+	}
+
+	if ratio, ok := Diff(golden, img); !ok || ratio > maxDiffRatio {
+		actual := path[:len(path)-len(filepath.Ext(path))] + ".actual.png"
+		if err := render.WritePNG(actual, img); err != nil {
+			t.Logf("failed to save actual image: %v", err)
+		}
+		t.Errorf("render differs from %s (%.2f%% of pixels), wrote %s", path, ratio*100, actual)
+	}
+}
+
+// Diff returns the share of pixels that differ between a and b. It returns
+// false if the images are not the same size.
+func Diff(a, b image.Image) (float64, bool) {
+	bounds := a.Bounds()
+	if bounds.Size() != b.Bounds().Size() {
+		return 1, false
+	}
+
+	offset := b.Bounds().Min.Sub(bounds.Min)
+	differ := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if !similar(a.At(x, y), b.At(x+offset.X, y+offset.Y)) {
+				differ++
+			}
+		}
+	}
+	return float64(differ) / float64(bounds.Dx()*bounds.Dy()), true
+}
+
+func similar(a, b color.Color) bool {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	for _, d := range []int{int(r1>>8) - int(r2>>8), int(g1>>8) - int(g2>>8), int(b1>>8) - int(b2>>8), int(a1>>8) - int(a2>>8)} {
+		if d > channelTolerance || d < -channelTolerance {
+			return false
+		}
+	}
+	return true
+}
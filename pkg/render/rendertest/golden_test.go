@@ -0,0 +1,58 @@
+package rendertest
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func filled(size image.Point, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	for y := range size.Y {
+		for x := range size.X {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestDiff(t *testing.T) {
+	a := filled(image.Pt(10, 10), color.RGBA{R: 100, A: 255})
+
+	ratio, ok := Diff(a, filled(image.Pt(10, 10), color.RGBA{R: 104, A: 255}))
+	assert.True(t, ok)
+	assert.Zero(t, ratio, "small differences are tolerated")
+
+	b := filled(image.Pt(10, 10), color.RGBA{R: 100, A: 255})
+	b.SetRGBA(0, 0, color.RGBA{G: 255, A: 255})
+	ratio, ok = Diff(a, b)
+	assert.True(t, ok)
+	assert.InDelta(t, 0.01, ratio, 1e-9)
+
+	_, ok = Diff(a, filled(image.Pt(5, 10), color.RGBA{}))
+	assert.False(t, ok)
+}
+
+func TestCompareGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden", "widget.png")
+	img := filled(image.Pt(20, 10), color.RGBA{B: 200, A: 255})
+
+	t.Setenv(UpdateEnv, "1")
+	CompareGolden(t, path, img)
+	assert.FileExists(t, path)
+
+	t.Setenv(UpdateEnv, "")
+	CompareGolden(t, path, img)
+	_, err := os.Stat(filepath.Join(filepath.Dir(path), "widget.actual.png"))
+	assert.True(t, os.IsNotExist(err))
+
+	// A failing comparison leaves the actual image next to the golden one
+	mock := &testing.T{}
+	CompareGolden(mock, path, filled(image.Pt(20, 10), color.RGBA{R: 200, A: 255}))
+	assert.True(t, mock.Failed())
+	assert.FileExists(t, filepath.Join(filepath.Dir(path), "widget.actual.png"))
+}
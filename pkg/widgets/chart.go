@@ -0,0 +1,259 @@
+package widgets
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"time"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"github.com/mntndev/dash/pkg/config"
+)
+
+// seriesColors are used in turn for the series of multi-series charts.
+var seriesColors = []color.NRGBA{
+	{R: 0x3f, G: 0x51, B: 0xb5, A: 0xff},
+	{R: 0x43, G: 0xa0, B: 0x47, A: 0xff},
+	{R: 0xfb, G: 0x8c, B: 0x00, A: 0xff},
+	{R: 0x8e, G: 0x24, B: 0xaa, A: 0xff},
+	{R: 0x00, G: 0xac, B: 0xc1, A: 0xff},
+	{R: 0xd8, G: 0x1b, B: 0x60, A: 0xff},
+}
+
+var defaultThresholdColor = color.NRGBA{R: 0xe5, G: 0x39, B: 0x35, A: 0xff}
+
+// ThresholdConfig is a reference value in widget YAML, drawn as a line on
+// charts and used to color gauges.
+type ThresholdConfig struct {
+	Value float64 `yaml:"value"`
+	Color string  `yaml:"color"`
+}
+
+// ChartPoint is a single sample of a time series.
+type ChartPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// ChartThreshold is a horizontal reference line drawn across a chart.
+type ChartThreshold struct {
+	Value float64
+	Color color.NRGBA
+}
+
+// ChartBand is a horizontal range shaded behind the series, like a target
+// range.
+type ChartBand struct {
+	Low, High float64
+	Color     color.NRGBA
+}
+
+// ChartSeries is a time series drawn in one color. Points must be sorted by
+// time.
+type ChartSeries struct {
+	Points []ChartPoint
+	Color  color.NRGBA
+	// PointColor, if set, colors each point of a dot chart by its value.
+	PointColor func(value float64) color.NRGBA
+}
+
+// Chart draws time series as lines or filled areas. It fills the maximum
+// constraints it is given.
+type Chart struct {
+	Series []ChartSeries
+	// Start and End span the x axis. Points outside are clipped.
+	Start, End time.Time
+	// Min and Max fix the y axis. Unset bounds follow the data.
+	Min, Max   *float64
+	Area       bool
+	Thresholds []ChartThreshold
+	Bands      []ChartBand
+	LineWidth  unit.Dp
+	// PointSize draws every point as a dot of that diameter instead of
+	// connecting them with a line.
+	PointSize unit.Dp
+}
+
+// parseThresholds converts threshold YAML into chart thresholds. Thresholds
+// without a color are drawn red.
+func parseThresholds(thresholds []ThresholdConfig) ([]ChartThreshold, error) {
+	parsed := make([]ChartThreshold, 0, len(thresholds))
+	for _, threshold := range thresholds {
+		c := defaultThresholdColor
+		if threshold.Color != "" {
+			var err error
+			c, err = config.ParseColor(threshold.Color)
+			if err != nil {
+				return nil, fmt.Errorf("invalid threshold color: %w", err)
+			}
+		}
+		parsed = append(parsed, ChartThreshold{Value: threshold.Value, Color: c})
+	}
+	return parsed, nil
+}
+
+func (c Chart) Layout(gtx layout.Context) layout.Dimensions {
+	size := gtx.Constraints.Max
+	if size.X <= 0 || size.Y <= 0 || !c.End.After(c.Start) {
+		return layout.Dimensions{Size: gtx.Constraints.Min}
+	}
+	defer clip.Rect{Max: size}.Push(gtx.Ops).Pop()
+
+	lo, hi := c.bounds()
+	width, height := float32(size.X), float32(size.Y)
+	span := c.End.Sub(c.Start)
+	x := func(t time.Time) float32 {
+		return float32(t.Sub(c.Start)) / float32(span) * width
+	}
+	y := func(v float64) float32 {
+		return height - float32((v-lo)/(hi-lo))*height
+	}
+
+	lineWidth := c.LineWidth
+	if lineWidth == 0 {
+		lineWidth = 2
+	}
+	stroke := float32(gtx.Dp(lineWidth))
+
+	for _, band := range c.Bands {
+		top, bottom := y(min(band.High, hi)), y(max(band.Low, lo))
+		if bottom <= top {
+			continue
+		}
+		rect := clip.Rect{Min: image.Pt(0, int(top)), Max: image.Pt(size.X, int(bottom))}
+		paint.FillShape(gtx.Ops, band.Color, rect.Op())
+	}
+
+	for _, threshold := range c.Thresholds {
+		if threshold.Value < lo || threshold.Value > hi {
+			continue
+		}
+		ty := y(threshold.Value)
+		var path clip.Path
+		path.Begin(gtx.Ops)
+		path.MoveTo(f32.Pt(0, ty))
+		path.LineTo(f32.Pt(width, ty))
+		paint.FillShape(gtx.Ops, threshold.Color, clip.Stroke{Path: path.End(), Width: stroke / 2}.Op())
+	}
+
+	for _, series := range c.Series {
+		points := series.Points
+		if c.PointSize > 0 {
+			radius := float32(gtx.Dp(c.PointSize)) / 2
+			for _, p := range points {
+				center := f32.Pt(x(p.Time), y(p.Value))
+				dot := clip.Ellipse{
+					Min: image.Pt(int(center.X-radius), int(center.Y-radius)),
+					Max: image.Pt(int(center.X+radius+0.5), int(center.Y+radius+0.5)),
+				}
+				fill := series.Color
+				if series.PointColor != nil {
+					fill = series.PointColor(p.Value)
+				}
+				paint.FillShape(gtx.Ops, fill, dot.Op(gtx.Ops))
+			}
+			continue
+		}
+		if len(points) < 2 {
+			continue
+		}
+
+		if c.Area {
+			var path clip.Path
+			path.Begin(gtx.Ops)
+			path.MoveTo(f32.Pt(x(points[0].Time), height))
+			for _, p := range points {
+				path.LineTo(f32.Pt(x(p.Time), y(p.Value)))
+			}
+			path.LineTo(f32.Pt(x(points[len(points)-1].Time), height))
+			path.Close()
+
+			fill := series.Color
+			fill.A /= 3
+			paint.FillShape(gtx.Ops, fill, clip.Outline{Path: path.End()}.Op())
+		}
+
+		var path clip.Path
+		path.Begin(gtx.Ops)
+		path.MoveTo(f32.Pt(x(points[0].Time), y(points[0].Value)))
+		for _, p := range points[1:] {
+			path.LineTo(f32.Pt(x(p.Time), y(p.Value)))
+		}
+		paint.FillShape(gtx.Ops, series.Color, clip.Stroke{Path: path.End(), Width: stroke}.Op())
+	}
+
+	return layout.Dimensions{Size: size}
+}
+
+// layoutTimeAxis labels the x axis of a chart spanning start to end with
+// the time of day every step.
+func layoutTimeAxis(gtx layout.Context, th *material.Theme, start, end time.Time, step time.Duration) layout.Dimensions {
+	width := gtx.Constraints.Max.X
+	span := end.Sub(start)
+	if width <= 0 || span <= 0 || step <= 0 {
+		return layout.Dimensions{}
+	}
+
+	gtx.Constraints.Min = image.Point{}
+	height := 0
+	for t := start.Truncate(step); !t.After(end); t = t.Add(step) {
+		if t.Before(start) {
+			continue
+		}
+		macro := op.Record(gtx.Ops)
+		dims := material.Caption(th, t.Local().Format("15:04")).Layout(gtx)
+		call := macro.Stop()
+
+		// Center the label on its tick without running off the edges
+		x := int(float32(t.Sub(start))/float32(span)*float32(width)) - dims.Size.X/2
+		x = max(0, min(width-dims.Size.X, x))
+		stack := op.Offset(image.Pt(x, 0)).Push(gtx.Ops)
+		call.Add(gtx.Ops)
+		stack.Pop()
+		height = max(height, dims.Size.Y)
+	}
+	return layout.Dimensions{Size: image.Pt(width, height)}
+}
+
+// timeAxisStep picks a label spacing that gives a few labels for window.
+func timeAxisStep(window time.Duration) time.Duration {
+	for _, step := range []time.Duration{5 * time.Minute, 15 * time.Minute, 30 * time.Minute, time.Hour, 3 * time.Hour, 6 * time.Hour} {
+		if window/step <= 4 {
+			return step
+		}
+	}
+	return 12 * time.Hour
+}
+
+// bounds returns the y axis range, padding it when all values are equal.
+func (c Chart) bounds() (float64, float64) {
+	var lo, hi float64
+	first := true
+	for _, series := range c.Series {
+		for _, p := range series.Points {
+			if first || p.Value < lo {
+				lo = p.Value
+			}
+			if first || p.Value > hi {
+				hi = p.Value
+			}
+			first = false
+		}
+	}
+	if c.Min != nil {
+		lo = *c.Min
+	}
+	if c.Max != nil {
+		hi = *c.Max
+	}
+	if hi <= lo {
+		lo, hi = lo-1, lo+1
+	}
+	return lo, hi
+}
@@ -2,8 +2,10 @@ package widgets
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"gioui.org/app"
@@ -12,6 +14,7 @@ import (
 	"gioui.org/widget/material"
 	"github.com/goccy/go-yaml"
 	"github.com/goccy/go-yaml/ast"
+	"github.com/mntndev/dash/pkg/events"
 )
 
 type ClockConfig struct {
@@ -23,8 +26,10 @@ type ClockWidget struct {
 	Format     string
 	lastSecond int
 	provider   Provider
-	data       *ClockData
+	data       atomic.Pointer[ClockData]
 	theme      *material.Theme
+	// fixed stops the clock at the time of a fixture.
+	fixed atomic.Bool
 }
 
 type ClockData struct {
@@ -68,12 +73,12 @@ func (w *ClockWidget) Init(ctx context.Context) error {
 	now := time.Now()
 	w.lastSecond = now.Second()
 
-	w.data = &ClockData{
+	w.data.Store(&ClockData{
 		Time:    now,
 		Format:  w.Format,
 		Display: now.Format(w.Format),
-	}
-	w.LastUpdate = now
+	})
+	w.SetLastUpdate(now)
 
 	// Start a goroutine to update clock every second
 	go w.startClockUpdater(ctx)
@@ -89,28 +94,53 @@ func (w *ClockWidget) startClockUpdater(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case now := <-ticker.C:
+			if w.fixed.Load() {
+				continue
+			}
 			w.lastSecond = now.Second()
-			w.data = &ClockData{
+			data := &ClockData{
 				Time:    now,
 				Format:  w.Format,
 				Display: now.Format(w.Format),
 			}
-			w.LastUpdate = now
+			w.data.Store(data)
+			w.SetLastUpdate(now)
 
 			// Trigger window redraw
 			w.Invalidate()
+			publish(w.provider, events.WidgetData{ID: w.ID, Type: w.Type, Data: data})
 		}
 	}
 }
 
+// GetData returns the time the clock shows.
+func (w *ClockWidget) GetData() interface{} {
+	return w.data.Load()
+}
+
+// LoadFixture stops the clock at the time in data.
+func (w *ClockWidget) LoadFixture(raw json.RawMessage) error {
+	var data ClockData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("invalid clock fixture: %w", err)
+	}
+	data.Format = w.Format
+	data.Display = data.Time.Format(w.Format)
+
+	w.fixed.Store(true)
+	w.data.Store(&data)
+	w.Invalidate()
+	return nil
+}
+
 func (w *ClockWidget) Close() error {
 	return nil
 }
 
 func (w *ClockWidget) Layout(gtx layout.Context) layout.Dimensions {
 	clock_text := "Clock"
-	if w.data != nil {
-		clock_text = w.data.Display
+	if data := w.data.Load(); data != nil {
+		clock_text = data.Display
 	}
 
 	label := material.H3(w.theme, clock_text)
@@ -2,46 +2,111 @@ package widgets
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"strconv"
-	"strings"
+	"image/color"
+	"log"
+	"sync/atomic"
 	"time"
 
 	"gioui.org/app"
 	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/unit"
 	"gioui.org/widget/material"
 	"github.com/goccy/go-yaml"
 	"github.com/goccy/go-yaml/ast"
+	"github.com/mntndev/dash/pkg/events"
 	"github.com/mntndev/dash/pkg/integrations"
 )
 
+// Colors of a glucose value below, in and above the target range.
+var (
+	dexcomLowColor     = color.NRGBA{R: 0xe5, G: 0x39, B: 0x35, A: 0xff}
+	dexcomInRangeColor = color.NRGBA{R: 0x43, G: 0xa0, B: 0x47, A: 0xff}
+	dexcomHighColor    = color.NRGBA{R: 0xfb, G: 0x8c, B: 0x00, A: 0xff}
+)
+
+const (
+	defaultDexcomPointSize = 4
+	// Default target range in mg/dL.
+	defaultDexcomLow  = 70
+	defaultDexcomHigh = 160
+	// The y axis shows at least this range in mg/dL so the chart does not
+	// jump around with every reading.
+	dexcomChartMin = 40
+	dexcomChartMax = 300
+)
+
 type DexcomConfig struct {
-	LowThreshold  int `yaml:"low_threshold"`
-	HighThreshold int `yaml:"high_threshold"`
+	// Source is the integration the readings come from, "dexcom" (default)
+	// or "nightscout".
+	Source string `yaml:"source"`
+	// Unit is "mg/dL" (default) or "mmol/L". Thresholds and alert values are
+	// given in it.
+	Unit          string             `yaml:"unit"`
+	LowThreshold  float64            `yaml:"low_threshold"`
+	HighThreshold float64            `yaml:"high_threshold"`
+	Chart         *DexcomChartConfig `yaml:"chart"`
+	// StaleAfter is how old the latest reading may get before the value is
+	// shown as stale. Defaults to 15m.
+	StaleAfter string `yaml:"stale_after"`
+	// StaleStyle is "grey" (default) or "flash".
+	StaleStyle string             `yaml:"stale_style"`
+	Alerts     *DexcomAlertConfig `yaml:"alerts"`
+}
+
+// DexcomChartConfig turns on the history chart of a Dexcom widget.
+type DexcomChartConfig struct {
+	// Type is "points" (default) or "line".
+	Type string `yaml:"type"`
+	// Window is how far back the chart reaches, at most 3h. Defaults to 3h.
+	Window string `yaml:"window"`
+	// PointSize is the diameter of the points, or the width of the line, in
+	// dp.
+	PointSize float32 `yaml:"point_size"`
 }
 
 type DexcomWidget struct {
 	*BaseWidget
-	dexcomProvider integrations.DexcomProvider
-	provider       Provider
-	lowThreshold   int
-	highThreshold  int
-	data           *DexcomData
-	theme          *material.Theme
+	provider      Provider
+	source        string
+	unit          string
+	lowThreshold  float64
+	highThreshold float64
+	data          atomic.Pointer[DexcomData]
+	theme         *material.Theme
+
+	// chart is nil unless the chart is configured
+	chart       *Chart
+	chartWindow time.Duration
+
+	staleAfter time.Duration
+	flashStale bool
+	// alerts is nil unless alerts are configured
+	alerts *dexcomAlerts
 }
 
+// DexcomData holds the latest reading and the history. Values, thresholds
+// and the rate are in Unit.
 type DexcomData struct {
-	Value         int             `json:"value"`
-	Trend         string          `json:"trend"`
+	Value     float64 `json:"value"`
+	Trend     string  `json:"trend"`
+	TrendName string  `json:"trend_name"`
+	// Rate is the change per minute, measured from the last two readings or
+	// estimated from the trend. It is nil if neither tells.
+	Rate          *float64        `json:"rate,omitempty"`
 	Timestamp     time.Time       `json:"timestamp"`
 	Unit          string          `json:"unit"`
 	Historical    []DexcomReading `json:"historical,omitempty"`
-	LowThreshold  int             `json:"low_threshold"`
-	HighThreshold int             `json:"high_threshold"`
+	LowThreshold  float64         `json:"low_threshold"`
+	HighThreshold float64         `json:"high_threshold"`
+	// Device is set if the source reports the uploader and pump.
+	Device *integrations.GlucoseDevice `json:"device,omitempty"`
 }
 
 type DexcomReading struct {
-	Value     int       `json:"value"`
+	Value     float64   `json:"value"`
 	Trend     string    `json:"trend"`
 	Timestamp time.Time `json:"timestamp"`
 }
@@ -55,14 +120,30 @@ func CreateDexcomWidget(id string, config ast.Node, children []Widget, provider
 		}
 	}
 
+	if dexcomConfig.Source == "" {
+		dexcomConfig.Source = integrations.DexcomName
+	}
+	if !integrations.IsGlucoseSource(dexcomConfig.Source) {
+		return nil, fmt.Errorf("unsupported source %q, use %s or %s", dexcomConfig.Source, integrations.DexcomName, integrations.NightscoutName)
+	}
+
+	glucoseUnit, err := parseGlucoseUnit(dexcomConfig.Unit)
+	if err != nil {
+		return nil, err
+	}
+
 	// Set defaults
 	lowThreshold := dexcomConfig.LowThreshold
 	if lowThreshold == 0 {
-		lowThreshold = 70
+		lowThreshold = convertGlucose(defaultDexcomLow, glucoseUnit)
 	}
 	highThreshold := dexcomConfig.HighThreshold
 	if highThreshold == 0 {
-		highThreshold = 160
+		highThreshold = convertGlucose(defaultDexcomHigh, glucoseUnit)
+	}
+
+	if lowThreshold >= highThreshold {
+		return nil, fmt.Errorf("low_threshold must be less than high_threshold")
 	}
 
 	widget := &DexcomWidget{
@@ -73,208 +154,328 @@ func CreateDexcomWidget(id string, config ast.Node, children []Widget, provider
 			Children: children,
 			window:   window,
 		},
-		dexcomProvider: provider,
-		provider:       provider,
-		lowThreshold:   lowThreshold,
-		highThreshold:  highThreshold,
-		theme:          theme,
+		provider:      provider,
+		source:        dexcomConfig.Source,
+		unit:          glucoseUnit,
+		lowThreshold:  lowThreshold,
+		highThreshold: highThreshold,
+		theme:         theme,
+		staleAfter:    defaultDexcomStaleAfter,
 	}
 
-	return widget, nil
-}
+	if dexcomConfig.StaleAfter != "" {
+		staleAfter, err := time.ParseDuration(dexcomConfig.StaleAfter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stale_after: %w", err)
+		}
+		if staleAfter <= 0 {
+			return nil, fmt.Errorf("stale_after must be positive")
+		}
+		widget.staleAfter = staleAfter
+	}
 
-func (w *DexcomWidget) getLowThreshold() int {
-	return w.lowThreshold
-}
+	switch dexcomConfig.StaleStyle {
+	case "", "grey":
+	case "flash":
+		widget.flashStale = true
+	default:
+		return nil, fmt.Errorf("unsupported stale_style %q", dexcomConfig.StaleStyle)
+	}
+
+	if dexcomConfig.Alerts != nil {
+		alerts, err := newDexcomAlerts(dexcomConfig.Alerts, lowThreshold, highThreshold, glucoseUnit)
+		if err != nil {
+			return nil, err
+		}
+		widget.alerts = alerts
+	}
+
+	if dexcomConfig.Chart != nil {
+		if err := widget.configureChart(dexcomConfig.Chart); err != nil {
+			return nil, err
+		}
+	}
 
-func (w *DexcomWidget) getHighThreshold() int {
-	return w.highThreshold
+	return widget, nil
 }
 
-func (w *DexcomWidget) Init(ctx context.Context) error {
-	w.LastUpdate = time.Now()
+func (w *DexcomWidget) configureChart(cfg *DexcomChartConfig) error {
+	w.chart = &Chart{
+		Bands: []ChartBand{{
+			Low:   w.lowThreshold,
+			High:  w.highThreshold,
+			Color: color.NRGBA{R: dexcomInRangeColor.R, G: dexcomInRangeColor.G, B: dexcomInRangeColor.B, A: 0x30},
+		}},
+	}
+	w.chartWindow = integrations.DexcomHistory
 
-	// Always start the connection check asynchronously to avoid any blocking
-	// during widget initialization
-	go w.waitForConnectionAndUpdate(ctx)
+	if cfg.PointSize < 0 {
+		return fmt.Errorf("chart point_size must not be negative")
+	}
+	switch cfg.Type {
+	case "", "points":
+		w.chart.PointSize = unit.Dp(defaultDexcomPointSize)
+		if cfg.PointSize > 0 {
+			w.chart.PointSize = unit.Dp(cfg.PointSize)
+		}
+	case "line":
+		w.chart.LineWidth = unit.Dp(cfg.PointSize)
+	default:
+		return fmt.Errorf("unsupported chart type %q", cfg.Type)
+	}
 
+	if cfg.Window != "" {
+		window, err := time.ParseDuration(cfg.Window)
+		if err != nil {
+			return fmt.Errorf("invalid chart window: %w", err)
+		}
+		if window <= 0 || window > integrations.DexcomHistory {
+			return fmt.Errorf("chart window must be between 0 and %s", integrations.DexcomHistory)
+		}
+		w.chartWindow = window
+	}
 	return nil
 }
 
-func (w *DexcomWidget) waitForConnectionAndUpdate(ctx context.Context) {
-	ticker := time.NewTicker(30 * time.Second)
-	defer ticker.Stop()
+// rangeColor returns the color of a glucose value by where it is relative to
+// the target range.
+func (w *DexcomWidget) rangeColor(value float64) color.NRGBA {
+	switch {
+	case value < w.lowThreshold:
+		return dexcomLowColor
+	case value > w.highThreshold:
+		return dexcomHighColor
+	default:
+		return dexcomInRangeColor
+	}
+}
+
+func (w *DexcomWidget) Init(ctx context.Context) error {
+	w.SetLastUpdate(time.Now())
 
-	// Try to fetch data immediately
-	if err := w.updateData(); err != nil {
-		fmt.Printf("Failed to update Dexcom data: %v\n", err)
-	}
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			// Use a timeout for potentially blocking provider calls
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						fmt.Printf("Recovered from panic in Dexcom provider call: %v\n", r)
-					}
-				}()
+	source := w.glucoseSource()
+	if source == nil {
+		log.Printf("Glucose source %q for widget %s is not configured", w.source, w.ID)
+		return nil
+	}
 
+	// The source polls for every widget, show what it already has
+	updates := source.Subscribe()
+	if err := w.updateData(); err != nil {
+		log.Printf("Dexcom widget %s has no readings yet: %v", w.ID, err)
+	}
+	go func() {
+		defer source.Unsubscribe(updates)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-updates:
+				if !ok {
+					return
+				}
 				if err := w.updateData(); err != nil {
-					fmt.Printf("Failed to update Dexcom data: %v\n", err)
+					log.Printf("Failed to update Dexcom widget %s: %v", w.ID, err)
 				}
-			}()
+			}
 		}
-	}
+	}()
+	return nil
 }
 
-func (w *DexcomWidget) updateData() error {
-	dexcomClient := w.dexcomProvider.GetDexcomClient()
-	if dexcomClient == nil {
-		return fmt.Errorf("dexcom client not available")
-	}
+func (w *DexcomWidget) glucoseSource() integrations.GlucoseSource {
+	return getIntegration[integrations.GlucoseSource](w.provider, w.source)
+}
 
-	// Fetch fresh data from the API
-	if err := dexcomClient.FetchGlucoseData(); err != nil {
-		return fmt.Errorf("failed to fetch glucose data: %w", err)
+// updateData shows the readings the glucose source fetched last.
+func (w *DexcomWidget) updateData() error {
+	source := w.glucoseSource()
+	if source == nil {
+		return fmt.Errorf("glucose source %q not available", w.source)
 	}
 
-	latest, lastUpdate, err := dexcomClient.GetLatestGlucose()
+	glucose, err := source.Glucose()
 	if err != nil {
 		return fmt.Errorf("failed to get glucose data: %w", err)
 	}
-
-	trendString := formatTrendString(latest.Trend)
-
-	// Try WT first, then DT as fallback
-	timestamp := parseDexcomTimestamp(latest.WT)
-	if timestamp.IsZero() {
-		timestamp = parseDexcomTimestamp(latest.DT)
+	if len(glucose.Readings) == 0 {
+		// The last reading is kept and turns stale
+		return fmt.Errorf("failed to get glucose data: %w", integrations.ErrNoGlucoseData)
 	}
+
+	latest := glucose.Readings[0]
+	trend := parseGlucoseTrend(latest.Trend)
+	timestamp := latest.Time
 	if timestamp.IsZero() {
 		timestamp = time.Now()
 	}
 
-	// Get historical data
-	historical, err := dexcomClient.GetHistoricalGlucose()
-	var historicalReadings []DexcomReading
-	if err != nil {
-		historicalReadings = make([]DexcomReading, 0)
-	} else {
-		// Convert historical data
-		historicalReadings = make([]DexcomReading, 0, len(historical))
-		for _, entry := range historical {
-			// Try WT first, then DT as fallback
-			histTimestamp := parseDexcomTimestamp(entry.WT)
-			if histTimestamp.IsZero() {
-				histTimestamp = parseDexcomTimestamp(entry.DT)
-			}
-			if histTimestamp.IsZero() {
-				continue
-			}
-
-			histTrend := formatTrendString(entry.Trend)
-			historicalReadings = append(historicalReadings, DexcomReading{
-				Value:     entry.Value,
-				Trend:     histTrend,
-				Timestamp: histTimestamp,
-			})
+	historicalReadings := make([]DexcomReading, 0, len(glucose.Readings))
+	for _, reading := range glucose.Readings {
+		if reading.Time.IsZero() {
+			continue
 		}
+		historicalReadings = append(historicalReadings, DexcomReading{
+			Value:     convertGlucose(float64(reading.Value), w.unit),
+			Trend:     parseGlucoseTrend(reading.Trend).Arrow(),
+			Timestamp: reading.Time,
+		})
 	}
 
 	data := &DexcomData{
-		Value:         latest.Value,
-		Trend:         trendString,
+		Value:         convertGlucose(float64(latest.Value), w.unit),
+		Trend:         trend.Arrow(),
+		TrendName:     trend.String(),
 		Timestamp:     timestamp,
-		Unit:          "mg/dL",
+		Unit:          w.unit,
 		Historical:    historicalReadings,
-		LowThreshold:  w.getLowThreshold(),
-		HighThreshold: w.getHighThreshold(),
+		LowThreshold:  w.lowThreshold,
+		HighThreshold: w.highThreshold,
+		Device:        glucose.Device,
+	}
+	if rate, ok := glucoseRate(historicalReadings); ok {
+		data.Rate = &rate
+	} else if rate, ok := trend.Rate(); ok {
+		rate = convertGlucose(rate, w.unit)
+		data.Rate = &rate
 	}
 
 	w.setDataAndInvalidate(data)
-	w.LastUpdate = lastUpdate
+	w.SetLastUpdate(glucose.Updated)
+	w.updateAlert(data)
 	return nil
 }
 
 func (w *DexcomWidget) setDataAndInvalidate(data *DexcomData) {
-	w.data = data
-	w.LastUpdate = time.Now()
+	w.data.Store(data)
+	w.SetLastUpdate(time.Now())
 	w.Invalidate()
+	publish(w.provider, events.WidgetData{ID: w.ID, Type: w.Type, Data: data})
 }
 
-func parseDexcomTimestamp(dateStr string) time.Time {
-	if dateStr == "" {
-		return time.Time{}
+// GetData returns the latest reading and the history.
+func (w *DexcomWidget) GetData() interface{} {
+	return w.data.Load()
+}
+
+// LoadFixture shows the readings in data until the glucose source fetches
+// new ones. A reading without a timestamp is taken as current.
+func (w *DexcomWidget) LoadFixture(raw json.RawMessage) error {
+	data := DexcomData{Unit: w.unit, LowThreshold: w.lowThreshold, HighThreshold: w.highThreshold}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("invalid glucose fixture: %w", err)
+	}
+	if data.Timestamp.IsZero() {
+		data.Timestamp = time.Now()
 	}
+	w.setDataAndInvalidate(&data)
+	return nil
+}
 
-	var timestampStr string
+func (w *DexcomWidget) Close() error {
+	w.clearAlert()
+	return nil
+}
 
-	// Dexcom timestamps can come in different formats: "/Date(milliseconds)/" or "Date(milliseconds)"
-	switch {
-	case strings.HasPrefix(dateStr, "/Date(") && strings.HasSuffix(dateStr, ")/"):
-		// Format: /Date(milliseconds)/
-		timestampStr = strings.TrimPrefix(dateStr, "/Date(")
-		timestampStr = strings.TrimSuffix(timestampStr, ")/")
-	case strings.HasPrefix(dateStr, "Date(") && strings.HasSuffix(dateStr, ")"):
-		timestampStr = strings.TrimPrefix(dateStr, "Date(")
-		timestampStr = strings.TrimSuffix(timestampStr, ")")
-	default:
-		return time.Time{}
+func (w *DexcomWidget) Layout(gtx layout.Context) layout.Dimensions {
+	data := w.data.Load()
+	if w.chart == nil {
+		return w.layoutValue(gtx, data)
 	}
 
-	// Handle timezone offset if present (like "-0600")
-	if idx := strings.LastIndex(timestampStr, "+"); idx > 0 {
-		timestampStr = timestampStr[:idx]
-	} else if idx := strings.LastIndex(timestampStr, "-"); idx > 0 {
-		timestampStr = timestampStr[:idx]
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return w.layoutValue(gtx, data)
+		}),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return w.layoutChart(gtx, data)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			start, end := w.chartSpan(gtx)
+			return layoutTimeAxis(gtx, w.theme, start, end, timeAxisStep(w.chartWindow))
+		}),
+	)
+}
+
+// layoutValue shows the latest reading and its age. Once the reading is
+// stale the value is greyed out or flashes.
+func (w *DexcomWidget) layoutValue(gtx layout.Context, data *DexcomData) layout.Dimensions {
+	label := material.H4(w.theme, "Dexcom")
+	if data == nil {
+		return label.Layout(gtx)
 	}
 
-	// Parse milliseconds
-	milliseconds, err := strconv.ParseInt(timestampStr, 10, 64)
-	if err != nil {
-		return time.Time{}
+	now := gtx.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	age := now.Sub(data.Timestamp)
+	label.Text = fmt.Sprintf("%s %s %s", formatGlucose(data.Value, data.Unit), data.Unit, data.Trend)
+	label.Color = w.rangeColor(data.Value)
+	ageLabel := material.Caption(w.theme, formatAge(age))
+	if data.Rate != nil {
+		ageLabel.Text = formatGlucoseRate(*data.Rate, data.Unit) + ", " + ageLabel.Text
 	}
 
-	// Convert to time.Time (Dexcom uses milliseconds since Unix epoch)
-	return time.Unix(milliseconds/1000, (milliseconds%1000)*1000000).UTC()
-}
+	refresh := now.Add(ageRefresh)
+	if age > w.staleAfter {
+		// The rate of an old reading says nothing about now
+		ageLabel.Text = "Stale, " + formatAge(age)
+		ageLabel.Color = dexcomLowColor
+		label.Color = w.theme.Fg
+		label.Color.A = 0x60
+		if w.flashStale {
+			refresh = now.Truncate(time.Second).Add(time.Second)
+			if now.Second()%2 == 1 {
+				label.Color.A = 0
+			}
+		}
+	}
+	gtx.Execute(op.InvalidateCmd{At: refresh})
 
-func (w *DexcomWidget) Close() error {
-	return nil
+	children := []layout.FlexChild{
+		layout.Rigid(label.Layout),
+		layout.Rigid(ageLabel.Layout),
+	}
+	if device := formatGlucoseDevice(data.Device); device != "" {
+		children = append(children, layout.Rigid(material.Caption(w.theme, device).Layout))
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
 }
 
-func (w *DexcomWidget) Layout(gtx layout.Context) layout.Dimensions {
-	text := "Dexcom"
-	if w.data != nil {
-		text = fmt.Sprintf("%d %s %s", w.data.Value, w.data.Unit, w.data.Trend)
+// chartSpan returns the time range the chart shows, ending now.
+func (w *DexcomWidget) chartSpan(gtx layout.Context) (time.Time, time.Time) {
+	now := gtx.Now
+	if now.IsZero() {
+		now = time.Now()
 	}
-
-	th := w.theme
-	label := material.H4(th, text)
-	return label.Layout(gtx)
+	return now.Add(-w.chartWindow), now
 }
 
-func formatTrendString(trend string) string {
-	switch trend {
-	case "1":
-		return "↗↗"
-	case "2":
-		return "↗"
-	case "3":
-		return "↗"
-	case "4":
-		return "→"
-	case "5":
-		return "↘"
-	case "6":
-		return "↘"
-	case "7":
-		return "↘↘"
-	default:
-		return "?"
+func (w *DexcomWidget) layoutChart(gtx layout.Context, data *DexcomData) layout.Dimensions {
+	chart := *w.chart
+	chart.Start, chart.End = w.chartSpan(gtx)
+
+	// Readings arrive newest first
+	var points []ChartPoint
+	hi := max(convertGlucose(dexcomChartMax, w.unit), w.highThreshold)
+	if data != nil {
+		for i := len(data.Historical) - 1; i >= 0; i-- {
+			reading := data.Historical[i]
+			if reading.Timestamp.Before(chart.Start) {
+				continue
+			}
+			points = append(points, ChartPoint{Time: reading.Timestamp, Value: reading.Value})
+			hi = max(hi, reading.Value)
+		}
 	}
+	lo := min(convertGlucose(dexcomChartMin, w.unit), w.lowThreshold)
+	chart.Min, chart.Max = &lo, &hi
+
+	chart.Series = []ChartSeries{{
+		Points:     points,
+		Color:      w.theme.Palette.ContrastBg,
+		PointColor: w.rangeColor,
+	}}
+	return chart.Layout(gtx)
 }
@@ -0,0 +1,186 @@
+package widgets
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mntndev/dash/pkg/events"
+)
+
+const (
+	defaultDexcomStaleAfter = 15 * time.Minute
+	defaultDexcomUrgentLow  = 55
+	defaultDexcomFastRate   = 3.0
+	defaultDexcomSnooze     = 30 * time.Minute
+	// dexcomRateSpan is how far apart two readings may be to compute the
+	// rate of change from them.
+	dexcomRateSpan = 15 * time.Minute
+)
+
+// DexcomAlertConfig turns on full-screen alerts for a Dexcom widget. Values
+// are in the unit of the widget.
+type DexcomAlertConfig struct {
+	// UrgentLow defaults to 55 mg/dL.
+	UrgentLow float64 `yaml:"urgent_low"`
+	// Low and High default to the low_threshold and high_threshold of the
+	// widget.
+	Low  float64 `yaml:"low"`
+	High float64 `yaml:"high"`
+	// FastRate is the change per minute that counts as rising or falling
+	// fast. Defaults to 3 mg/dL.
+	FastRate float64 `yaml:"fast_rate"`
+	// Snooze is how long snoozing hides an alert. Defaults to 30m.
+	Snooze string `yaml:"snooze"`
+}
+
+// dexcomAlerts decides which alert a reading raises and remembers the
+// current one.
+type dexcomAlerts struct {
+	unit      string
+	urgentLow float64
+	low       float64
+	high      float64
+	fastRate  float64
+	snooze    time.Duration
+
+	mu      sync.Mutex
+	current events.Alert
+}
+
+func newDexcomAlerts(cfg *DexcomAlertConfig, low, high float64, unit string) (*dexcomAlerts, error) {
+	a := &dexcomAlerts{
+		unit:      unit,
+		urgentLow: convertGlucose(defaultDexcomUrgentLow, unit),
+		low:       low,
+		high:      high,
+		fastRate:  convertGlucose(defaultDexcomFastRate, unit),
+		snooze:    defaultDexcomSnooze,
+	}
+	if cfg.UrgentLow != 0 {
+		a.urgentLow = cfg.UrgentLow
+	}
+	if cfg.Low != 0 {
+		a.low = cfg.Low
+	}
+	if cfg.High != 0 {
+		a.high = cfg.High
+	}
+	if cfg.FastRate != 0 {
+		a.fastRate = cfg.FastRate
+	}
+
+	if a.urgentLow > a.low {
+		return nil, fmt.Errorf("alert urgent_low must not be above low")
+	}
+	if a.low >= a.high {
+		return nil, fmt.Errorf("alert low must be less than high")
+	}
+	if a.fastRate < 0 {
+		return nil, fmt.Errorf("alert fast_rate must be positive")
+	}
+
+	if cfg.Snooze != "" {
+		snooze, err := time.ParseDuration(cfg.Snooze)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alert snooze: %w", err)
+		}
+		if snooze <= 0 {
+			return nil, fmt.Errorf("alert snooze must be positive")
+		}
+		a.snooze = snooze
+	}
+	return a, nil
+}
+
+// check returns the most severe condition of a reading, or an empty
+// condition if there is nothing to alert about.
+func (a *dexcomAlerts) check(data *DexcomData) (condition, message string, urgent bool) {
+	reading := formatGlucose(data.Value, a.unit) + " " + a.unit
+	risingFast := data.Rate != nil && *data.Rate >= a.fastRate
+	fallingFast := data.Rate != nil && *data.Rate <= -a.fastRate
+
+	switch {
+	case data.Value <= a.urgentLow:
+		return "urgent_low", "Urgent low: " + reading, true
+	case data.Value <= a.low:
+		return "low", "Low: " + reading, false
+	case data.Value >= a.high:
+		return "high", "High: " + reading, false
+	case fallingFast:
+		return "falling_fast", "Falling fast: " + reading, false
+	case risingFast:
+		return "rising_fast", "Rising fast: " + reading, false
+	default:
+		return "", "", false
+	}
+}
+
+// glucoseRate returns the change per minute between the two newest
+// readings, which come newest first. It returns false if they are too far
+// apart.
+func glucoseRate(readings []DexcomReading) (float64, bool) {
+	if len(readings) < 2 {
+		return 0, false
+	}
+	elapsed := readings[0].Timestamp.Sub(readings[1].Timestamp)
+	if elapsed <= 0 || elapsed > dexcomRateSpan {
+		return 0, false
+	}
+	return (readings[0].Value - readings[1].Value) / elapsed.Minutes(), true
+}
+
+// updateAlert publishes the alert for the latest reading, or that the
+// previous one ended.
+func (w *DexcomWidget) updateAlert(data *DexcomData) {
+	if w.alerts == nil {
+		return
+	}
+
+	alert := events.Alert{ID: w.ID}
+	if condition, message, urgent := w.alerts.check(data); condition != "" {
+		alert = events.Alert{
+			ID:        w.ID,
+			Condition: condition,
+			Urgent:    urgent,
+			Message:   message,
+			Snooze:    w.alerts.snooze,
+		}
+	}
+
+	w.alerts.mu.Lock()
+	previous := w.alerts.current
+	w.alerts.current = alert
+	w.alerts.mu.Unlock()
+
+	if !alert.Active() && !previous.Active() {
+		return
+	}
+	publish(w.provider, alert)
+}
+
+// Alert returns the alert of the latest reading.
+func (w *DexcomWidget) Alert() events.Alert {
+	if w.alerts == nil {
+		return events.Alert{ID: w.ID}
+	}
+	w.alerts.mu.Lock()
+	defer w.alerts.mu.Unlock()
+	return w.alerts.current
+}
+
+// clearAlert ends the alert of a widget that goes away.
+func (w *DexcomWidget) clearAlert() {
+	if w.alerts == nil {
+		return
+	}
+
+	w.alerts.mu.Lock()
+	previous := w.alerts.current
+	w.alerts.current = events.Alert{ID: w.ID}
+	w.alerts.mu.Unlock()
+
+	if previous.Active() {
+		publish(w.provider, events.Alert{ID: w.ID})
+	}
+}
@@ -0,0 +1,327 @@
+package widgets
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"testing"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"github.com/mntndev/dash/pkg/config"
+	"github.com/mntndev/dash/pkg/events"
+	"github.com/mntndev/dash/pkg/integrations"
+	"github.com/mntndev/dash/pkg/integrations/nstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDexcomChartConfig(t *testing.T) {
+	w, err := CreateDexcomWidget("glucose", configToNode(map[string]interface{}{
+		"low_threshold": 80,
+		"chart": map[string]interface{}{
+			"window":     "2h",
+			"point_size": 6,
+		},
+	}), nil, newFakeProvider(), nil, nil)
+	require.NoError(t, err)
+	dexcom := w.(*DexcomWidget)
+	require.NotNil(t, dexcom.chart)
+	assert.Equal(t, 2*time.Hour, dexcom.chartWindow)
+	assert.Equal(t, unit.Dp(6), dexcom.chart.PointSize)
+	require.Len(t, dexcom.chart.Bands, 1)
+	assert.Equal(t, 80.0, dexcom.chart.Bands[0].Low)
+	assert.Equal(t, 160.0, dexcom.chart.Bands[0].High)
+
+	w, err = CreateDexcomWidget("glucose", configToNode(map[string]interface{}{
+		"chart": map[string]interface{}{"type": "line"},
+	}), nil, newFakeProvider(), nil, nil)
+	require.NoError(t, err)
+	dexcom = w.(*DexcomWidget)
+	assert.Zero(t, dexcom.chart.PointSize)
+	assert.Equal(t, 3*time.Hour, dexcom.chartWindow)
+
+	w, err = CreateDexcomWidget("glucose", nil, nil, newFakeProvider(), nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, w.(*DexcomWidget).chart, "the chart is off by default")
+}
+
+func TestDexcomConfigValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+	}{
+		{name: "source without readings", config: map[string]interface{}{"source": "prometheus"}},
+		{name: "unknown chart type", config: map[string]interface{}{"chart": map[string]interface{}{"type": "bar"}}},
+		{name: "invalid window", config: map[string]interface{}{"chart": map[string]interface{}{"window": "soon"}}},
+		{name: "window beyond history", config: map[string]interface{}{"chart": map[string]interface{}{"window": "6h"}}},
+		{name: "negative point size", config: map[string]interface{}{"chart": map[string]interface{}{"point_size": -1}}},
+		{name: "low above high", config: map[string]interface{}{"low_threshold": 200, "high_threshold": 150}},
+		{name: "invalid stale_after", config: map[string]interface{}{"stale_after": "-5m"}},
+		{name: "unknown stale_style", config: map[string]interface{}{"stale_style": "blink"}},
+		{name: "urgent low above low", config: map[string]interface{}{"alerts": map[string]interface{}{"urgent_low": 80}}},
+		{name: "alert low above high", config: map[string]interface{}{"alerts": map[string]interface{}{"low": 200, "high": 180}}},
+		{name: "invalid snooze", config: map[string]interface{}{"alerts": map[string]interface{}{"snooze": "later"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CreateDexcomWidget("glucose", configToNode(tt.config), nil, newFakeProvider(), nil, nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDexcomRangeColor(t *testing.T) {
+	w, err := CreateDexcomWidget("glucose", nil, nil, newFakeProvider(), nil, nil)
+	require.NoError(t, err)
+	dexcom := w.(*DexcomWidget)
+
+	assert.Equal(t, dexcomLowColor, dexcom.rangeColor(69))
+	assert.Equal(t, dexcomInRangeColor, dexcom.rangeColor(70))
+	assert.Equal(t, dexcomInRangeColor, dexcom.rangeColor(160))
+	assert.Equal(t, dexcomHighColor, dexcom.rangeColor(161))
+}
+
+func TestDexcomChartLayout(t *testing.T) {
+	w, err := CreateDexcomWidget("glucose", configToNode(map[string]interface{}{
+		"chart": map[string]interface{}{},
+	}), nil, newFakeProvider(), nil, material.NewTheme())
+	require.NoError(t, err)
+	dexcom := w.(*DexcomWidget)
+
+	now := time.Date(2025, 10, 13, 12, 0, 0, 0, time.UTC)
+	dexcom.data.Store(&DexcomData{
+		Value: 180,
+		Unit:  "mg/dL",
+		Historical: []DexcomReading{
+			{Value: 180, Timestamp: now.Add(-5 * time.Minute)},
+			{Value: 120, Timestamp: now.Add(-time.Hour)},
+			{Value: 60, Timestamp: now.Add(-4 * time.Hour)},
+		},
+	})
+
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Now:         now,
+		Constraints: layout.Exact(image.Pt(400, 300)),
+	}
+	dims := dexcom.Layout(gtx)
+	assert.Equal(t, image.Pt(400, 300), dims.Size)
+}
+
+func TestTimeAxisStep(t *testing.T) {
+	assert.Equal(t, 15*time.Minute, timeAxisStep(time.Hour))
+	assert.Equal(t, time.Hour, timeAxisStep(3*time.Hour))
+	assert.Equal(t, 6*time.Hour, timeAxisStep(24*time.Hour))
+}
+
+func TestDexcomAlertConditions(t *testing.T) {
+	alerts, err := newDexcomAlerts(&DexcomAlertConfig{High: 250}, 70, 160, unitMgdl)
+	require.NoError(t, err)
+
+	rate := func(r float64) *float64 { return &r }
+	tests := []struct {
+		value     float64
+		rate      *float64
+		condition string
+		urgent    bool
+	}{
+		{value: 50, condition: "urgent_low", urgent: true},
+		{value: 55, rate: rate(5), condition: "urgent_low", urgent: true},
+		{value: 68, condition: "low"},
+		{value: 200},
+		{value: 260, condition: "high"},
+		{value: 120, rate: rate(-3.5), condition: "falling_fast"},
+		{value: 120, rate: rate(3), condition: "rising_fast"},
+		{value: 120, rate: rate(1)},
+	}
+	for _, tt := range tests {
+		condition, message, urgent := alerts.check(&DexcomData{Value: tt.value, Rate: tt.rate, Unit: unitMgdl})
+		assert.Equal(t, tt.condition, condition, "value %v rate %v", tt.value, tt.rate)
+		assert.Equal(t, tt.urgent, urgent)
+		if condition != "" {
+			assert.Contains(t, message, fmt.Sprintf("%.0f mg/dL", tt.value))
+		}
+	}
+}
+
+func TestDexcomMmol(t *testing.T) {
+	w, err := CreateDexcomWidget("glucose", configToNode(map[string]interface{}{
+		"unit":           "mmol/L",
+		"high_threshold": 10,
+		"alerts":         map[string]interface{}{},
+	}), nil, newFakeProvider(), nil, nil)
+	require.NoError(t, err)
+	dexcom := w.(*DexcomWidget)
+	assert.Equal(t, unitMmol, dexcom.unit)
+	assert.InDelta(t, 3.9, dexcom.lowThreshold, 0.05, "the default is converted")
+	assert.Equal(t, 10.0, dexcom.highThreshold)
+
+	condition, message, urgent := dexcom.alerts.check(&DexcomData{Value: 2.8, Unit: unitMmol})
+	assert.Equal(t, "urgent_low", condition)
+	assert.True(t, urgent)
+	assert.Equal(t, "Urgent low: 2.8 mmol/L", message)
+
+	fast := 0.2
+	condition, _, _ = dexcom.alerts.check(&DexcomData{Value: 6, Rate: &fast, Unit: unitMmol})
+	assert.Equal(t, "rising_fast", condition)
+
+	_, err = CreateDexcomWidget("glucose", configToNode(map[string]interface{}{"unit": "mmol/dL"}), nil, newFakeProvider(), nil, nil)
+	assert.Error(t, err)
+}
+
+func TestGlucoseRate(t *testing.T) {
+	now := time.Now()
+	rate, ok := glucoseRate([]DexcomReading{
+		{Value: 130, Timestamp: now},
+		{Value: 115, Timestamp: now.Add(-5 * time.Minute)},
+	})
+	require.True(t, ok)
+	assert.InDelta(t, 3.0, rate, 1e-9)
+
+	_, ok = glucoseRate([]DexcomReading{
+		{Value: 130, Timestamp: now},
+		{Value: 115, Timestamp: now.Add(-30 * time.Minute)},
+	})
+	assert.False(t, ok, "readings too far apart")
+
+	_, ok = glucoseRate([]DexcomReading{{Value: 130, Timestamp: now}})
+	assert.False(t, ok)
+}
+
+func TestDexcomWidgetPublishesAlerts(t *testing.T) {
+	provider := newFakeProvider()
+	provider.bus = events.NewBus()
+	defer provider.bus.Close()
+	alerts := events.Subscribe[events.Alert](provider.bus, 8)
+
+	w, err := CreateDexcomWidget("glucose", configToNode(map[string]interface{}{
+		"alerts": map[string]interface{}{"snooze": "10m"},
+	}), nil, provider, nil, nil)
+	require.NoError(t, err)
+	dexcom := w.(*DexcomWidget)
+
+	dexcom.updateAlert(&DexcomData{Value: 120, Unit: "mg/dL"})
+	assert.Empty(t, alerts.C(), "nothing to clear")
+
+	dexcom.updateAlert(&DexcomData{Value: 50, Unit: "mg/dL"})
+	require.Len(t, alerts.C(), 1)
+	alert := <-alerts.C()
+	assert.Equal(t, "glucose", alert.ID)
+	assert.Equal(t, "urgent_low", alert.Condition)
+	assert.True(t, alert.Urgent)
+	assert.Equal(t, 10*time.Minute, alert.Snooze)
+	assert.Equal(t, alert, dexcom.Alert())
+
+	// Every reading while the alert holds is published, so the message
+	// stays current
+	dexcom.updateAlert(&DexcomData{Value: 48, Unit: "mg/dL"})
+	require.Len(t, alerts.C(), 1)
+	assert.Equal(t, "Urgent low: 48 mg/dL", (<-alerts.C()).Message)
+
+	dexcom.updateAlert(&DexcomData{Value: 90, Unit: "mg/dL"})
+	require.Len(t, alerts.C(), 1)
+	assert.False(t, (<-alerts.C()).Active())
+
+	// Removing the widget ends its alert
+	dexcom.updateAlert(&DexcomData{Value: 65, Unit: "mg/dL"})
+	<-alerts.C()
+	require.NoError(t, dexcom.Close())
+	require.Len(t, alerts.C(), 1)
+	assert.False(t, (<-alerts.C()).Active())
+	assert.False(t, dexcom.Alert().Active())
+}
+
+func TestDexcomStaleReading(t *testing.T) {
+	w, err := CreateDexcomWidget("glucose", configToNode(map[string]interface{}{
+		"stale_after": "10m",
+		"stale_style": "flash",
+	}), nil, newFakeProvider(), nil, material.NewTheme())
+	require.NoError(t, err)
+	dexcom := w.(*DexcomWidget)
+	assert.Equal(t, 10*time.Minute, dexcom.staleAfter)
+	assert.True(t, dexcom.flashStale)
+
+	now := time.Date(2025, 10, 13, 12, 0, 1, 0, time.UTC)
+	dexcom.data.Store(&DexcomData{Value: 120, Unit: "mg/dL", Timestamp: now.Add(-20 * time.Minute)})
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Now:         now,
+		Constraints: layout.Exact(image.Pt(400, 300)),
+	}
+	dims := dexcom.Layout(gtx)
+	assert.NotZero(t, dims.Size.Y)
+}
+
+func TestDexcomWidgetNightscoutSource(t *testing.T) {
+	server := nstest.NewServer(t, "", "dash-1234")
+	now := time.Now().Truncate(time.Millisecond)
+	server.AddReading(108, "Flat", now.Add(-5*time.Minute))
+	server.AddReading(126, "SingleUp", now)
+	server.AddDeviceStatus(map[string]interface{}{
+		"device":          "loop://iPhone",
+		"uploaderBattery": 71,
+		"loop":            map[string]interface{}{"iob": map[string]interface{}{"iob": 2.5}},
+	}, now)
+
+	client := integrations.NewNightscoutClient(&config.NightscoutConfig{URL: server.URL(), Token: "dash-1234", PollInterval: "1h"})
+	require.NoError(t, client.Start())
+	t.Cleanup(func() { _ = client.Stop() })
+
+	w, err := CreateDexcomWidget("glucose", configToNode(map[string]interface{}{
+		"source": "nightscout",
+		"unit":   "mmol/L",
+	}), nil, newFakeProvider(client), nil, material.NewTheme())
+	require.NoError(t, err)
+	dexcom := w.(*DexcomWidget)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, dexcom.Init(ctx))
+
+	require.Eventually(t, func() bool { return dexcom.data.Load() != nil }, 5*time.Second, 10*time.Millisecond)
+	data := dexcom.data.Load()
+	assert.InDelta(t, 7.0, data.Value, 1e-9)
+	assert.Equal(t, unitMmol, data.Unit)
+	assert.Equal(t, "↑", data.Trend)
+	assert.Equal(t, "SingleUp", data.TrendName)
+	require.NotNil(t, data.Rate)
+	assert.InDelta(t, 0.2, *data.Rate, 1e-9, "18 mg/dL in 5 minutes")
+	require.Len(t, data.Historical, 2)
+	assert.True(t, now.Equal(data.Timestamp))
+	assert.Equal(t, "IOB 2.50 U, Battery 71%", formatGlucoseDevice(data.Device))
+}
+
+// emptyGlucoseSource is a glucose source that has fetched no readings but
+// does not say so with an error.
+type emptyGlucoseSource struct{}
+
+func (emptyGlucoseSource) Name() string                   { return integrations.NightscoutName }
+func (emptyGlucoseSource) Start() error                   { return nil }
+func (emptyGlucoseSource) Stop() error                    { return nil }
+func (emptyGlucoseSource) Health() integrations.Health    { return integrations.Health{} }
+func (emptyGlucoseSource) Subscribe() <-chan struct{}     { return make(chan struct{}) }
+func (emptyGlucoseSource) Unsubscribe(ch <-chan struct{}) {}
+func (emptyGlucoseSource) Glucose() (integrations.GlucoseData, error) {
+	return integrations.GlucoseData{Updated: time.Now()}, nil
+}
+
+func TestDexcomWidgetEmptyReadings(t *testing.T) {
+	w, err := CreateDexcomWidget("glucose", configToNode(map[string]interface{}{"source": "nightscout"}), nil, newFakeProvider(emptyGlucoseSource{}), nil, nil)
+	require.NoError(t, err)
+	dexcom := w.(*DexcomWidget)
+	assert.ErrorIs(t, dexcom.updateData(), integrations.ErrNoGlucoseData)
+	assert.Nil(t, dexcom.data.Load())
+}
+
+func TestDexcomWidgetWithoutSource(t *testing.T) {
+	w, err := CreateDexcomWidget("glucose", configToNode(map[string]interface{}{"source": "nightscout"}), nil, newFakeProvider(), nil, nil)
+	require.NoError(t, err)
+	dexcom := w.(*DexcomWidget)
+	require.NoError(t, dexcom.Init(context.Background()))
+	assert.ErrorContains(t, dexcom.updateData(), `glucose source "nightscout" not available`)
+}
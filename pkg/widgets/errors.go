@@ -0,0 +1,164 @@
+package widgets
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"log"
+	"runtime/debug"
+	"sync"
+
+	"gioui.org/app"
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+)
+
+// WidgetError is a widget that failed to be created, initialized or laid
+// out.
+type WidgetError struct {
+	ID   string
+	Type string
+	Err  error
+}
+
+func (e *WidgetError) Error() string {
+	return fmt.Sprintf("%s (%s): %v", e.Type, e.ID, e.Err)
+}
+
+func (e *WidgetError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorWidget takes the place of a widget that could not be created or
+// initialized, so the rest of the dashboard keeps working.
+type ErrorWidget struct {
+	*BaseWidget
+	theme *material.Theme
+	err   *WidgetError
+}
+
+// NewErrorWidget returns a tile showing err in place of the widget with the
+// given id and type.
+func NewErrorWidget(id, widgetType string, err error, window *app.Window, theme *material.Theme) *ErrorWidget {
+	return &ErrorWidget{
+		BaseWidget: &BaseWidget{
+			ID:     id,
+			Type:   widgetType,
+			window: window,
+		},
+		theme: theme,
+		err:   &WidgetError{ID: id, Type: widgetType, Err: err},
+	}
+}
+
+func (w *ErrorWidget) Init(ctx context.Context) error {
+	return nil
+}
+
+// Err returns why the widget failed.
+func (w *ErrorWidget) Err() *WidgetError {
+	return w.err
+}
+
+func (w *ErrorWidget) Layout(gtx layout.Context) layout.Dimensions {
+	return layoutErrorTile(gtx, w.theme, w.err)
+}
+
+// errorTheme draws error tiles for widgets that panicked, which may not have
+// a theme.
+var errorTheme = sync.OnceValue(material.NewTheme)
+
+// layoutPanics remembers the widgets whose Layout panicked. They are drawn as
+// an error tile from then on instead of panicking every frame.
+var layoutPanics = struct {
+	sync.Mutex
+	widgets map[Widget]*WidgetError
+}{widgets: make(map[Widget]*WidgetError)}
+
+// LayoutWidget lays out w, or an error tile if w panics. Containers use it
+// for their children so one broken widget only blanks its own slot.
+func LayoutWidget(gtx layout.Context, w Widget) (dims layout.Dimensions) {
+	if err, failed := LayoutError(w); failed {
+		return layoutErrorTile(gtx, nil, err)
+	}
+
+	// Record the widget so the ops of a half finished layout can be dropped
+	macro := op.Record(gtx.Ops)
+	defer func() {
+		r := recover()
+		call := macro.Stop()
+		if r == nil {
+			call.Add(gtx.Ops)
+			return
+		}
+
+		err := &WidgetError{ID: w.GetID(), Type: w.GetType(), Err: fmt.Errorf("layout panicked: %v", r)}
+		log.Printf("Widget %s: %v\n%s", w.GetID(), err.Err, debug.Stack())
+		layoutPanics.Lock()
+		layoutPanics.widgets[w] = err
+		layoutPanics.Unlock()
+		dims = layoutErrorTile(gtx, nil, err)
+	}()
+
+	return w.Layout(gtx)
+}
+
+// childLayout returns a layout.Widget that lays out child through
+// LayoutWidget.
+func childLayout(child Widget) layout.Widget {
+	return func(gtx layout.Context) layout.Dimensions {
+		return LayoutWidget(gtx, child)
+	}
+}
+
+// LayoutError returns the error of w if its Layout panicked.
+func LayoutError(w Widget) (*WidgetError, bool) {
+	layoutPanics.Lock()
+	defer layoutPanics.Unlock()
+	err, failed := layoutPanics.widgets[w]
+	return err, failed
+}
+
+// ForgetLayoutError drops the layout error of w once it is closed.
+func ForgetLayoutError(w Widget) {
+	layoutPanics.Lock()
+	defer layoutPanics.Unlock()
+	delete(layoutPanics.widgets, w)
+}
+
+func layoutErrorTile(gtx layout.Context, theme *material.Theme, err *WidgetError) layout.Dimensions {
+	if theme == nil {
+		theme = errorTheme()
+	}
+	fg := color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+
+	return layout.Background{}.Layout(gtx,
+		func(gtx layout.Context) layout.Dimensions {
+			defer clip.Rect{Max: gtx.Constraints.Min}.Push(gtx.Ops).Pop()
+			paint.Fill(gtx.Ops, color.NRGBA{R: 0x80, G: 0x18, B: 0x18, A: 0xff})
+			return layout.Dimensions{Size: gtx.Constraints.Min}
+		},
+		func(gtx layout.Context) layout.Dimensions {
+			return layout.UniformInset(unit.Dp(8)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						label := material.Body1(theme, fmt.Sprintf("%s (%s)", err.Type, err.ID))
+						label.Color = fg
+						label.Font.Weight = font.Bold
+						label.MaxLines = 1
+						return label.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						label := material.Body2(theme, err.Err.Error())
+						label.Color = fg
+						return label.Layout(gtx)
+					}),
+				)
+			})
+		})
+}
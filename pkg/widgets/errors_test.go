@@ -0,0 +1,64 @@
+package widgets
+
+import (
+	"errors"
+	"image"
+	"testing"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// panicWidget panics on every layout and counts how often it was tried.
+type panicWidget struct {
+	*BaseWidget
+	layouts int
+}
+
+func (w *panicWidget) Layout(gtx layout.Context) layout.Dimensions {
+	w.layouts++
+	panic("broken")
+}
+
+func TestLayoutWidgetRecovers(t *testing.T) {
+	broken := &panicWidget{BaseWidget: &BaseWidget{ID: "broken", Type: "test"}}
+	healthy := &sizeWidget{BaseWidget: &BaseWidget{}}
+	defer ForgetLayoutError(broken)
+
+	w, err := CreateVFlexWidget("vflex", nil, []Widget{broken, healthy}, newFakeProvider(), nil, nil)
+	require.NoError(t, err)
+
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(200, 200))}
+	dims := LayoutWidget(gtx, w)
+
+	assert.Equal(t, image.Pt(200, 200), dims.Size)
+	assert.Equal(t, image.Pt(200, 100), healthy.size)
+
+	failure, failed := LayoutError(broken)
+	require.True(t, failed)
+	assert.Equal(t, "test (broken): layout panicked: broken", failure.Error())
+	_, failed = LayoutError(w)
+	assert.False(t, failed)
+
+	// Broken widgets are not laid out again
+	LayoutWidget(gtx, w)
+	assert.Equal(t, 1, broken.layouts)
+
+	ForgetLayoutError(broken)
+	_, failed = LayoutError(broken)
+	assert.False(t, failed)
+}
+
+func TestErrorWidget(t *testing.T) {
+	cause := errors.New("entity not found")
+	w := NewErrorWidget("lights", "home_assistant.switch", cause, nil, nil)
+
+	assert.Equal(t, "lights", w.GetID())
+	assert.Equal(t, "home_assistant.switch", w.GetType())
+	assert.ErrorIs(t, w.Err(), cause)
+
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(200, 100))}
+	assert.Equal(t, image.Pt(200, 100), w.Layout(gtx).Size)
+}
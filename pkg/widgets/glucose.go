@@ -0,0 +1,136 @@
+package widgets
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mntndev/dash/pkg/integrations"
+)
+
+// Glucose units. Readings come in mg/dL and are converted for display.
+const (
+	unitMgdl = "mg/dL"
+	unitMmol = "mmol/L"
+	// mgdlPerMmol converts between the two units.
+	mgdlPerMmol = 18.0
+)
+
+// parseGlucoseUnit returns the configured unit, mg/dL if empty.
+func parseGlucoseUnit(unit string) (string, error) {
+	switch strings.ToLower(unit) {
+	case "", "mg/dl":
+		return unitMgdl, nil
+	case "mmol/l", "mmol":
+		return unitMmol, nil
+	default:
+		return "", fmt.Errorf("unsupported unit %q, use mg/dL or mmol/L", unit)
+	}
+}
+
+// convertGlucose converts a value or rate in mg/dL to unit.
+func convertGlucose(mgdl float64, unit string) float64 {
+	if unit == unitMmol {
+		return mgdl / mgdlPerMmol
+	}
+	return mgdl
+}
+
+// formatGlucose formats a value in unit with the precision it is usually
+// given in.
+func formatGlucose(value float64, unit string) string {
+	if unit == unitMmol {
+		return fmt.Sprintf("%.1f", value)
+	}
+	return fmt.Sprintf("%.0f", value)
+}
+
+// formatGlucoseRate formats a change per minute in unit.
+func formatGlucoseRate(rate float64, unit string) string {
+	if unit == unitMmol {
+		return fmt.Sprintf("%+.2f %s/min", rate, unit)
+	}
+	return fmt.Sprintf("%+.1f %s/min", rate, unit)
+}
+
+// formatGlucoseDevice summarizes what the uploader and pump report, or
+// returns an empty string if they report nothing.
+func formatGlucoseDevice(device *integrations.GlucoseDevice) string {
+	if device == nil {
+		return ""
+	}
+	var parts []string
+	if device.IOB != nil {
+		parts = append(parts, fmt.Sprintf("IOB %.2f U", *device.IOB))
+	}
+	if device.COB != nil {
+		parts = append(parts, fmt.Sprintf("COB %.0f g", *device.COB))
+	}
+	if device.Battery != nil {
+		parts = append(parts, fmt.Sprintf("Battery %d%%", *device.Battery))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// glucoseTrend is the direction a sensor reports with a reading.
+type glucoseTrend int
+
+const (
+	trendNone glucoseTrend = iota
+	trendDoubleUp
+	trendSingleUp
+	trendFortyFiveUp
+	trendFlat
+	trendFortyFiveDown
+	trendSingleDown
+	trendDoubleDown
+	trendNotComputable
+	trendRateOutOfRange
+)
+
+// glucoseTrends describes every trend. Rate is the middle of the range of
+// change per minute in mg/dL the trend stands for.
+var glucoseTrends = [...]struct {
+	name    string
+	arrow   string
+	rate    float64
+	hasRate bool
+}{
+	trendNone:           {name: "None", arrow: "?"},
+	trendDoubleUp:       {name: "DoubleUp", arrow: "⇈", rate: 3.5, hasRate: true},
+	trendSingleUp:       {name: "SingleUp", arrow: "↑", rate: 2.5, hasRate: true},
+	trendFortyFiveUp:    {name: "FortyFiveUp", arrow: "↗", rate: 1.5, hasRate: true},
+	trendFlat:           {name: "Flat", arrow: "→", rate: 0, hasRate: true},
+	trendFortyFiveDown:  {name: "FortyFiveDown", arrow: "↘", rate: -1.5, hasRate: true},
+	trendSingleDown:     {name: "SingleDown", arrow: "↓", rate: -2.5, hasRate: true},
+	trendDoubleDown:     {name: "DoubleDown", arrow: "⇊", rate: -3.5, hasRate: true},
+	trendNotComputable:  {name: "NotComputable", arrow: "-"},
+	trendRateOutOfRange: {name: "RateOutOfRange", arrow: "⇕"},
+}
+
+// parseGlucoseTrend accepts the numeric codes of older Dexcom Share
+// responses and the names it and Nightscout use now, in any case and with
+// or without spaces.
+func parseGlucoseTrend(raw string) glucoseTrend {
+	name := strings.ReplaceAll(strings.TrimSpace(raw), " ", "")
+	for trend := range glucoseTrends {
+		if name == fmt.Sprint(trend) || strings.EqualFold(name, glucoseTrends[trend].name) {
+			return glucoseTrend(trend)
+		}
+	}
+	return trendNone
+}
+
+func (t glucoseTrend) String() string {
+	return glucoseTrends[t].name
+}
+
+// Arrow returns the arrow the Dexcom apps show for the trend.
+func (t glucoseTrend) Arrow() string {
+	return glucoseTrends[t].arrow
+}
+
+// Rate returns the estimated change per minute in mg/dL, or false if the
+// trend does not tell.
+func (t glucoseTrend) Rate() (float64, bool) {
+	return glucoseTrends[t].rate, glucoseTrends[t].hasRate
+}
@@ -0,0 +1,60 @@
+package widgets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGlucoseTrend(t *testing.T) {
+	tests := []struct {
+		raw   string
+		trend glucoseTrend
+	}{
+		{raw: "1", trend: trendDoubleUp},
+		{raw: "DoubleUp", trend: trendDoubleUp},
+		{raw: "2", trend: trendSingleUp},
+		{raw: "3", trend: trendFortyFiveUp},
+		{raw: "FortyFiveUp", trend: trendFortyFiveUp},
+		{raw: "Flat", trend: trendFlat},
+		{raw: "5", trend: trendFortyFiveDown},
+		{raw: "SingleDown", trend: trendSingleDown},
+		{raw: "7", trend: trendDoubleDown},
+		{raw: "NOT COMPUTABLE", trend: trendNotComputable},
+		{raw: "RateOutOfRange", trend: trendRateOutOfRange},
+		{raw: "0", trend: trendNone},
+		{raw: "", trend: trendNone},
+		{raw: "Sideways", trend: trendNone},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.trend, parseGlucoseTrend(tt.raw), tt.raw)
+	}
+
+	arrows := map[string]bool{}
+	for trend := range glucoseTrends {
+		arrows[glucoseTrend(trend).Arrow()] = true
+	}
+	assert.Len(t, arrows, len(glucoseTrends), "every trend has its own arrow")
+
+	rate, ok := trendSingleUp.Rate()
+	assert.True(t, ok)
+	assert.Equal(t, 2.5, rate)
+	_, ok = trendNotComputable.Rate()
+	assert.False(t, ok)
+}
+
+func TestGlucoseUnits(t *testing.T) {
+	unit, err := parseGlucoseUnit("")
+	assert.NoError(t, err)
+	assert.Equal(t, unitMgdl, unit)
+	unit, err = parseGlucoseUnit("mmol/l")
+	assert.NoError(t, err)
+	assert.Equal(t, unitMmol, unit)
+	_, err = parseGlucoseUnit("g/L")
+	assert.Error(t, err)
+
+	assert.Equal(t, "6.7", formatGlucose(convertGlucose(120, unitMmol), unitMmol))
+	assert.Equal(t, "120", formatGlucose(convertGlucose(120, unitMgdl), unitMgdl))
+	assert.Equal(t, "-1.5 mg/dL/min", formatGlucoseRate(-1.5, unitMgdl))
+	assert.Equal(t, "+0.08 mmol/L/min", formatGlucoseRate(convertGlucose(1.5, unitMmol), unitMmol))
+}
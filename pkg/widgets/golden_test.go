@@ -0,0 +1,95 @@
+package widgets
+
+import (
+	"context"
+	"image"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"gioui.org/layout"
+	"gioui.org/widget/material"
+	"github.com/mntndev/dash/pkg/config"
+	"github.com/mntndev/dash/pkg/render"
+	"github.com/mntndev/dash/pkg/render/rendertest"
+	"github.com/stretchr/testify/require"
+)
+
+// goldenFixture is the config a widget type is rendered with in the golden
+// image tests, and the data it shows if any. Output must not depend on the
+// time or the network.
+type goldenFixture struct {
+	config   map[string]interface{}
+	children int
+	data     string
+}
+
+var goldenFixtures = map[string]goldenFixture{
+	"home_assistant.entity": {config: map[string]interface{}{"entity_id": "sensor.temperature"}, data: `{"state": "21.5", "attributes": {"unit_of_measurement": "°C", "friendly_name": "Living room"}}`},
+	"home_assistant.button": {config: map[string]interface{}{"entity_id": "light.kitchen", "service": "toggle", "domain": "light", "label": "Kitchen"}},
+	"home_assistant.switch": {config: map[string]interface{}{"entity_id": "switch.fan"}, data: `{"state": "on", "attributes": {"friendly_name": "Fan"}}`},
+	"home_assistant.light":  {config: map[string]interface{}{"entity_id": "light.desk"}, data: `{"state": "on", "attributes": {"friendly_name": "Desk", "brightness": 128}}`},
+	"dexcom":                {},
+	"prometheus.stat":       {config: map[string]interface{}{"query": "up", "label": "Up"}, data: `{"value": 1}`},
+	"prometheus.gauge":      {config: map[string]interface{}{"query": "load", "label": "Load"}, data: `{"value": 63.5}`},
+	"prometheus.graph":      {config: map[string]interface{}{"query": "rate(requests[5m])", "label": "Requests"}, data: `{}`},
+	"rss.list":              {data: `{"items": [{"title": "Dash 1.0 released"}, {"title": "Nightscout support"}]}`},
+	"rss.ticker":            {data: `{"items": [{"title": "Dash 1.0 released"}, {"title": "Nightscout support"}]}`},
+	// A layout without time tokens keeps the clock still
+	"clock":            {config: map[string]interface{}{"format": "Clock"}},
+	"hstack":           {children: 2},
+	"vstack":           {children: 2},
+	"hflex":            {config: map[string]interface{}{"sizes": []interface{}{1, 2}}, children: 2},
+	"vflex":            {children: 2},
+	"horizontal_split": {children: 2},
+	"vertical_split":   {children: 2},
+	"pages":            {config: map[string]interface{}{"tab_bar": true}, children: 2},
+	"grid":             {config: map[string]interface{}{"columns": 2}, children: 3},
+	"grow":             {config: map[string]interface{}{"grow": 2}, children: 1},
+}
+
+func TestGoldenWidgets(t *testing.T) {
+	registry := NewWidgetRegistry()
+	registerBuiltinWidgets(registry)
+	types := registry.GetSupportedTypes()
+	sort.Strings(types)
+
+	for _, widgetType := range types {
+		if _, ok := goldenFixtures[widgetType]; !ok {
+			t.Errorf("no golden fixture for widget type %s", widgetType)
+		}
+	}
+
+	theme := material.NewTheme()
+	for _, widgetType := range types {
+		fixture, ok := goldenFixtures[widgetType]
+		if !ok {
+			continue
+		}
+
+		t.Run(widgetType, func(t *testing.T) {
+			children := make([]Widget, fixture.children)
+			for i := range children {
+				children[i] = &BaseWidget{ID: "child", Type: "child"}
+			}
+			w, err := registry.Create(widgetType, widgetType, configToNode(fixture.config), children, newFakeProvider(), nil, theme)
+			require.NoError(t, err)
+			if placer, ok := w.(Placer); ok {
+				require.NoError(t, placer.SetPositions(make([]*config.Position, len(children))))
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			require.NoError(t, w.Init(ctx))
+			defer w.Close()
+			if fixture.data != "" {
+				require.NoError(t, w.(FixtureLoader).LoadFixture([]byte(fixture.data)))
+			}
+
+			img := rendertest.Render(t, func(gtx layout.Context) layout.Dimensions {
+				return LayoutWidget(gtx, w)
+			}, render.Options{Size: image.Pt(320, 160)})
+			rendertest.CompareGolden(t, filepath.Join("testdata", "golden", widgetType+".png"), img)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package widgets
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log"
+	"time"
+
+	"gioui.org/app"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/mntndev/dash/pkg/config"
+)
+
+// GridWidget lays out its children on a grid of equally sized cells. Each
+// child covers the cells given by its position in the config.
+type GridWidget struct {
+	*BaseWidget
+	grid  config.GridConfig
+	cells []config.Position
+	rows  int
+}
+
+func CreateGridWidget(id string, node ast.Node, children []Widget, provider Provider, window *app.Window, theme *material.Theme) (Widget, error) {
+	grid, err := config.ParseGridConfig(node)
+	if err != nil {
+		return nil, err
+	}
+
+	widget := &GridWidget{
+		BaseWidget: &BaseWidget{
+			ID:       id,
+			Type:     "grid",
+			Config:   node,
+			Children: children,
+			window:   window,
+		},
+		grid: grid,
+	}
+
+	// Until positions are set every child takes the next free cell
+	if err := widget.SetPositions(make([]*config.Position, len(children))); err != nil {
+		return nil, err
+	}
+	return widget, nil
+}
+
+func (w *GridWidget) SetPositions(positions []*config.Position) error {
+	if len(positions) != len(w.Children) {
+		return fmt.Errorf("got %d positions for %d children", len(positions), len(w.Children))
+	}
+
+	cells, err := config.PlaceGridCells(w.grid, positions)
+	if err != nil {
+		return err
+	}
+	w.cells = cells
+	w.rows = config.GridRows(w.grid, cells)
+	return nil
+}
+
+func (w *GridWidget) Init(ctx context.Context) error {
+	w.SetLastUpdate(time.Now())
+	return nil
+}
+
+func (w *GridWidget) IsContainer() bool {
+	return true
+}
+
+func (w *GridWidget) SetChildren(children []Widget) {
+	w.Children = children
+	w.SetLastUpdate(time.Now())
+}
+
+func (w *GridWidget) Close() error {
+	for _, child := range w.Children {
+		if err := child.Close(); err != nil {
+			log.Printf("Failed to close child widget: %v", err)
+		}
+	}
+	return nil
+}
+
+func (w *GridWidget) Layout(gtx layout.Context) layout.Dimensions {
+	size := gtx.Constraints.Max
+	children := w.GetChildren()
+	if len(children) == 0 || w.rows == 0 {
+		return layout.Dimensions{Size: gtx.Constraints.Min}
+	}
+
+	for i, child := range children {
+		if i >= len(w.cells) {
+			break
+		}
+		w.layoutCell(gtx, child, w.cellRect(gtx, size, w.cells[i]))
+	}
+
+	return layout.Dimensions{Size: size}
+}
+
+// cellRect returns the area a position covers. Cell edges are rounded
+// separately so the gaps stay even and the last cell reaches the edge.
+func (w *GridWidget) cellRect(gtx layout.Context, size image.Point, pos config.Position) image.Rectangle {
+	gap := float32(gtx.Dp(unit.Dp(w.grid.Gap)))
+	columns, rows := float32(w.grid.Columns), float32(w.rows)
+	cellWidth := (float32(size.X) - gap*(columns-1)) / columns
+	cellHeight := (float32(size.Y) - gap*(rows-1)) / rows
+
+	// start is where cell index begins, end where the cell before it ends
+	start := func(index int, cell float32) int {
+		return int(float32(index)*(cell+gap) + 0.5)
+	}
+	end := func(index int, cell float32) int {
+		return int(float32(index)*(cell+gap) - gap + 0.5)
+	}
+	return image.Rectangle{
+		Min: image.Pt(start(pos.X, cellWidth), start(pos.Y, cellHeight)),
+		Max: image.Pt(end(pos.X+pos.Width, cellWidth), end(pos.Y+pos.Height, cellHeight)),
+	}
+}
+
+func (w *GridWidget) layoutCell(gtx layout.Context, child Widget, rect image.Rectangle) {
+	size := rect.Size()
+	if size.X <= 0 || size.Y <= 0 {
+		return
+	}
+
+	defer op.Offset(rect.Min).Push(gtx.Ops).Pop()
+	defer clip.Rect{Max: size}.Push(gtx.Ops).Pop()
+	gtx.Constraints = layout.Exact(size)
+	LayoutWidget(gtx, child)
+}
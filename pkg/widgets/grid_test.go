@@ -0,0 +1,73 @@
+package widgets
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"github.com/mntndev/dash/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sizeWidget records the constraints of its last layout.
+type sizeWidget struct {
+	*BaseWidget
+	size image.Point
+}
+
+func (w *sizeWidget) Layout(gtx layout.Context) layout.Dimensions {
+	w.size = gtx.Constraints.Max
+	return layout.Dimensions{Size: w.size}
+}
+
+func TestGridWidget(t *testing.T) {
+	children := []Widget{
+		&sizeWidget{BaseWidget: &BaseWidget{}},
+		&sizeWidget{BaseWidget: &BaseWidget{}},
+		&sizeWidget{BaseWidget: &BaseWidget{}},
+	}
+	w, err := CreateGridWidget("grid", configToNode(map[string]interface{}{
+		"columns": 3,
+		"rows":    2,
+		"gap":     10,
+	}), children, newFakeProvider(), nil, nil)
+	require.NoError(t, err)
+	grid := w.(*GridWidget)
+
+	require.NoError(t, grid.SetPositions([]*config.Position{
+		{X: 0, Y: 0, Width: 2, Height: 2},
+		nil,
+		{X: 2, Y: 1, Width: 1, Height: 1},
+	}))
+
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(320, 210))}
+	dims := grid.Layout(gtx)
+	assert.Equal(t, image.Pt(320, 210), dims.Size)
+
+	// Cells are 100x100 with 10 between them
+	size := image.Pt(320, 210)
+	assert.Equal(t, image.Rect(0, 0, 210, 210), grid.cellRect(gtx, size, grid.cells[0]))
+	assert.Equal(t, image.Rect(220, 0, 320, 100), grid.cellRect(gtx, size, grid.cells[1]))
+	assert.Equal(t, image.Rect(220, 110, 320, 210), grid.cellRect(gtx, size, grid.cells[2]))
+
+	assert.Equal(t, image.Pt(210, 210), children[0].(*sizeWidget).size)
+	assert.Equal(t, image.Pt(100, 100), children[1].(*sizeWidget).size)
+	assert.Equal(t, image.Pt(100, 100), children[2].(*sizeWidget).size)
+
+	t.Run("invalid positions", func(t *testing.T) {
+		err := grid.SetPositions([]*config.Position{
+			{X: 0, Y: 0, Width: 2, Height: 1},
+			{X: 1, Y: 0, Width: 1, Height: 1},
+			nil,
+		})
+		assert.ErrorContains(t, err, "overlaps")
+		assert.Error(t, grid.SetPositions(nil))
+	})
+
+	t.Run("columns are required", func(t *testing.T) {
+		_, err := CreateGridWidget("grid", nil, children, newFakeProvider(), nil, nil)
+		assert.ErrorContains(t, err, "columns must be positive")
+	})
+}
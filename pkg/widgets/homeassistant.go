@@ -2,21 +2,31 @@ package widgets
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"image/color"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"gioui.org/app"
 	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
 	"gioui.org/widget/material"
 	"github.com/goccy/go-yaml"
 	"github.com/goccy/go-yaml/ast"
+	"github.com/mntndev/dash/pkg/events"
 	"github.com/mntndev/dash/pkg/integrations"
 )
 
 // HAEntityConfig represents Home Assistant entity configuration.
 type HAEntityConfig struct {
 	EntityID string `yaml:"entity_id"`
+	// Graph adds a history graph under the state. Only used by
+	// home_assistant.entity.
+	Graph *HAGraphConfig `yaml:"graph"`
 }
 
 type HAButtonConfig struct {
@@ -29,17 +39,27 @@ type HAButtonConfig struct {
 type HABaseWidget struct {
 	*BaseWidget
 	EntityID     string
-	haProvider   integrations.HAProvider
 	provider     Provider
-	subscription <-chan integrations.StateChangeEvent
-	cancelSub    context.CancelFunc
 	dataCallback func(*HAEntityData)
 	theme        *material.Theme
+
+	// The subscription is set up by the Init goroutine and stopped by Close
+	subMu        sync.Mutex
+	subscription <-chan integrations.StateChangeEvent
+	cancelSub    context.CancelFunc
+	subStopped   bool
+
+	// Touch handling for widgets that call services when tapped
+	clickable widget.Clickable
+	actionMu  sync.Mutex
+	pending   bool
+	actionErr error
 }
 
 type HAEntityWidget struct {
 	*HABaseWidget
-	data *HAEntityData
+	data  atomic.Pointer[HAEntityData]
+	graph *haGraph
 }
 
 type HAButtonWidget struct {
@@ -51,12 +71,12 @@ type HAButtonWidget struct {
 
 type HASwitchWidget struct {
 	*HABaseWidget
-	data *HAEntityData
+	data atomic.Pointer[HAEntityData]
 }
 
 type HALightWidget struct {
 	*HABaseWidget
-	data *HAEntityData
+	data atomic.Pointer[HAEntityData]
 }
 
 type HAEntityData struct {
@@ -68,21 +88,69 @@ type HAEntityData struct {
 }
 
 func (w *HAEntityWidget) setDataAndInvalidate(data *HAEntityData) {
-	w.data = data
-	w.LastUpdate = time.Now()
+	w.data.Store(data)
+	if w.graph != nil {
+		w.graph.add(data.State, data.LastUpdated)
+	}
+	w.SetLastUpdate(time.Now())
 	w.Invalidate()
+	w.publishData(data)
 }
 
 func (w *HASwitchWidget) setDataAndInvalidate(data *HAEntityData) {
-	w.data = data
-	w.LastUpdate = time.Now()
+	w.data.Store(data)
+	w.SetLastUpdate(time.Now())
 	w.Invalidate()
+	w.publishData(data)
 }
 
 func (w *HALightWidget) setDataAndInvalidate(data *HAEntityData) {
-	w.data = data
-	w.LastUpdate = time.Now()
+	w.data.Store(data)
+	w.SetLastUpdate(time.Now())
 	w.Invalidate()
+	w.publishData(data)
+}
+
+// publishData announces a new entity state on the event bus.
+func (w *HABaseWidget) publishData(data *HAEntityData) {
+	publish(w.provider, events.WidgetData{ID: w.ID, Type: w.Type, Data: data})
+}
+
+// LoadFixture shows the entity state in data until Home Assistant reports a
+// newer one.
+func (hab *HABaseWidget) LoadFixture(raw json.RawMessage) error {
+	if hab.dataCallback == nil {
+		return fmt.Errorf("%s widgets do not show entity states", hab.Type)
+	}
+	var data HAEntityData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("invalid entity fixture: %w", err)
+	}
+	if data.EntityID == "" {
+		data.EntityID = hab.EntityID
+	}
+	hab.dataCallback(&data)
+	return nil
+}
+
+// GetData returns the last known state of the entity.
+func (w *HAEntityWidget) GetData() interface{} {
+	return w.data.Load()
+}
+
+// GetData returns the last known state of the switch.
+func (w *HASwitchWidget) GetData() interface{} {
+	return w.data.Load()
+}
+
+// GetData returns the last known state of the light.
+func (w *HALightWidget) GetData() interface{} {
+	return w.data.Load()
+}
+
+// GetData returns the service the button calls.
+func (w *HAButtonWidget) GetData() interface{} {
+	return w.data
 }
 
 type HAButtonData struct {
@@ -92,18 +160,23 @@ type HAButtonData struct {
 	Label    string `json:"label"`
 }
 
+// client returns the Home Assistant client, or nil if it is not configured.
+func (hab *HABaseWidget) client() *integrations.HomeAssistantClient {
+	return getIntegration[*integrations.HomeAssistantClient](hab.provider, integrations.HomeAssistantName)
+}
+
 func (hab *HABaseWidget) startSubscription(ctx context.Context) error {
-	haClient := hab.haProvider.GetHAClient()
-	if haClient == nil || !haClient.IsConnected() {
-		// HA client not connected yet, start a goroutine to wait for connection
-		go hab.waitForConnectionAndSubscribe(ctx)
+	haClient := hab.client()
+	if haClient == nil {
+		// HA client not created yet, start a goroutine to wait for it
+		go hab.waitForClientAndSubscribe(ctx)
 		return nil
 	}
 
 	return hab.setupSubscription(ctx)
 }
 
-func (hab *HABaseWidget) waitForConnectionAndSubscribe(ctx context.Context) {
+func (hab *HABaseWidget) waitForClientAndSubscribe(ctx context.Context) {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
@@ -112,13 +185,10 @@ func (hab *HABaseWidget) waitForConnectionAndSubscribe(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			haClient := hab.haProvider.GetHAClient()
-			if haClient != nil && haClient.IsConnected() {
+			if hab.client() != nil {
 				if err := hab.setupSubscription(ctx); err != nil {
 					// Log error but don't fail - widget can try again
 					fmt.Printf("Failed to setup HA subscription for %s: %v\n", hab.EntityID, err)
-				} else {
-					fmt.Printf("HA widget %s successfully connected and subscribed\n", hab.EntityID)
 				}
 				return
 			}
@@ -126,10 +196,13 @@ func (hab *HABaseWidget) waitForConnectionAndSubscribe(ctx context.Context) {
 	}
 }
 
+// setupSubscription subscribes to the entity. The client delivers the cached
+// state as the first event and replays the subscription across reconnects,
+// so this only needs to happen once.
 func (hab *HABaseWidget) setupSubscription(ctx context.Context) error {
-	haClient := hab.haProvider.GetHAClient()
-	if haClient == nil || !haClient.IsConnected() {
-		return fmt.Errorf("home Assistant client not connected")
+	haClient := hab.client()
+	if haClient == nil {
+		return fmt.Errorf("home Assistant client not available")
 	}
 
 	subscription, err := haClient.Subscribe(hab.EntityID)
@@ -137,23 +210,32 @@ func (hab *HABaseWidget) setupSubscription(ctx context.Context) error {
 		return fmt.Errorf("failed to subscribe to entity %s: %w", hab.EntityID, err)
 	}
 
+	hab.subMu.Lock()
+	defer hab.subMu.Unlock()
+	if hab.subStopped || ctx.Err() != nil {
+		// The widget was closed while subscribing
+		haClient.Unsubscribe(hab.EntityID, subscription)
+		return nil
+	}
 	hab.subscription = subscription
 
 	ctx, cancel := context.WithCancel(ctx)
 	hab.cancelSub = cancel
 
-	go hab.processStateChanges(ctx)
+	go hab.processStateChanges(ctx, subscription)
 
-	return hab.fetchInitialState()
+	return nil
 }
 
-func (hab *HABaseWidget) processStateChanges(ctx context.Context) {
+func (hab *HABaseWidget) processStateChanges(ctx context.Context, subscription <-chan integrations.StateChangeEvent) {
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case event := <-hab.subscription:
-			log.Printf("%v", event)
+		case event, ok := <-subscription:
+			if !ok {
+				return
+			}
 			if event.NewState != nil {
 				data := &HAEntityData{
 					EntityID:    event.NewState.EntityID,
@@ -170,46 +252,91 @@ func (hab *HABaseWidget) processStateChanges(ctx context.Context) {
 	}
 }
 
-func (hab *HABaseWidget) fetchInitialState() error {
-	haClient := hab.haProvider.GetHAClient()
-	if haClient == nil || !haClient.IsConnected() {
-		return fmt.Errorf("home Assistant client not connected")
+// stopSubscription ends the subscription, and one that is still being set
+// up as soon as it is.
+func (hab *HABaseWidget) stopSubscription() {
+	hab.subMu.Lock()
+	defer hab.subMu.Unlock()
+	hab.subStopped = true
+	if hab.cancelSub != nil {
+		hab.cancelSub()
+	}
+	if hab.subscription != nil {
+		haClient := hab.client()
+		if haClient != nil {
+			haClient.Unsubscribe(hab.EntityID, hab.subscription)
+		}
+		hab.subscription = nil
 	}
+}
 
-	states, err := haClient.GetStates()
-	if err != nil {
-		return fmt.Errorf("failed to get states: %w", err)
+// handleClicks runs trigger for every tap since the last frame. Service calls
+// block on the websocket, so they run off the UI goroutine.
+func (hab *HABaseWidget) handleClicks(gtx layout.Context, trigger func() error) {
+	for hab.clickable.Clicked(gtx) {
+		hab.runAction(trigger)
 	}
+}
 
-	for _, state := range states {
-		if state.EntityID == hab.EntityID {
-			data := &HAEntityData{
-				EntityID:    state.EntityID,
-				State:       state.State,
-				Attributes:  state.Attributes,
-				LastChanged: state.LastChanged,
-				LastUpdated: state.LastUpdated,
-			}
-			if hab.dataCallback != nil {
-				hab.dataCallback(data)
-			}
-			return nil
-		}
+func (hab *HABaseWidget) runAction(trigger func() error) {
+	hab.actionMu.Lock()
+	if hab.pending {
+		// Ignore taps while the previous call is still in flight
+		hab.actionMu.Unlock()
+		return
 	}
+	hab.pending = true
+	hab.actionErr = nil
+	hab.actionMu.Unlock()
+	hab.Invalidate()
+
+	go func() {
+		err := trigger()
+		if err != nil {
+			log.Printf("HA action for %s failed: %v", hab.EntityID, err)
+		}
 
-	return fmt.Errorf("entity %s not found", hab.EntityID)
+		hab.actionMu.Lock()
+		hab.pending = false
+		hab.actionErr = err
+		hab.actionMu.Unlock()
+		hab.Invalidate()
+	}()
 }
 
-func (hab *HABaseWidget) stopSubscription() {
-	if hab.cancelSub != nil {
-		hab.cancelSub()
+func (hab *HABaseWidget) actionState() (bool, error) {
+	hab.actionMu.Lock()
+	defer hab.actionMu.Unlock()
+	return hab.pending, hab.actionErr
+}
+
+// layoutControl draws a tappable tile with the given text, a pending marker
+// while a service call is running and the error of the last failed call.
+func (hab *HABaseWidget) layoutControl(gtx layout.Context, text string) layout.Dimensions {
+	pending, err := hab.actionState()
+	if pending {
+		text += " …"
 	}
-	if hab.subscription != nil {
-		haClient := hab.haProvider.GetHAClient()
-		if haClient != nil {
-			haClient.Unsubscribe(hab.EntityID, hab.subscription)
-		}
+
+	return material.Clickable(gtx, &hab.clickable, func(gtx layout.Context) layout.Dimensions {
+		return layout.UniformInset(unit.Dp(8)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+				layout.Rigid(material.Body1(hab.theme, text).Layout),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return hab.layoutActionError(gtx, err)
+				}),
+			)
+		})
+	})
+}
+
+func (hab *HABaseWidget) layoutActionError(gtx layout.Context, err error) layout.Dimensions {
+	if err == nil {
+		return layout.Dimensions{}
 	}
+	label := material.Caption(hab.theme, err.Error())
+	label.Color = color.NRGBA{R: 0xe5, G: 0x39, B: 0x35, A: 0xff}
+	return label.Layout(gtx)
 }
 
 func CreateHAEntityWidget(id string, config ast.Node, children []Widget, provider Provider, window *app.Window, theme *material.Theme) (Widget, error) {
@@ -234,13 +361,20 @@ func CreateHAEntityWidget(id string, config ast.Node, children []Widget, provide
 				Children: children,
 				window:   window,
 			},
-			EntityID:   haConfig.EntityID,
-			haProvider: provider,
-			provider:   provider,
-			theme:      theme,
+			EntityID: haConfig.EntityID,
+			provider: provider,
+			theme:    theme,
 		},
 	}
 
+	if haConfig.Graph != nil {
+		graph, err := newHAGraph(haConfig.Graph, theme)
+		if err != nil {
+			return nil, err
+		}
+		widget.graph = graph
+	}
+
 	// Set up the data callback
 	widget.dataCallback = widget.setDataAndInvalidate
 
@@ -269,10 +403,9 @@ func CreateHASwitchWidget(id string, config ast.Node, children []Widget, provide
 				Children: children,
 				window:   window,
 			},
-			EntityID:   haConfig.EntityID,
-			haProvider: provider,
-			provider:   provider,
-			theme:      theme,
+			EntityID: haConfig.EntityID,
+			provider: provider,
+			theme:    theme,
 		},
 	}
 
@@ -304,10 +437,9 @@ func CreateHALightWidget(id string, config ast.Node, children []Widget, provider
 				Children: children,
 				window:   window,
 			},
-			EntityID:   haConfig.EntityID,
-			haProvider: provider,
-			provider:   provider,
-			theme:      theme,
+			EntityID: haConfig.EntityID,
+			provider: provider,
+			theme:    theme,
 		},
 	}
 
@@ -352,10 +484,9 @@ func CreateHAButtonWidget(id string, config ast.Node, children []Widget, provide
 				Children: children,
 				window:   window,
 			},
-			EntityID:   haConfig.EntityID,
-			haProvider: provider,
-			provider:   provider,
-			theme:      theme,
+			EntityID: haConfig.EntityID,
+			provider: provider,
+			theme:    theme,
 		},
 		Service: haConfig.Service,
 		Domain:  haConfig.Domain,
@@ -372,23 +503,15 @@ func CreateHAButtonWidget(id string, config ast.Node, children []Widget, provide
 }
 
 func (w *HAEntityWidget) Init(ctx context.Context) error {
-	w.LastUpdate = time.Now()
+	w.SetLastUpdate(time.Now())
 	// Initialize with empty data to avoid null
-	w.data = &HAEntityData{
+	w.data.Store(&HAEntityData{
 		EntityID:    w.EntityID,
 		State:       "unknown",
 		Attributes:  make(map[string]interface{}),
 		LastChanged: time.Now(),
 		LastUpdated: time.Now(),
-	}
-
-	// Try to fetch initial state asynchronously with a short delay to avoid blocking
-	go func() {
-		time.Sleep(100 * time.Millisecond) // Brief delay to let HA client fully initialize
-		if err := w.fetchInitialState(); err != nil {
-			fmt.Printf("Failed to fetch initial state for %s: %v\n", w.EntityID, err)
-		}
-	}()
+	})
 
 	// Start subscription asynchronously to avoid blocking widget initialization
 	go func() {
@@ -396,27 +519,23 @@ func (w *HAEntityWidget) Init(ctx context.Context) error {
 			fmt.Printf("Failed to start subscription for HA widget %s: %v\n", w.EntityID, err)
 		}
 	}()
+
+	if w.graph != nil {
+		go w.loadHistory(ctx)
+	}
 	return nil
 }
 
 func (w *HASwitchWidget) Init(ctx context.Context) error {
-	w.LastUpdate = time.Now()
+	w.SetLastUpdate(time.Now())
 	// Initialize with empty data to avoid null
-	w.data = &HAEntityData{
+	w.data.Store(&HAEntityData{
 		EntityID:    w.EntityID,
 		State:       "unknown",
 		Attributes:  make(map[string]interface{}),
 		LastChanged: time.Now(),
 		LastUpdated: time.Now(),
-	}
-
-	// Try to fetch initial state asynchronously with a short delay to avoid blocking
-	go func() {
-		time.Sleep(100 * time.Millisecond) // Brief delay to let HA client fully initialize
-		if err := w.fetchInitialState(); err != nil {
-			fmt.Printf("Failed to fetch initial state for %s: %v\n", w.EntityID, err)
-		}
-	}()
+	})
 	// Start subscription asynchronously to avoid blocking widget initialization
 	go func() {
 		if err := w.startSubscription(ctx); err != nil {
@@ -427,23 +546,15 @@ func (w *HASwitchWidget) Init(ctx context.Context) error {
 }
 
 func (w *HALightWidget) Init(ctx context.Context) error {
-	w.LastUpdate = time.Now()
+	w.SetLastUpdate(time.Now())
 	// Initialize with empty data to avoid null
-	w.data = &HAEntityData{
+	w.data.Store(&HAEntityData{
 		EntityID:    w.EntityID,
 		State:       "unknown",
 		Attributes:  make(map[string]interface{}),
 		LastChanged: time.Now(),
 		LastUpdated: time.Now(),
-	}
-
-	// Try to fetch initial state asynchronously with a short delay to avoid blocking
-	go func() {
-		time.Sleep(100 * time.Millisecond) // Brief delay to let HA client fully initialize
-		if err := w.fetchInitialState(); err != nil {
-			fmt.Printf("Failed to fetch initial state for %s: %v\n", w.EntityID, err)
-		}
-	}()
+	})
 
 	// Start subscription asynchronously to avoid blocking widget initialization
 	go func() {
@@ -455,12 +566,12 @@ func (w *HALightWidget) Init(ctx context.Context) error {
 }
 
 func (w *HAButtonWidget) Init(ctx context.Context) error {
-	w.LastUpdate = time.Now()
+	w.SetLastUpdate(time.Now())
 	return nil
 }
 
 func (w *HASwitchWidget) Trigger() error {
-	haClient := w.haProvider.GetHAClient()
+	haClient := w.client()
 	if haClient == nil || !haClient.IsConnected() {
 		return fmt.Errorf("home Assistant client not connected")
 	}
@@ -473,7 +584,7 @@ func (w *HASwitchWidget) Trigger() error {
 }
 
 func (w *HALightWidget) Trigger() error {
-	haClient := w.haProvider.GetHAClient()
+	haClient := w.client()
 	if haClient == nil || !haClient.IsConnected() {
 		return fmt.Errorf("home Assistant client not connected")
 	}
@@ -486,7 +597,7 @@ func (w *HALightWidget) Trigger() error {
 }
 
 func (w *HALightWidget) SetBrightness(brightness int) error {
-	haClient := w.haProvider.GetHAClient()
+	haClient := w.client()
 	if haClient == nil || !haClient.IsConnected() {
 		return fmt.Errorf("home Assistant client not connected")
 	}
@@ -500,7 +611,7 @@ func (w *HALightWidget) SetBrightness(brightness int) error {
 }
 
 func (w *HAButtonWidget) Trigger() error {
-	haClient := w.haProvider.GetHAClient()
+	haClient := w.client()
 	if haClient == nil || !haClient.IsConnected() {
 		return fmt.Errorf("home Assistant client not connected")
 	}
@@ -519,13 +630,22 @@ func (w *HAEntityWidget) Close() error {
 
 func (w *HAEntityWidget) Layout(gtx layout.Context) layout.Dimensions {
 	text := "HA Entity"
-	if w.data != nil {
-		text = fmt.Sprintf("%s: %s", w.data.EntityID, w.data.State)
+	if data := w.data.Load(); data != nil {
+		text = fmt.Sprintf("%s: %s", data.EntityID, data.State)
 	}
 
 	th := w.theme
 	label := material.Body1(th, text)
-	return label.Layout(gtx)
+	if w.graph == nil {
+		return label.Layout(gtx)
+	}
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(label.Layout),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Top: unit.Dp(4)}.Layout(gtx, w.graph.Layout)
+		}),
+	)
 }
 
 func (w *HASwitchWidget) Close() error {
@@ -534,14 +654,14 @@ func (w *HASwitchWidget) Close() error {
 }
 
 func (w *HASwitchWidget) Layout(gtx layout.Context) layout.Dimensions {
+	w.handleClicks(gtx, w.Trigger)
+
 	text := "HA Switch"
-	if w.data != nil {
-		text = fmt.Sprintf("Switch %s: %s", w.data.EntityID, w.data.State)
+	if data := w.data.Load(); data != nil {
+		text = fmt.Sprintf("Switch %s: %s", data.EntityID, data.State)
 	}
 
-	th := w.theme
-	label := material.Body1(th, text)
-	return label.Layout(gtx)
+	return w.layoutControl(gtx, text)
 }
 
 func (w *HALightWidget) Close() error {
@@ -550,14 +670,14 @@ func (w *HALightWidget) Close() error {
 }
 
 func (w *HALightWidget) Layout(gtx layout.Context) layout.Dimensions {
+	w.handleClicks(gtx, w.Trigger)
+
 	text := "HA Light"
-	if w.data != nil {
-		text = fmt.Sprintf("Light %s: %s", w.data.EntityID, w.data.State)
+	if data := w.data.Load(); data != nil {
+		text = fmt.Sprintf("Light %s: %s", data.EntityID, data.State)
 	}
 
-	th := w.theme
-	label := material.Body1(th, text)
-	return label.Layout(gtx)
+	return w.layoutControl(gtx, text)
 }
 
 func (w *HAButtonWidget) Close() error {
@@ -565,12 +685,24 @@ func (w *HAButtonWidget) Close() error {
 }
 
 func (w *HAButtonWidget) Layout(gtx layout.Context) layout.Dimensions {
+	w.handleClicks(gtx, w.Trigger)
+
 	text := "HA Button"
 	if w.data != nil {
 		text = w.data.Label
 	}
 
+	pending, err := w.actionState()
+	if pending {
+		text += " …"
+	}
+
 	th := w.theme
-	btn := material.Button(th, nil, text)
-	return btn.Layout(gtx)
+	btn := material.Button(th, &w.clickable, text)
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(btn.Layout),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return w.layoutActionError(gtx, err)
+		}),
+	)
 }
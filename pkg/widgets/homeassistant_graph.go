@@ -0,0 +1,183 @@
+package widgets
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/widget/material"
+	"github.com/mntndev/dash/pkg/integrations"
+)
+
+const defaultGraphWindow = 24 * time.Hour
+
+// HAGraphConfig configures the history graph of an entity widget.
+type HAGraphConfig struct {
+	// Type is "line" (default) or "area".
+	Type string `yaml:"type"`
+	// Window is how far back the graph reaches, e.g. "6h". Defaults to 24h.
+	Window     string            `yaml:"window"`
+	Min        *float64          `yaml:"min"`
+	Max        *float64          `yaml:"max"`
+	Thresholds []ThresholdConfig `yaml:"thresholds"`
+}
+
+// haGraph keeps the numeric history of an entity for the last window. It is
+// seeded from Home Assistant's recorder and extended by live state changes.
+type haGraph struct {
+	window time.Duration
+	chart  Chart
+	color  color.NRGBA
+
+	mu     sync.Mutex
+	points []ChartPoint
+}
+
+func newHAGraph(cfg *HAGraphConfig, theme *material.Theme) (*haGraph, error) {
+	g := &haGraph{
+		window: defaultGraphWindow,
+		chart: Chart{
+			Min: cfg.Min,
+			Max: cfg.Max,
+		},
+		color: seriesColors[0],
+	}
+	if theme != nil {
+		g.color = theme.Palette.ContrastBg
+	}
+
+	switch cfg.Type {
+	case "", "line":
+	case "area":
+		g.chart.Area = true
+	default:
+		return nil, fmt.Errorf("unsupported graph type %q", cfg.Type)
+	}
+
+	if cfg.Window != "" {
+		window, err := time.ParseDuration(cfg.Window)
+		if err != nil {
+			return nil, fmt.Errorf("invalid graph window: %w", err)
+		}
+		if window <= 0 {
+			return nil, fmt.Errorf("graph window must be positive")
+		}
+		g.window = window
+	}
+
+	if cfg.Min != nil && cfg.Max != nil && *cfg.Min >= *cfg.Max {
+		return nil, fmt.Errorf("graph min must be less than max")
+	}
+
+	thresholds, err := parseThresholds(cfg.Thresholds)
+	if err != nil {
+		return nil, err
+	}
+	g.chart.Thresholds = thresholds
+
+	return g, nil
+}
+
+// add appends a live state. Non-numeric states such as "unavailable" and
+// states older than the newest point are skipped.
+func (g *haGraph) add(state string, at time.Time) {
+	value, err := strconv.ParseFloat(state, 64)
+	if err != nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if n := len(g.points); n > 0 && !at.After(g.points[n-1].Time) {
+		return
+	}
+	g.points = append(g.points, ChartPoint{Time: at, Value: value})
+	g.prune(time.Now())
+}
+
+// seed fills in recorded history before the first live point.
+func (g *haGraph) seed(history []integrations.HAHistoryPoint) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var points []ChartPoint
+	for _, p := range history {
+		if len(g.points) > 0 && !p.Time.Before(g.points[0].Time) {
+			break
+		}
+		value, err := strconv.ParseFloat(p.State, 64)
+		if err != nil {
+			continue
+		}
+		points = append(points, ChartPoint{Time: p.Time, Value: value})
+	}
+	g.points = append(points, g.points...)
+	g.prune(time.Now())
+}
+
+// prune drops points that scrolled out of the window, keeping the last one
+// before it so the line starts at the left edge. Must be called with g.mu
+// held.
+func (g *haGraph) prune(now time.Time) {
+	start := now.Add(-g.window)
+	drop := 0
+	for drop+1 < len(g.points) && !g.points[drop+1].Time.After(start) {
+		drop++
+	}
+	g.points = g.points[drop:]
+}
+
+func (g *haGraph) snapshot() []ChartPoint {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]ChartPoint(nil), g.points...)
+}
+
+func (g *haGraph) Layout(gtx layout.Context) layout.Dimensions {
+	now := gtx.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	chart := g.chart
+	chart.Start = now.Add(-g.window)
+	chart.End = now
+	points := g.snapshot()
+	if n := len(points); n > 0 {
+		// The last state holds until now
+		points = append(points, ChartPoint{Time: now, Value: points[n-1].Value})
+	}
+	chart.Series = []ChartSeries{{Points: points, Color: g.color}}
+	return chart.Layout(gtx)
+}
+
+// loadHistory seeds the graph once the client is connected, retrying until it
+// succeeds or ctx is done.
+func (w *HAEntityWidget) loadHistory(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if haClient := w.client(); haClient != nil && haClient.IsConnected() {
+			now := time.Now()
+			history, err := haClient.GetHistory(w.EntityID, now.Add(-w.graph.window), now)
+			if err == nil {
+				w.graph.seed(history)
+				w.Invalidate()
+				return
+			}
+			log.Printf("Failed to load history for %s: %v", w.EntityID, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package widgets
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mntndev/dash/pkg/config"
+	"github.com/mntndev/dash/pkg/events"
+	"github.com/mntndev/dash/pkg/integrations"
+	"github.com/mntndev/dash/pkg/integrations/hatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTimeout = 5 * time.Second
+
+// fakeProvider hands out integrations that can be added after widgets were
+// created, like the dashboard does during startup.
+type fakeProvider struct {
+	mu           sync.RWMutex
+	integrations map[string]integrations.Integration
+	bus          *events.Bus
+}
+
+func newFakeProvider(list ...integrations.Integration) *fakeProvider {
+	p := &fakeProvider{integrations: make(map[string]integrations.Integration)}
+	for _, integration := range list {
+		p.set(integration)
+	}
+	return p
+}
+
+func (p *fakeProvider) set(integration integrations.Integration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.integrations[integration.Name()] = integration
+}
+
+func (p *fakeProvider) GetIntegration(name string) integrations.Integration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.integrations[name]
+}
+
+func (p *fakeProvider) Events() *events.Bus {
+	return p.bus
+}
+
+func startHAClient(t *testing.T, server *hatest.Server) *integrations.HomeAssistantClient {
+	t.Helper()
+	client := integrations.NewHomeAssistantClient(&config.HomeAssistantConfig{
+		URL:   server.URL(),
+		Token: "test-token",
+	})
+	t.Cleanup(func() { _ = client.Stop() })
+	require.NoError(t, client.Start())
+	require.Eventually(t, client.IsConnected, testTimeout, 10*time.Millisecond)
+	return client
+}
+
+// watchData replaces the widget's data callback with one that forwards
+// updates to the returned channel.
+func watchData(hab *HABaseWidget) <-chan *HAEntityData {
+	updates := make(chan *HAEntityData, 10)
+	hab.dataCallback = func(data *HAEntityData) {
+		updates <- data
+	}
+	return updates
+}
+
+func waitForData(t *testing.T, updates <-chan *HAEntityData, state string) *HAEntityData {
+	t.Helper()
+	timeout := time.After(testTimeout)
+	for {
+		select {
+		case data := <-updates:
+			if data.State == state {
+				return data
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for state %q", state)
+		}
+	}
+}
+
+func TestHASwitchWidget(t *testing.T) {
+	server := hatest.NewServer(t, "test-token")
+	server.SetState("switch.fan", "off", map[string]interface{}{"friendly_name": "Fan"})
+
+	provider := newFakeProvider(startHAClient(t, server))
+
+	w, err := CreateHASwitchWidget("switch", configToNode(map[string]interface{}{
+		"entity_id": "switch.fan",
+	}), nil, provider, nil, nil)
+	require.NoError(t, err)
+	sw := w.(*HASwitchWidget)
+	updates := watchData(sw.HABaseWidget)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, sw.Init(ctx))
+
+	data := waitForData(t, updates, "off")
+	assert.Equal(t, "Fan", data.Attributes["friendly_name"])
+	require.Eventually(t, func() bool { return server.Watching("switch.fan") }, testTimeout, 10*time.Millisecond)
+
+	t.Run("state changes reach the widget", func(t *testing.T) {
+		server.SetState("switch.fan", "on", map[string]interface{}{"friendly_name": "Fan"})
+		waitForData(t, updates, "on")
+	})
+
+	t.Run("trigger toggles the switch", func(t *testing.T) {
+		require.NoError(t, sw.Trigger())
+
+		calls := server.Calls()
+		require.Len(t, calls, 1)
+		assert.Equal(t, "switch", calls[0].Domain)
+		assert.Equal(t, "toggle", calls[0].Service)
+		assert.Equal(t, "switch.fan", calls[0].Data["entity_id"])
+	})
+
+	t.Run("state survives a reconnect", func(t *testing.T) {
+		server.DropConnections()
+		server.SetState("switch.fan", "off", map[string]interface{}{"friendly_name": "Fan"})
+		waitForData(t, updates, "off")
+	})
+
+	t.Run("close unsubscribes", func(t *testing.T) {
+		require.NoError(t, sw.Close())
+		assert.Eventually(t, func() bool { return !server.Watching("switch.fan") }, testTimeout, 10*time.Millisecond)
+	})
+}
+
+func TestHAWidgetWaitsForClient(t *testing.T) {
+	server := hatest.NewServer(t, "test-token")
+	server.SetState("light.desk", "on", nil)
+
+	provider := newFakeProvider()
+
+	w, err := CreateHALightWidget("light", configToNode(map[string]interface{}{
+		"entity_id": "light.desk",
+	}), nil, provider, nil, nil)
+	require.NoError(t, err)
+	light := w.(*HALightWidget)
+	updates := watchData(light.HABaseWidget)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, light.Init(ctx))
+
+	assert.Error(t, light.Trigger(), "trigger without a client should fail")
+
+	provider.set(startHAClient(t, server))
+	waitForData(t, updates, "on")
+}
+
+func TestHAWidgetClosedWhileSubscribing(t *testing.T) {
+	server := hatest.NewServer(t, "test-token")
+	server.SetState("switch.fan", "off", nil)
+
+	provider := newFakeProvider(startHAClient(t, server))
+
+	w, err := CreateHASwitchWidget("switch", configToNode(map[string]interface{}{
+		"entity_id": "switch.fan",
+	}), nil, provider, nil, nil)
+	require.NoError(t, err)
+	sw := w.(*HASwitchWidget)
+	updates := watchData(sw.HABaseWidget)
+
+	// A reload closes the widget before the Init goroutine got its
+	// subscription
+	require.NoError(t, sw.Close())
+	require.NoError(t, sw.setupSubscription(context.Background()))
+
+	assert.Nil(t, sw.subscription)
+	assert.Never(t, func() bool { return len(updates) > 0 }, 200*time.Millisecond, 10*time.Millisecond)
+	assert.False(t, server.Watching("switch.fan"))
+}
+
+func TestHAEntityWidgetGraph(t *testing.T) {
+	server := hatest.NewServer(t, "test-token")
+	now := time.Now()
+	server.AddHistory("sensor.power", "120", now.Add(-5*time.Hour))
+	server.AddHistory("sensor.power", "150", now.Add(-2*time.Hour))
+	server.AddHistory("sensor.power", "unavailable", now.Add(-time.Hour))
+	server.SetState("sensor.power", "180", nil)
+
+	provider := newFakeProvider(startHAClient(t, server))
+
+	w, err := CreateHAEntityWidget("power", configToNode(map[string]interface{}{
+		"entity_id": "sensor.power",
+		"graph": map[string]interface{}{
+			"type":   "area",
+			"window": "3h",
+			"min":    0,
+			"thresholds": []interface{}{
+				map[string]interface{}{"value": 200},
+			},
+		},
+	}), nil, provider, nil, nil)
+	require.NoError(t, err)
+	entity := w.(*HAEntityWidget)
+	require.NotNil(t, entity.graph)
+	assert.True(t, entity.graph.chart.Area)
+	assert.Equal(t, 3*time.Hour, entity.graph.window)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, entity.Init(ctx))
+
+	values := func() []float64 {
+		var values []float64
+		for _, p := range entity.graph.snapshot() {
+			values = append(values, p.Value)
+		}
+		return values
+	}
+
+	// History before the window start collapses into its start state
+	require.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]float64{120, 150, 180}, values())
+	}, testTimeout, 10*time.Millisecond)
+
+	server.SetState("sensor.power", "175.5", nil)
+	require.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]float64{120, 150, 180, 175.5}, values())
+	}, testTimeout, 10*time.Millisecond)
+}
+
+func TestHAGraphConfigValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		graph map[string]interface{}
+	}{
+		{name: "unknown type", graph: map[string]interface{}{"type": "bar"}},
+		{name: "invalid window", graph: map[string]interface{}{"window": "soon"}},
+		{name: "negative window", graph: map[string]interface{}{"window": "-1h"}},
+		{name: "min above max", graph: map[string]interface{}{"min": 10, "max": 5}},
+		{name: "invalid threshold color", graph: map[string]interface{}{
+			"thresholds": []interface{}{map[string]interface{}{"value": 1, "color": "reddish"}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CreateHAEntityWidget("entity", configToNode(map[string]interface{}{
+				"entity_id": "sensor.power",
+				"graph":     tt.graph,
+			}), nil, newFakeProvider(), nil, nil)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -2,12 +2,17 @@ package widgets
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"gioui.org/app"
 	"gioui.org/layout"
+	"gioui.org/unit"
 	"gioui.org/widget/material"
+	"github.com/goccy/go-yaml"
 	"github.com/goccy/go-yaml/ast"
 )
 
@@ -24,11 +29,149 @@ type VStackWidget struct {
 // HFlex widget - horizontal flex layout with Flexed children
 type HFlexWidget struct {
 	*BaseWidget
+	sizes []flexSize
 }
 
 // VFlex widget - vertical flex layout with Flexed children
 type VFlexWidget struct {
 	*BaseWidget
+	sizes []flexSize
+}
+
+// FlexConfig sets the size of each child of a flex widget. Entries are
+// weights like 1 or 0.3, or fixed sizes like "120px" or "48dp". Sizes and
+// weights are the same list under two names.
+type FlexConfig struct {
+	Sizes   []interface{} `yaml:"sizes"`
+	Weights []interface{} `yaml:"weights"`
+}
+
+// flexSize is either a share of the free space or a fixed size.
+type flexSize struct {
+	weight float32
+	px     int
+	dp     unit.Dp
+}
+
+func (s flexSize) fixed() bool {
+	return s.weight == 0
+}
+
+// parseFlexSize reads a weight or a fixed "px" or "dp" size.
+func parseFlexSize(value interface{}) (flexSize, error) {
+	var weight float64
+	switch v := value.(type) {
+	case uint64:
+		weight = float64(v)
+	case int64:
+		weight = float64(v)
+	case int:
+		weight = float64(v)
+	case float64:
+		weight = v
+	case string:
+		v = strings.TrimSpace(v)
+		if px, ok := strings.CutSuffix(v, "px"); ok {
+			n, err := strconv.Atoi(strings.TrimSpace(px))
+			if err != nil || n < 0 {
+				return flexSize{}, fmt.Errorf("invalid size %q", v)
+			}
+			return flexSize{px: n}, nil
+		}
+		if dp, ok := strings.CutSuffix(v, "dp"); ok {
+			n, err := strconv.ParseFloat(strings.TrimSpace(dp), 32)
+			if err != nil || n < 0 {
+				return flexSize{}, fmt.Errorf("invalid size %q", v)
+			}
+			return flexSize{dp: unit.Dp(n)}, nil
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return flexSize{}, fmt.Errorf("invalid size %q", v)
+		}
+		weight = n
+	default:
+		return flexSize{}, fmt.Errorf("invalid size %v", value)
+	}
+
+	if weight <= 0 {
+		return flexSize{}, fmt.Errorf("weight must be positive, got %v", value)
+	}
+	return flexSize{weight: float32(weight)}, nil
+}
+
+func parseFlexConfig(config ast.Node, childCount int) ([]flexSize, error) {
+	var flexConfig FlexConfig
+	if config != nil {
+		if err := yaml.NodeToValue(config, &flexConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse flex config: %w", err)
+		}
+	}
+
+	values := flexConfig.Sizes
+	if len(flexConfig.Weights) > 0 {
+		if len(values) > 0 {
+			return nil, fmt.Errorf("use either sizes or weights, not both")
+		}
+		values = flexConfig.Weights
+	}
+	if len(values) > childCount {
+		return nil, fmt.Errorf("%d sizes configured but only %d children", len(values), childCount)
+	}
+
+	sizes := make([]flexSize, len(values))
+	for i, value := range values {
+		size, err := parseFlexSize(value)
+		if err != nil {
+			return nil, fmt.Errorf("size %d: %w", i, err)
+		}
+		sizes[i] = size
+	}
+	return sizes, nil
+}
+
+// childSize returns the size of child i. The sizes list wins over a grow
+// wrapper, anything else gets a weight of 1.
+func childSize(sizes []flexSize, i int, child Widget) flexSize {
+	if i < len(sizes) {
+		return sizes[i]
+	}
+	if grow, ok := child.(*GrowWidget); ok {
+		if size, err := parseFlexSize(grow.GetGrowValue()); err == nil {
+			return size
+		}
+	}
+	return flexSize{weight: 1}
+}
+
+// layoutFlex lays out children along axis, sharing the space left by fixed
+// size children by weight.
+func layoutFlex(gtx layout.Context, axis layout.Axis, sizes []flexSize, children []Widget) layout.Dimensions {
+	flexChildren := make([]layout.FlexChild, 0, len(children))
+	for i, child := range children {
+		size := childSize(sizes, i, child)
+		if !size.fixed() {
+			flexChildren = append(flexChildren, layout.Flexed(size.weight, childLayout(child)))
+			continue
+		}
+
+		flexChildren = append(flexChildren, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			px := size.px
+			if size.dp > 0 {
+				px = gtx.Dp(size.dp)
+			}
+			if axis == layout.Horizontal {
+				px = min(px, gtx.Constraints.Max.X)
+				gtx.Constraints.Min.X, gtx.Constraints.Max.X = px, px
+			} else {
+				px = min(px, gtx.Constraints.Max.Y)
+				gtx.Constraints.Min.Y, gtx.Constraints.Max.Y = px, px
+			}
+			return LayoutWidget(gtx, child)
+		}))
+	}
+
+	return layout.Flex{Axis: axis}.Layout(gtx, flexChildren...)
 }
 
 func CreateHStackWidget(id string, config ast.Node, children []Widget, provider Provider, window *app.Window, theme *material.Theme) (Widget, error) {
@@ -58,33 +201,65 @@ func CreateVStackWidget(id string, config ast.Node, children []Widget, provider
 }
 
 func CreateHFlexWidget(id string, config ast.Node, children []Widget, provider Provider, window *app.Window, theme *material.Theme) (Widget, error) {
+	return createHFlexWidget(id, "hflex", config, children, window)
+}
+
+// CreateHorizontalSplitWidget creates an hflex under the name used in older
+// configs.
+func CreateHorizontalSplitWidget(id string, config ast.Node, children []Widget, provider Provider, window *app.Window, theme *material.Theme) (Widget, error) {
+	return createHFlexWidget(id, "horizontal_split", config, children, window)
+}
+
+func createHFlexWidget(id, widgetType string, config ast.Node, children []Widget, window *app.Window) (Widget, error) {
+	sizes, err := parseFlexConfig(config, len(children))
+	if err != nil {
+		return nil, err
+	}
+
 	widget := &HFlexWidget{
 		BaseWidget: &BaseWidget{
 			ID:       id,
-			Type:     "hflex",
+			Type:     widgetType,
 			Config:   config,
 			Children: children,
 			window:   window,
 		},
+		sizes: sizes,
 	}
 	return widget, nil
 }
 
 func CreateVFlexWidget(id string, config ast.Node, children []Widget, provider Provider, window *app.Window, theme *material.Theme) (Widget, error) {
+	return createVFlexWidget(id, "vflex", config, children, window)
+}
+
+// CreateVerticalSplitWidget creates a vflex under the name used in older
+// configs.
+func CreateVerticalSplitWidget(id string, config ast.Node, children []Widget, provider Provider, window *app.Window, theme *material.Theme) (Widget, error) {
+	return createVFlexWidget(id, "vertical_split", config, children, window)
+}
+
+func createVFlexWidget(id, widgetType string, config ast.Node, children []Widget, window *app.Window) (Widget, error) {
+	sizes, err := parseFlexConfig(config, len(children))
+	if err != nil {
+		return nil, err
+	}
+
 	widget := &VFlexWidget{
 		BaseWidget: &BaseWidget{
 			ID:       id,
-			Type:     "vflex",
+			Type:     widgetType,
 			Config:   config,
 			Children: children,
 			window:   window,
 		},
+		sizes: sizes,
 	}
 	return widget, nil
 }
 
 func (w *HStackWidget) Init(ctx context.Context) error {
-	w.LastUpdate = time.Now()
+	w.SetLastUpdate(time.Now())
 	return nil
 }
 
@@ -94,7 +269,7 @@ func (w *HStackWidget) IsContainer() bool {
 
 func (w *HStackWidget) SetChildren(children []Widget) {
 	w.Children = children
-	w.LastUpdate = time.Now()
+	w.SetLastUpdate(time.Now())
 }
 
 func (w *HStackWidget) Close() error {
@@ -115,14 +290,14 @@ func (w *HStackWidget) Layout(gtx layout.Context) layout.Dimensions {
 	// Use Flex with Rigid children for horizontal stacking
 	var flexChildren []layout.FlexChild
 	for _, child := range children {
-		flexChildren = append(flexChildren, layout.Rigid(child.Layout))
+		flexChildren = append(flexChildren, layout.Rigid(childLayout(child)))
 	}
 
 	return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, flexChildren...)
 }
 
 func (w *VStackWidget) Init(ctx context.Context) error {
-	w.LastUpdate = time.Now()
+	w.SetLastUpdate(time.Now())
 	return nil
 }
 
@@ -132,7 +307,7 @@ func (w *VStackWidget) IsContainer() bool {
 
 func (w *VStackWidget) SetChildren(children []Widget) {
 	w.Children = children
-	w.LastUpdate = time.Now()
+	w.SetLastUpdate(time.Now())
 }
 
 func (w *VStackWidget) Close() error {
@@ -153,14 +328,14 @@ func (w *VStackWidget) Layout(gtx layout.Context) layout.Dimensions {
 	// Use Flex with Rigid children for vertical stacking
 	var flexChildren []layout.FlexChild
 	for _, child := range children {
-		flexChildren = append(flexChildren, layout.Rigid(child.Layout))
+		flexChildren = append(flexChildren, layout.Rigid(childLayout(child)))
 	}
 
 	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, flexChildren...)
 }
 
 func (w *HFlexWidget) Init(ctx context.Context) error {
-	w.LastUpdate = time.Now()
+	w.SetLastUpdate(time.Now())
 	return nil
 }
 
@@ -170,7 +345,7 @@ func (w *HFlexWidget) IsContainer() bool {
 
 func (w *HFlexWidget) SetChildren(children []Widget) {
 	w.Children = children
-	w.LastUpdate = time.Now()
+	w.SetLastUpdate(time.Now())
 }
 
 func (w *HFlexWidget) Close() error {
@@ -188,17 +363,11 @@ func (w *HFlexWidget) Layout(gtx layout.Context) layout.Dimensions {
 		return layout.Dimensions{}
 	}
 
-	// Use Flex for equal distribution horizontally
-	var flexChildren []layout.FlexChild
-	for _, child := range children {
-		flexChildren = append(flexChildren, layout.Flexed(1, child.Layout))
-	}
-
-	return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, flexChildren...)
+	return layoutFlex(gtx, layout.Horizontal, w.sizes, children)
 }
 
 func (w *VFlexWidget) Init(ctx context.Context) error {
-	w.LastUpdate = time.Now()
+	w.SetLastUpdate(time.Now())
 	return nil
 }
 
@@ -208,7 +377,7 @@ func (w *VFlexWidget) IsContainer() bool {
 
 func (w *VFlexWidget) SetChildren(children []Widget) {
 	w.Children = children
-	w.LastUpdate = time.Now()
+	w.SetLastUpdate(time.Now())
 }
 
 func (w *VFlexWidget) Close() error {
@@ -226,11 +395,5 @@ func (w *VFlexWidget) Layout(gtx layout.Context) layout.Dimensions {
 		return layout.Dimensions{}
 	}
 
-	// Use Flex for equal distribution vertically
-	var flexChildren []layout.FlexChild
-	for _, child := range children {
-		flexChildren = append(flexChildren, layout.Flexed(1, child.Layout))
-	}
-
-	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, flexChildren...)
+	return layoutFlex(gtx, layout.Vertical, w.sizes, children)
 }
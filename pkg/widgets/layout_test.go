@@ -0,0 +1,141 @@
+package widgets
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/unit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSizeWidgets(count int) []Widget {
+	children := make([]Widget, count)
+	for i := range children {
+		children[i] = &sizeWidget{BaseWidget: &BaseWidget{}}
+	}
+	return children
+}
+
+func TestParseFlexSize(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    flexSize
+		wantErr bool
+	}{
+		{value: uint64(2), want: flexSize{weight: 2}},
+		{value: 0.3, want: flexSize{weight: 0.3}},
+		{value: "1.5", want: flexSize{weight: 1.5}},
+		{value: "120px", want: flexSize{px: 120}},
+		{value: "48dp", want: flexSize{dp: 48}},
+		{value: 0.0, wantErr: true},
+		{value: int64(-1), wantErr: true},
+		{value: "-5px", wantErr: true},
+		{value: "wide", wantErr: true},
+		{value: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseFlexSize(tt.value)
+		if tt.wantErr {
+			assert.Error(t, err, "value %v", tt.value)
+			continue
+		}
+		require.NoError(t, err, "value %v", tt.value)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func TestHFlexWidgetSizes(t *testing.T) {
+	children := newSizeWidgets(3)
+	w, err := CreateHFlexWidget("hflex", configToNode(map[string]interface{}{
+		"sizes": []interface{}{"100px", 1, 3},
+	}), children, newFakeProvider(), nil, nil)
+	require.NoError(t, err)
+
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(500, 200))}
+	w.Layout(gtx)
+
+	assert.Equal(t, image.Pt(100, 200), children[0].(*sizeWidget).size)
+	assert.Equal(t, image.Pt(100, 200), children[1].(*sizeWidget).size)
+	assert.Equal(t, image.Pt(300, 200), children[2].(*sizeWidget).size)
+}
+
+func TestVFlexWidgetGrow(t *testing.T) {
+	grow := func(value interface{}, child Widget) Widget {
+		w, err := CreateGrowWidget("grow", configToNode(map[string]interface{}{"grow": value}), []Widget{child})
+		require.NoError(t, err)
+		return w
+	}
+
+	children := newSizeWidgets(3)
+	w, err := CreateVFlexWidget("vflex", nil, []Widget{
+		grow(0.5, children[0]),
+		grow("20dp", children[1]),
+		children[2],
+	}, newFakeProvider(), nil, nil)
+	require.NoError(t, err)
+
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(100, 340)),
+		Metric:      unit.Metric{PxPerDp: 2},
+	}
+	w.Layout(gtx)
+
+	assert.Equal(t, image.Pt(100, 100), children[0].(*sizeWidget).size)
+	assert.Equal(t, image.Pt(100, 40), children[1].(*sizeWidget).size)
+	assert.Equal(t, image.Pt(100, 200), children[2].(*sizeWidget).size)
+}
+
+func TestFlexSizeClamped(t *testing.T) {
+	children := newSizeWidgets(2)
+	w, err := CreateHFlexWidget("hflex", configToNode(map[string]interface{}{
+		"weights": []interface{}{"300px"},
+	}), children, newFakeProvider(), nil, nil)
+	require.NoError(t, err)
+
+	// The fixed child gets what space there is, the other none
+	gtx := layout.Context{Ops: new(op.Ops), Constraints: layout.Exact(image.Pt(200, 50))}
+	w.Layout(gtx)
+
+	assert.Equal(t, image.Pt(200, 50), children[0].(*sizeWidget).size)
+	assert.Equal(t, image.Pt(0, 50), children[1].(*sizeWidget).size)
+}
+
+func TestFlexConfigErrors(t *testing.T) {
+	_, err := CreateHFlexWidget("hflex", configToNode(map[string]interface{}{
+		"sizes":   []interface{}{1},
+		"weights": []interface{}{1},
+	}), newSizeWidgets(1), newFakeProvider(), nil, nil)
+	assert.ErrorContains(t, err, "not both")
+
+	_, err = CreateVFlexWidget("vflex", configToNode(map[string]interface{}{
+		"sizes": []interface{}{1, 2, 3},
+	}), newSizeWidgets(2), newFakeProvider(), nil, nil)
+	assert.ErrorContains(t, err, "only 2 children")
+
+	_, err = CreateVFlexWidget("vflex", configToNode(map[string]interface{}{
+		"sizes": []interface{}{"big"},
+	}), newSizeWidgets(1), newFakeProvider(), nil, nil)
+	assert.ErrorContains(t, err, "size 0")
+}
+
+func TestSplitWidgets(t *testing.T) {
+	registry := NewWidgetRegistry()
+	registerBuiltinWidgets(registry)
+
+	w, err := registry.Create("horizontal_split", "split", configToNode(map[string]interface{}{
+		"sizes": []interface{}{0.3, 0.7},
+	}), newSizeWidgets(2), newFakeProvider(), nil, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &HFlexWidget{}, w)
+	assert.Equal(t, "horizontal_split", w.GetType())
+
+	w, err = registry.Create("vertical_split", "split", nil, newSizeWidgets(2), newFakeProvider(), nil, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &VFlexWidget{}, w)
+	assert.Equal(t, "vertical_split", w.GetType())
+}
@@ -0,0 +1,360 @@
+package widgets
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log"
+	"sync"
+	"time"
+
+	"gioui.org/app"
+	"gioui.org/gesture"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/mntndev/dash/pkg/events"
+	"golang.org/x/exp/shiny/materialdesign/icons"
+)
+
+// swipeThreshold is the fraction of the width a swipe has to cover to turn
+// the page.
+const swipeThreshold = 0.2
+
+// pageIcons are the icons pages can show in the tab bar.
+var pageIcons = map[string][]byte{
+	"home":      icons.ActionHome,
+	"dashboard": icons.ActionDashboard,
+	"lights":    icons.ActionLightbulbOutline,
+	"climate":   icons.ImageWBSunny,
+	"weather":   icons.FileCloud,
+	"cameras":   icons.AVVideocam,
+	"security":  icons.HardwareSecurity,
+	"power":     icons.NotificationPower,
+	"charts":    icons.EditorShowChart,
+	"calendar":  icons.ActionEvent,
+	"news":      icons.AVFeaturedPlayList,
+	"music":     icons.AVLibraryMusic,
+	"kitchen":   icons.PlacesKitchen,
+	"network":   icons.HardwareRouter,
+	"servers":   icons.DeviceStorage,
+	"health":    icons.ActionFavorite,
+	"settings":  icons.ActionSettings,
+}
+
+type PagesConfig struct {
+	TabBar bool       `yaml:"tab_bar"`
+	Pages  []PageInfo `yaml:"pages"`
+}
+
+// PageInfo describes one child of a pages widget.
+type PageInfo struct {
+	Name string `yaml:"name" json:"name"`
+	Icon string `yaml:"icon,omitempty" json:"icon,omitempty"`
+}
+
+// PagesWidget shows one child at a time. Users turn pages by swiping
+// horizontally, with the arrow keys or through the tab bar.
+type PagesWidget struct {
+	*BaseWidget
+	provider Provider
+	theme    *material.Theme
+	pages    []PageInfo
+	icons    []*widget.Icon
+	tabBar   bool
+	tabs     []widget.Clickable
+
+	drag      gesture.Drag
+	dragStart float32
+	dragDelta float32
+
+	mu      sync.Mutex
+	current int
+}
+
+func CreatePagesWidget(id string, config ast.Node, children []Widget, provider Provider, window *app.Window, theme *material.Theme) (Widget, error) {
+	var pagesConfig PagesConfig
+	if config != nil {
+		if err := yaml.NodeToValue(config, &pagesConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse pages config: %w", err)
+		}
+	}
+
+	if len(children) == 0 {
+		return nil, fmt.Errorf("pages widget must have at least one child")
+	}
+	if len(pagesConfig.Pages) > len(children) {
+		return nil, fmt.Errorf("%d pages configured but only %d children", len(pagesConfig.Pages), len(children))
+	}
+
+	// Children without an entry are numbered
+	pages := make([]PageInfo, len(children))
+	copy(pages, pagesConfig.Pages)
+	pageIconList := make([]*widget.Icon, len(children))
+	for i := range pages {
+		if pages[i].Name == "" {
+			pages[i].Name = fmt.Sprintf("Page %d", i+1)
+		}
+		if pages[i].Icon == "" {
+			continue
+		}
+		data, exists := pageIcons[pages[i].Icon]
+		if !exists {
+			return nil, fmt.Errorf("page %s: unknown icon %q", pages[i].Name, pages[i].Icon)
+		}
+		icon, err := widget.NewIcon(data)
+		if err != nil {
+			return nil, fmt.Errorf("page %s: failed to load icon: %w", pages[i].Name, err)
+		}
+		pageIconList[i] = icon
+	}
+
+	return &PagesWidget{
+		BaseWidget: &BaseWidget{
+			ID:       id,
+			Type:     "pages",
+			Config:   config,
+			Children: children,
+			window:   window,
+		},
+		provider: provider,
+		theme:    theme,
+		pages:    pages,
+		icons:    pageIconList,
+		tabBar:   pagesConfig.TabBar,
+		tabs:     make([]widget.Clickable, len(children)),
+	}, nil
+}
+
+func (w *PagesWidget) Init(ctx context.Context) error {
+	w.SetLastUpdate(time.Now())
+	return nil
+}
+
+func (w *PagesWidget) IsContainer() bool {
+	return true
+}
+
+func (w *PagesWidget) SetChildren(children []Widget) {
+	w.Children = children
+	w.SetLastUpdate(time.Now())
+}
+
+func (w *PagesWidget) Close() error {
+	for _, child := range w.Children {
+		if err := child.Close(); err != nil {
+			log.Printf("Failed to close child widget: %v", err)
+		}
+	}
+	return nil
+}
+
+// Pages returns the name and icon of every page.
+func (w *PagesWidget) Pages() []PageInfo {
+	return append([]PageInfo(nil), w.pages...)
+}
+
+// CurrentPage returns the index of the page on screen.
+func (w *PagesWidget) CurrentPage() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// SetPage switches to the page at index. It is safe to call from any
+// goroutine.
+func (w *PagesWidget) SetPage(index int) error {
+	if index < 0 || index >= len(w.pages) {
+		return fmt.Errorf("page %d out of range, have %d pages", index, len(w.pages))
+	}
+
+	w.mu.Lock()
+	changed := w.current != index
+	w.current = index
+	w.mu.Unlock()
+
+	w.Invalidate()
+	if changed {
+		w.publishPage(index)
+	}
+	return nil
+}
+
+// turn moves delta pages forward or back, stopping at the first and last
+// page.
+func (w *PagesWidget) turn(delta int) {
+	w.mu.Lock()
+	previous := w.current
+	w.current = max(0, min(len(w.pages)-1, w.current+delta))
+	current := w.current
+	w.mu.Unlock()
+
+	if current != previous {
+		w.publishPage(current)
+	}
+}
+
+// publishPage announces the page on screen on the event bus.
+func (w *PagesWidget) publishPage(index int) {
+	publish(w.provider, events.PageChanged{Index: index, Name: w.pages[index].Name})
+}
+
+func (w *PagesWidget) Layout(gtx layout.Context) layout.Dimensions {
+	w.handleKeys(gtx)
+	for i := range w.tabs {
+		if w.tabs[i].Clicked(gtx) {
+			_ = w.SetPage(i)
+		}
+	}
+
+	if !w.tabBar {
+		return w.layoutPages(gtx)
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Flexed(1, w.layoutPages),
+		layout.Rigid(w.layoutTabBar),
+	)
+}
+
+func (w *PagesWidget) handleKeys(gtx layout.Context) {
+	for {
+		ev, ok := gtx.Event(
+			key.Filter{Name: key.NameLeftArrow},
+			key.Filter{Name: key.NameRightArrow},
+		)
+		if !ok {
+			return
+		}
+		e, ok := ev.(key.Event)
+		if !ok || e.State != key.Press {
+			continue
+		}
+		if e.Name == key.NameLeftArrow {
+			w.turn(-1)
+		} else {
+			w.turn(1)
+		}
+	}
+}
+
+// layoutPages draws the current page. While a swipe is in progress the page
+// follows the finger with its neighbour sliding in next to it.
+func (w *PagesWidget) layoutPages(gtx layout.Context) layout.Dimensions {
+	size := gtx.Constraints.Max
+	w.handleDrag(gtx, size.X)
+
+	children := w.GetChildren()
+	current := min(w.CurrentPage(), len(children)-1)
+
+	offset := int(w.dragDelta)
+	if (offset > 0 && current == 0) || (offset < 0 && current == len(children)-1) {
+		// Nothing to reveal, resist the drag
+		offset /= 4
+	}
+
+	defer clip.Rect{Max: size}.Push(gtx.Ops).Pop()
+	w.drag.Add(gtx.Ops)
+
+	w.layoutPage(gtx, children[current], offset, size)
+	if offset > 0 && current > 0 {
+		w.layoutPage(gtx, children[current-1], offset-size.X, size)
+	}
+	if offset < 0 && current < len(children)-1 {
+		w.layoutPage(gtx, children[current+1], offset+size.X, size)
+	}
+
+	return layout.Dimensions{Size: size}
+}
+
+func (w *PagesWidget) layoutPage(gtx layout.Context, page Widget, x int, size image.Point) {
+	defer op.Offset(image.Pt(x, 0)).Push(gtx.Ops).Pop()
+	gtx.Constraints = layout.Exact(size)
+	LayoutWidget(gtx, page)
+}
+
+func (w *PagesWidget) handleDrag(gtx layout.Context, width int) {
+	for {
+		e, ok := w.drag.Update(gtx.Metric, gtx.Source, gesture.Horizontal)
+		if !ok {
+			return
+		}
+
+		switch e.Kind {
+		case pointer.Press:
+			w.dragStart = e.Position.X
+			w.dragDelta = 0
+		case pointer.Drag:
+			w.dragDelta = e.Position.X - w.dragStart
+		case pointer.Release:
+			threshold := float32(width) * swipeThreshold
+			if w.dragDelta > threshold {
+				w.turn(-1)
+			} else if w.dragDelta < -threshold {
+				w.turn(1)
+			}
+			w.dragDelta = 0
+		case pointer.Cancel:
+			w.dragDelta = 0
+		}
+	}
+}
+
+func (w *PagesWidget) layoutTabBar(gtx layout.Context) layout.Dimensions {
+	current := w.CurrentPage()
+	tabs := make([]layout.FlexChild, len(w.pages))
+	for i := range w.pages {
+		tabs[i] = layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return w.layoutTab(gtx, i, i == current)
+		})
+	}
+	return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, tabs...)
+}
+
+func (w *PagesWidget) layoutTab(gtx layout.Context, index int, selected bool) layout.Dimensions {
+	fg := w.theme.Fg
+	if selected {
+		fg = w.theme.ContrastFg
+	}
+
+	return w.tabs[index].Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		return layout.Background{}.Layout(gtx,
+			func(gtx layout.Context) layout.Dimensions {
+				if selected {
+					defer clip.Rect{Max: gtx.Constraints.Min}.Push(gtx.Ops).Pop()
+					paint.Fill(gtx.Ops, w.theme.ContrastBg)
+				}
+				return layout.Dimensions{Size: gtx.Constraints.Min}
+			},
+			func(gtx layout.Context) layout.Dimensions {
+				gtx.Constraints.Min.X = gtx.Constraints.Max.X
+				return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+					return layout.Flex{Axis: layout.Horizontal, Spacing: layout.SpaceSides, Alignment: layout.Middle}.Layout(gtx,
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							icon := w.icons[index]
+							if icon == nil {
+								return layout.Dimensions{}
+							}
+							return layout.Inset{Right: unit.Dp(6)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+								gtx.Constraints.Min.X = gtx.Dp(unit.Dp(20))
+								return icon.Layout(gtx, fg)
+							})
+						}),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							label := material.Body1(w.theme, w.pages[index].Name)
+							label.Color = fg
+							label.MaxLines = 1
+							return label.Layout(gtx)
+						}),
+					)
+				})
+			})
+	})
+}
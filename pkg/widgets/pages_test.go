@@ -0,0 +1,141 @@
+package widgets
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/io/input"
+	"gioui.org/io/key"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/widget/material"
+	"github.com/mntndev/dash/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPages(t *testing.T, config map[string]interface{}, count int) *PagesWidget {
+	t.Helper()
+	children := make([]Widget, count)
+	for i := range children {
+		children[i] = &BaseWidget{ID: "page", Type: "test"}
+	}
+	w, err := CreatePagesWidget("pages", configToNode(config), children, newFakeProvider(), nil, material.NewTheme())
+	require.NoError(t, err)
+	return w.(*PagesWidget)
+}
+
+// layoutPages runs one frame of w with the queued input.
+func layoutPages(router *input.Router, w *PagesWidget) {
+	var ops op.Ops
+	gtx := layout.Context{
+		Ops:         &ops,
+		Constraints: layout.Exact(image.Pt(400, 300)),
+		Source:      router.Source(),
+	}
+	w.Layout(gtx)
+	router.Frame(gtx.Ops)
+}
+
+func TestCreatePagesWidget(t *testing.T) {
+	w := newTestPages(t, map[string]interface{}{
+		"tab_bar": true,
+		"pages": []interface{}{
+			map[string]interface{}{"name": "Lighting", "icon": "lights"},
+		},
+	}, 2)
+
+	assert.Equal(t, []PageInfo{{Name: "Lighting", Icon: "lights"}, {Name: "Page 2"}}, w.Pages())
+	assert.NotNil(t, w.icons[0])
+	assert.Nil(t, w.icons[1])
+	assert.True(t, w.tabBar)
+
+	_, err := CreatePagesWidget("pages", configToNode(map[string]interface{}{
+		"pages": []interface{}{map[string]interface{}{"name": "A", "icon": "no-such-icon"}},
+	}), []Widget{&BaseWidget{}}, newFakeProvider(), nil, nil)
+	assert.ErrorContains(t, err, "unknown icon")
+
+	_, err = CreatePagesWidget("pages", nil, nil, newFakeProvider(), nil, nil)
+	assert.Error(t, err)
+}
+
+func TestPagesWidgetSetPage(t *testing.T) {
+	w := newTestPages(t, nil, 3)
+
+	require.NoError(t, w.SetPage(2))
+	assert.Equal(t, 2, w.CurrentPage())
+	assert.Error(t, w.SetPage(3))
+	assert.Error(t, w.SetPage(-1))
+	assert.Equal(t, 2, w.CurrentPage())
+
+	// Turning stops at the last page
+	w.turn(1)
+	assert.Equal(t, 2, w.CurrentPage())
+	w.turn(-5)
+	assert.Equal(t, 0, w.CurrentPage())
+}
+
+func TestPagesWidgetPublishesPageChanges(t *testing.T) {
+	provider := newFakeProvider()
+	provider.bus = events.NewBus()
+	defer provider.bus.Close()
+	sub := events.Subscribe[events.PageChanged](provider.bus, 4)
+
+	children := []Widget{&BaseWidget{ID: "a", Type: "test"}, &BaseWidget{ID: "b", Type: "test"}}
+	w, err := CreatePagesWidget("pages", configToNode(map[string]interface{}{
+		"pages": []interface{}{map[string]interface{}{"name": "Lighting"}},
+	}), children, provider, nil, material.NewTheme())
+	require.NoError(t, err)
+	pages := w.(*PagesWidget)
+
+	require.NoError(t, pages.SetPage(1))
+	require.NoError(t, pages.SetPage(1))
+	pages.turn(-1)
+	pages.turn(-1)
+
+	require.Len(t, sub.C(), 2, "only actual changes are published")
+	assert.Equal(t, events.PageChanged{Index: 1, Name: "Page 2"}, <-sub.C())
+	assert.Equal(t, events.PageChanged{Index: 0, Name: "Lighting"}, <-sub.C())
+}
+
+func TestPagesWidgetSwipe(t *testing.T) {
+	w := newTestPages(t, nil, 3)
+	router := new(input.Router)
+	layoutPages(router, w)
+
+	swipe := func(fromX, toX float32) {
+		router.Queue(
+			pointer.Event{Kind: pointer.Press, Source: pointer.Touch, Position: f32.Pt(fromX, 150)},
+			pointer.Event{Kind: pointer.Move, Source: pointer.Touch, Position: f32.Pt((fromX+toX)/2, 150)},
+			pointer.Event{Kind: pointer.Move, Source: pointer.Touch, Position: f32.Pt(toX, 150)},
+			pointer.Event{Kind: pointer.Release, Source: pointer.Touch, Position: f32.Pt(toX, 150)},
+		)
+		layoutPages(router, w)
+	}
+
+	swipe(300, 100)
+	assert.Equal(t, 1, w.CurrentPage())
+
+	// Short drags snap back
+	swipe(300, 260)
+	assert.Equal(t, 1, w.CurrentPage())
+
+	swipe(100, 300)
+	assert.Equal(t, 0, w.CurrentPage())
+}
+
+func TestPagesWidgetArrowKeys(t *testing.T) {
+	w := newTestPages(t, nil, 2)
+	router := new(input.Router)
+	layoutPages(router, w)
+
+	router.Queue(key.Event{Name: key.NameRightArrow, State: key.Press})
+	layoutPages(router, w)
+	assert.Equal(t, 1, w.CurrentPage())
+
+	router.Queue(key.Event{Name: key.NameLeftArrow, State: key.Press})
+	layoutPages(router, w)
+	assert.Equal(t, 0, w.CurrentPage())
+}
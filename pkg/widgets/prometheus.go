@@ -0,0 +1,621 @@
+package widgets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"gioui.org/app"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/mntndev/dash/pkg/events"
+	"github.com/mntndev/dash/pkg/integrations"
+)
+
+const (
+	defaultPrometheusRefresh = 30 * time.Second
+	defaultPrometheusWindow  = time.Hour
+	// prometheusGraphPoints is the resolution of graphs without a step.
+	prometheusGraphPoints = 240
+)
+
+// PrometheusQueryConfig holds the settings shared by all Prometheus widgets.
+type PrometheusQueryConfig struct {
+	Query string `yaml:"query"`
+	// RefreshInterval is how often the query runs, e.g. "15s". Defaults to 30s.
+	RefreshInterval string `yaml:"refresh_interval"`
+	Label           string `yaml:"label"`
+	Unit            string `yaml:"unit"`
+	// Decimals is the number of decimals shown. Defaults to 2.
+	Decimals *int `yaml:"decimals"`
+}
+
+type PrometheusGaugeConfig struct {
+	PrometheusQueryConfig `yaml:",inline"`
+	// Min and Max are the ends of the gauge. They default to 0 and 100.
+	Min        *float64          `yaml:"min"`
+	Max        *float64          `yaml:"max"`
+	Thresholds []ThresholdConfig `yaml:"thresholds"`
+}
+
+type PrometheusGraphConfig struct {
+	PrometheusQueryConfig `yaml:",inline"`
+	// Type is "line" (default) or "area".
+	Type string `yaml:"type"`
+	// Window is how far back the graph reaches. Defaults to 1h.
+	Window string `yaml:"window"`
+	// Step is the query resolution. Defaults to the window split into 240
+	// points.
+	Step       string            `yaml:"step"`
+	Min        *float64          `yaml:"min"`
+	Max        *float64          `yaml:"max"`
+	Thresholds []ThresholdConfig `yaml:"thresholds"`
+}
+
+// PrometheusData is the result of a Prometheus widget's last query.
+type PrometheusData struct {
+	Query string `json:"query"`
+	// Value is the result of stat and gauge widgets, nil if the query
+	// returned nothing.
+	Value *float64 `json:"value,omitempty"`
+	// Series is the result of graph widgets.
+	Series  []PrometheusSeriesData `json:"series,omitempty"`
+	Unit    string                 `json:"unit,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+	Updated time.Time              `json:"updated"`
+}
+
+// PrometheusSeriesData is one series of a graph.
+type PrometheusSeriesData struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Points []ChartPoint      `json:"points"`
+}
+
+// prometheusBase runs a widget's query on its refresh interval and keeps the
+// error of the last run.
+type prometheusBase struct {
+	*BaseWidget
+	provider Provider
+	theme    *material.Theme
+	query    string
+	label    string
+	unit     string
+	decimals int
+	interval time.Duration
+
+	mu  sync.Mutex
+	err error
+	// fixture is shown instead of the query results if set.
+	fixture *PrometheusData
+}
+
+type PrometheusStatWidget struct {
+	*prometheusBase
+	value *float64
+}
+
+type PrometheusGaugeWidget struct {
+	*prometheusBase
+	min, max   float64
+	thresholds []ChartThreshold
+	value      *float64
+}
+
+type PrometheusGraphWidget struct {
+	*prometheusBase
+	window time.Duration
+	step   time.Duration
+	chart  Chart
+	series []ChartSeries
+	result []PrometheusSeriesData
+}
+
+func newPrometheusBase(id, widgetType string, cfg PrometheusQueryConfig, config ast.Node, children []Widget, provider Provider, window *app.Window, theme *material.Theme) (*prometheusBase, error) {
+	if cfg.Query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+
+	interval := defaultPrometheusRefresh
+	if cfg.RefreshInterval != "" {
+		parsed, err := time.ParseDuration(cfg.RefreshInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid refresh_interval: %w", err)
+		}
+		if parsed <= 0 {
+			return nil, fmt.Errorf("refresh_interval must be positive")
+		}
+		interval = parsed
+	}
+
+	decimals := 2
+	if cfg.Decimals != nil {
+		if *cfg.Decimals < 0 {
+			return nil, fmt.Errorf("decimals must not be negative")
+		}
+		decimals = *cfg.Decimals
+	}
+
+	return &prometheusBase{
+		BaseWidget: &BaseWidget{
+			ID:       id,
+			Type:     widgetType,
+			Config:   config,
+			Children: children,
+			window:   window,
+		},
+		provider: provider,
+		theme:    theme,
+		query:    cfg.Query,
+		label:    cfg.Label,
+		unit:     cfg.Unit,
+		decimals: decimals,
+		interval: interval,
+	}, nil
+}
+
+func (b *prometheusBase) client() *integrations.PrometheusClient {
+	return getIntegration[*integrations.PrometheusClient](b.provider, integrations.PrometheusName)
+}
+
+// poll runs refresh right away and then on every tick until ctx is done.
+// After every run it publishes what data returns.
+func (b *prometheusBase) poll(ctx context.Context, refresh func(context.Context, *integrations.PrometheusClient) error, data func() interface{}) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+		if client := b.client(); client == nil {
+			err = fmt.Errorf("prometheus client not available")
+		} else {
+			queryCtx, cancel := context.WithTimeout(ctx, b.interval)
+			err = refresh(queryCtx, client)
+			cancel()
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Prometheus query for %s failed: %v", b.ID, err)
+		}
+
+		b.mu.Lock()
+		b.err = err
+		b.SetLastUpdate(time.Now())
+		b.mu.Unlock()
+		b.Invalidate()
+		publish(b.provider, events.WidgetData{ID: b.ID, Type: b.Type, Data: data()})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// data describes the last run of the query, or returns the fixture. The
+// caller must hold mu.
+func (b *prometheusBase) data() *PrometheusData {
+	if b.fixture != nil {
+		return b.fixture
+	}
+	data := &PrometheusData{Query: b.query, Unit: b.unit, Updated: b.GetLastUpdate()}
+	if b.err != nil {
+		data.Error = b.err.Error()
+	}
+	return data
+}
+
+// dataErr returns the error data reports.
+func dataErr(data *PrometheusData) error {
+	if data.Error == "" {
+		return nil
+	}
+	return errors.New(data.Error)
+}
+
+// LoadFixture shows data instead of the results of the query.
+func (b *prometheusBase) LoadFixture(raw json.RawMessage) error {
+	data := &PrometheusData{Query: b.query, Unit: b.unit}
+	if err := json.Unmarshal(raw, data); err != nil {
+		return fmt.Errorf("invalid prometheus fixture: %w", err)
+	}
+
+	b.mu.Lock()
+	b.fixture = data
+	b.mu.Unlock()
+	b.Invalidate()
+	return nil
+}
+
+func (b *prometheusBase) format(value float64) string {
+	text := strconv.FormatFloat(value, 'f', b.decimals, 64)
+	if b.unit != "" {
+		text += " " + b.unit
+	}
+	return text
+}
+
+// instantValue runs the widget's query and returns the value of its first
+// series, or nil if the query returned nothing.
+func (b *prometheusBase) instantValue(ctx context.Context, client *integrations.PrometheusClient) (*float64, error) {
+	samples, err := client.Query(ctx, b.query, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, nil
+	}
+	value := samples[0].Value
+	return &value, nil
+}
+
+func (b *prometheusBase) layoutLabel(gtx layout.Context) layout.Dimensions {
+	if b.label == "" {
+		return layout.Dimensions{}
+	}
+	return material.Caption(b.theme, b.label).Layout(gtx)
+}
+
+func (b *prometheusBase) layoutError(gtx layout.Context, err error) layout.Dimensions {
+	if err == nil {
+		return layout.Dimensions{}
+	}
+	label := material.Caption(b.theme, err.Error())
+	label.Color = defaultThresholdColor
+	return label.Layout(gtx)
+}
+
+func CreatePrometheusStatWidget(id string, config ast.Node, children []Widget, provider Provider, window *app.Window, theme *material.Theme) (Widget, error) {
+	var statConfig PrometheusQueryConfig
+	if config != nil {
+		if err := yaml.NodeToValue(config, &statConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse prometheus stat config: %w", err)
+		}
+	}
+
+	base, err := newPrometheusBase(id, "prometheus.stat", statConfig, config, children, provider, window, theme)
+	if err != nil {
+		return nil, err
+	}
+	return &PrometheusStatWidget{prometheusBase: base}, nil
+}
+
+func (w *PrometheusStatWidget) Init(ctx context.Context) error {
+	w.SetLastUpdate(time.Now())
+	go w.poll(ctx, w.refresh, w.GetData)
+	return nil
+}
+
+func (w *PrometheusStatWidget) refresh(ctx context.Context, client *integrations.PrometheusClient) error {
+	value, err := w.instantValue(ctx, client)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.value = value
+	w.mu.Unlock()
+	return nil
+}
+
+// GetData returns the value of the last query.
+func (w *PrometheusStatWidget) GetData() interface{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	data := w.data()
+	if w.fixture == nil {
+		data.Value = w.value
+	}
+	return data
+}
+
+func (w *PrometheusStatWidget) Layout(gtx layout.Context) layout.Dimensions {
+	w.mu.Lock()
+	value, err := w.value, w.err
+	if w.fixture != nil {
+		value, err = w.fixture.Value, dataErr(w.fixture)
+	}
+	w.mu.Unlock()
+
+	text := "No data"
+	if value != nil {
+		text = w.format(*value)
+	}
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(w.layoutLabel),
+		layout.Rigid(material.H3(w.theme, text).Layout),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return w.layoutError(gtx, err)
+		}),
+	)
+}
+
+func CreatePrometheusGaugeWidget(id string, config ast.Node, children []Widget, provider Provider, window *app.Window, theme *material.Theme) (Widget, error) {
+	var gaugeConfig PrometheusGaugeConfig
+	if config != nil {
+		if err := yaml.NodeToValue(config, &gaugeConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse prometheus gauge config: %w", err)
+		}
+	}
+
+	base, err := newPrometheusBase(id, "prometheus.gauge", gaugeConfig.PrometheusQueryConfig, config, children, provider, window, theme)
+	if err != nil {
+		return nil, err
+	}
+
+	widget := &PrometheusGaugeWidget{prometheusBase: base, min: 0, max: 100}
+	if gaugeConfig.Min != nil {
+		widget.min = *gaugeConfig.Min
+	}
+	if gaugeConfig.Max != nil {
+		widget.max = *gaugeConfig.Max
+	}
+	if widget.min >= widget.max {
+		return nil, fmt.Errorf("gauge min must be less than max")
+	}
+
+	widget.thresholds, err = parseThresholds(gaugeConfig.Thresholds)
+	if err != nil {
+		return nil, err
+	}
+
+	return widget, nil
+}
+
+func (w *PrometheusGaugeWidget) Init(ctx context.Context) error {
+	w.SetLastUpdate(time.Now())
+	go w.poll(ctx, w.refresh, w.GetData)
+	return nil
+}
+
+func (w *PrometheusGaugeWidget) refresh(ctx context.Context, client *integrations.PrometheusClient) error {
+	value, err := w.instantValue(ctx, client)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.value = value
+	w.mu.Unlock()
+	return nil
+}
+
+// fillColor returns the color of the highest threshold value has reached.
+func (w *PrometheusGaugeWidget) fillColor(value float64) color.NRGBA {
+	fill := seriesColors[0]
+	if w.theme != nil {
+		fill = w.theme.Palette.ContrastBg
+	}
+
+	var reached *ChartThreshold
+	for i := range w.thresholds {
+		threshold := &w.thresholds[i]
+		if value >= threshold.Value && (reached == nil || threshold.Value > reached.Value) {
+			reached = threshold
+		}
+	}
+	if reached != nil {
+		fill = reached.Color
+	}
+	return fill
+}
+
+// GetData returns the value of the last query.
+func (w *PrometheusGaugeWidget) GetData() interface{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	data := w.data()
+	if w.fixture == nil {
+		data.Value = w.value
+	}
+	return data
+}
+
+func (w *PrometheusGaugeWidget) Layout(gtx layout.Context) layout.Dimensions {
+	w.mu.Lock()
+	value, err := w.value, w.err
+	if w.fixture != nil {
+		value, err = w.fixture.Value, dataErr(w.fixture)
+	}
+	w.mu.Unlock()
+
+	text := "No data"
+	if value != nil {
+		text = w.format(*value)
+	}
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(w.layoutLabel),
+		layout.Rigid(material.H4(w.theme, text).Layout),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Top: unit.Dp(4)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				return w.layoutBar(gtx, value)
+			})
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return w.layoutError(gtx, err)
+		}),
+	)
+}
+
+func (w *PrometheusGaugeWidget) layoutBar(gtx layout.Context, value *float64) layout.Dimensions {
+	size := image.Pt(gtx.Constraints.Max.X, gtx.Dp(12))
+	radius := size.Y / 2
+
+	track := w.theme.Palette.Fg
+	track.A = 0x30
+	paint.FillShape(gtx.Ops, track, clip.UniformRRect(image.Rectangle{Max: size}, radius).Op(gtx.Ops))
+
+	if value != nil {
+		fraction := (*value - w.min) / (w.max - w.min)
+		fraction = max(0, min(1, fraction))
+		filled := image.Pt(int(float64(size.X)*fraction), size.Y)
+		paint.FillShape(gtx.Ops, w.fillColor(*value), clip.UniformRRect(image.Rectangle{Max: filled}, radius).Op(gtx.Ops))
+	}
+
+	return layout.Dimensions{Size: size}
+}
+
+func CreatePrometheusGraphWidget(id string, config ast.Node, children []Widget, provider Provider, window *app.Window, theme *material.Theme) (Widget, error) {
+	var graphConfig PrometheusGraphConfig
+	if config != nil {
+		if err := yaml.NodeToValue(config, &graphConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse prometheus graph config: %w", err)
+		}
+	}
+
+	base, err := newPrometheusBase(id, "prometheus.graph", graphConfig.PrometheusQueryConfig, config, children, provider, window, theme)
+	if err != nil {
+		return nil, err
+	}
+
+	widget := &PrometheusGraphWidget{
+		prometheusBase: base,
+		window:         defaultPrometheusWindow,
+		chart:          Chart{Min: graphConfig.Min, Max: graphConfig.Max},
+	}
+
+	switch graphConfig.Type {
+	case "", "line":
+	case "area":
+		widget.chart.Area = true
+	default:
+		return nil, fmt.Errorf("unsupported graph type %q", graphConfig.Type)
+	}
+
+	if graphConfig.Window != "" {
+		widget.window, err = time.ParseDuration(graphConfig.Window)
+		if err != nil {
+			return nil, fmt.Errorf("invalid graph window: %w", err)
+		}
+		if widget.window <= 0 {
+			return nil, fmt.Errorf("graph window must be positive")
+		}
+	}
+
+	widget.step = max(widget.window/prometheusGraphPoints, time.Second)
+	if graphConfig.Step != "" {
+		widget.step, err = time.ParseDuration(graphConfig.Step)
+		if err != nil {
+			return nil, fmt.Errorf("invalid graph step: %w", err)
+		}
+		if widget.step <= 0 {
+			return nil, fmt.Errorf("graph step must be positive")
+		}
+	}
+
+	if graphConfig.Min != nil && graphConfig.Max != nil && *graphConfig.Min >= *graphConfig.Max {
+		return nil, fmt.Errorf("graph min must be less than max")
+	}
+
+	widget.chart.Thresholds, err = parseThresholds(graphConfig.Thresholds)
+	if err != nil {
+		return nil, err
+	}
+
+	return widget, nil
+}
+
+func (w *PrometheusGraphWidget) Init(ctx context.Context) error {
+	w.SetLastUpdate(time.Now())
+	go w.poll(ctx, w.refresh, w.GetData)
+	return nil
+}
+
+func (w *PrometheusGraphWidget) refresh(ctx context.Context, client *integrations.PrometheusClient) error {
+	end := time.Now()
+	result, err := client.QueryRange(ctx, w.query, end.Add(-w.window), end, w.step)
+	if err != nil {
+		return err
+	}
+
+	data := make([]PrometheusSeriesData, 0, len(result))
+	for _, s := range result {
+		points := make([]ChartPoint, 0, len(s.Points))
+		for _, p := range s.Points {
+			points = append(points, ChartPoint{Time: p.Time, Value: p.Value})
+		}
+		data = append(data, PrometheusSeriesData{Labels: s.Labels, Points: points})
+	}
+
+	w.mu.Lock()
+	w.series = chartSeries(data)
+	w.result = data
+	w.mu.Unlock()
+	return nil
+}
+
+// chartSeries gives every series its own color.
+func chartSeries(data []PrometheusSeriesData) []ChartSeries {
+	series := make([]ChartSeries, len(data))
+	for i, s := range data {
+		series[i] = ChartSeries{Points: s.Points, Color: seriesColors[i%len(seriesColors)]}
+	}
+	return series
+}
+
+// GetData returns the series of the last query.
+func (w *PrometheusGraphWidget) GetData() interface{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	data := w.data()
+	if w.fixture == nil {
+		data.Series = w.result
+	}
+	return data
+}
+
+func (w *PrometheusGraphWidget) Layout(gtx layout.Context) layout.Dimensions {
+	w.mu.Lock()
+	series, err := w.series, w.err
+	if w.fixture != nil {
+		series, err = chartSeries(w.fixture.Series), dataErr(w.fixture)
+	}
+	w.mu.Unlock()
+
+	title := w.label
+	if len(series) == 1 {
+		if points := series[0].Points; len(points) > 0 {
+			current := w.format(points[len(points)-1].Value)
+			if title == "" {
+				title = current
+			} else {
+				title += ": " + current
+			}
+		}
+	}
+
+	now := gtx.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	chart := w.chart
+	chart.Start = now.Add(-w.window)
+	chart.End = now
+	chart.Series = series
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if title == "" {
+				return layout.Dimensions{}
+			}
+			return material.Caption(w.theme, title).Layout(gtx)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return w.layoutError(gtx, err)
+		}),
+		layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+			return layout.Inset{Top: unit.Dp(4)}.Layout(gtx, chart.Layout)
+		}),
+	)
+}
@@ -0,0 +1,147 @@
+package widgets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mntndev/dash/pkg/config"
+	"github.com/mntndev/dash/pkg/events"
+	"github.com/mntndev/dash/pkg/integrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPrometheusProvider(t *testing.T, response string) *fakeProvider {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	return newFakeProvider(integrations.NewPrometheusClient(&config.PrometheusConfig{URL: server.URL}))
+}
+
+func TestPrometheusStatWidget(t *testing.T) {
+	provider := newPrometheusProvider(t, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1700000000,"73.456"]}]}}`)
+
+	w, err := CreatePrometheusStatWidget("stat", configToNode(map[string]interface{}{
+		"query":    "node_load1",
+		"unit":     "W",
+		"decimals": 1,
+	}), nil, provider, nil, nil)
+	require.NoError(t, err)
+	stat := w.(*PrometheusStatWidget)
+	provider.bus = events.NewBus()
+	defer provider.bus.Close()
+	updates := events.Subscribe[events.WidgetData](provider.bus, 8)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, stat.Init(ctx))
+
+	select {
+	case update := <-updates.C():
+		assert.Equal(t, "stat", update.ID)
+		assert.Equal(t, "prometheus.stat", update.Type)
+		assert.NotNil(t, update.Data.(*PrometheusData).Value)
+	case <-time.After(testTimeout):
+		t.Fatal("no widget data published")
+	}
+
+	require.Eventually(t, func() bool {
+		stat.mu.Lock()
+		defer stat.mu.Unlock()
+		return stat.value != nil
+	}, testTimeout, 10*time.Millisecond)
+
+	data := stat.GetData().(*PrometheusData)
+	assert.Equal(t, "node_load1", data.Query)
+	assert.Equal(t, "W", data.Unit)
+	assert.Empty(t, data.Error)
+	require.NotNil(t, data.Value)
+	assert.InDelta(t, 73.456, *data.Value, 1e-9)
+
+	stat.mu.Lock()
+	defer stat.mu.Unlock()
+	assert.NoError(t, stat.err)
+	assert.Equal(t, "73.5 W", stat.format(*stat.value))
+}
+
+func TestPrometheusGraphWidget(t *testing.T) {
+	provider := newPrometheusProvider(t, `{"status":"success","data":{"resultType":"matrix","result":[
+		{"metric":{"instance":"a"},"values":[[1700000000,"1"],[1700000060,"2"]]},
+		{"metric":{"instance":"b"},"values":[[1700000000,"3"]]}
+	]}}`)
+
+	w, err := CreatePrometheusGraphWidget("graph", configToNode(map[string]interface{}{
+		"query":  "up",
+		"window": "2h",
+	}), nil, provider, nil, nil)
+	require.NoError(t, err)
+	graph := w.(*PrometheusGraphWidget)
+	assert.Equal(t, 30*time.Second, graph.step)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, graph.Init(ctx))
+
+	require.Eventually(t, func() bool {
+		graph.mu.Lock()
+		defer graph.mu.Unlock()
+		return len(graph.series) == 2
+	}, testTimeout, 10*time.Millisecond)
+
+	data := graph.GetData().(*PrometheusData)
+	require.Len(t, data.Series, 2)
+	assert.Equal(t, map[string]string{"instance": "b"}, data.Series[1].Labels)
+	require.Len(t, data.Series[1].Points, 1)
+	assert.Equal(t, int64(1700000000), data.Series[1].Points[0].Time.Unix())
+	assert.Equal(t, 3.0, data.Series[1].Points[0].Value)
+
+	graph.mu.Lock()
+	defer graph.mu.Unlock()
+	assert.Len(t, graph.series[0].Points, 2)
+	assert.NotEqual(t, graph.series[0].Color, graph.series[1].Color)
+}
+
+func TestPrometheusGaugeFillColor(t *testing.T) {
+	w, err := CreatePrometheusGaugeWidget("gauge", configToNode(map[string]interface{}{
+		"query": "disk_used_percent",
+		"thresholds": []interface{}{
+			map[string]interface{}{"value": 90},
+			map[string]interface{}{"value": 75},
+		},
+	}), nil, newFakeProvider(), nil, nil)
+	require.NoError(t, err)
+	gauge := w.(*PrometheusGaugeWidget)
+	gauge.thresholds[1].Color.G = 0x80
+
+	assert.Equal(t, seriesColors[0], gauge.fillColor(50))
+	assert.Equal(t, gauge.thresholds[1].Color, gauge.fillColor(80))
+	assert.Equal(t, gauge.thresholds[0].Color, gauge.fillColor(95))
+}
+
+func TestPrometheusWidgetConfigValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		create WidgetCreator
+		config map[string]interface{}
+	}{
+		{name: "missing query", create: CreatePrometheusStatWidget, config: map[string]interface{}{}},
+		{name: "invalid refresh interval", create: CreatePrometheusStatWidget, config: map[string]interface{}{"query": "up", "refresh_interval": "often"}},
+		{name: "negative decimals", create: CreatePrometheusStatWidget, config: map[string]interface{}{"query": "up", "decimals": -1}},
+		{name: "gauge min above max", create: CreatePrometheusGaugeWidget, config: map[string]interface{}{"query": "up", "min": 10, "max": 0}},
+		{name: "unknown graph type", create: CreatePrometheusGraphWidget, config: map[string]interface{}{"query": "up", "type": "bar"}},
+		{name: "invalid graph step", create: CreatePrometheusGraphWidget, config: map[string]interface{}{"query": "up", "step": "0s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.create("widget", configToNode(tt.config), nil, newFakeProvider(), nil, nil)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package widgets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gioui.org/app"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/mntndev/dash/pkg/events"
+	"github.com/mntndev/dash/pkg/integrations"
+)
+
+const (
+	defaultRSSListLimit   = 10
+	defaultRSSTickerLimit = 20
+	// defaultTickerSpeed is how far the ticker scrolls per second, in dp.
+	defaultTickerSpeed = 60
+	// ageRefresh is how often the list redraws so relative ages stay current.
+	ageRefresh = time.Minute
+)
+
+type RSSListConfig struct {
+	// Feeds selects feeds by name or URL. Empty means all feeds.
+	Feeds []string `yaml:"feeds"`
+	Limit int      `yaml:"limit"`
+	// ShowSource adds the feed name next to the age of each item.
+	ShowSource bool `yaml:"show_source"`
+}
+
+type RSSTickerConfig struct {
+	Feeds []string `yaml:"feeds"`
+	Limit int      `yaml:"limit"`
+	// Speed is the scroll speed in dp per second.
+	Speed     float64 `yaml:"speed"`
+	Separator string  `yaml:"separator"`
+}
+
+// RSSData is the headlines an RSS widget shows.
+type RSSData struct {
+	Items []RSSItem `json:"items"`
+	// Error is the last failed fetch of one of the widget's feeds.
+	Error string `json:"error,omitempty"`
+}
+
+type RSSItem struct {
+	Title     string    `json:"title"`
+	Link      string    `json:"link,omitempty"`
+	Feed      string    `json:"feed,omitempty"`
+	Published time.Time `json:"published,omitzero"`
+}
+
+// rssBase redraws a widget and publishes its headlines whenever one of its
+// feeds was fetched.
+type rssBase struct {
+	*BaseWidget
+	provider Provider
+	theme    *material.Theme
+	feeds    []string
+	limit    int
+	// fixture is shown instead of the feeds if set.
+	fixture atomic.Pointer[RSSData]
+}
+
+type RSSListWidget struct {
+	*rssBase
+	showSource bool
+	list       layout.List
+}
+
+type RSSTickerWidget struct {
+	*rssBase
+	speed     float64
+	separator string
+	start     time.Time
+}
+
+func (b *rssBase) client() *integrations.RSSClient {
+	return getIntegration[*integrations.RSSClient](b.provider, integrations.RSSName)
+}
+
+func (b *rssBase) Init(ctx context.Context) error {
+	b.SetLastUpdate(time.Now())
+
+	client := b.client()
+	if client == nil {
+		return nil
+	}
+
+	updates := client.Subscribe()
+	go func() {
+		defer client.Unsubscribe(updates)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-updates:
+				if !ok {
+					return
+				}
+				b.Invalidate()
+				publish(b.provider, events.WidgetData{ID: b.ID, Type: b.Type, Data: b.GetData()})
+			}
+		}
+	}()
+	return nil
+}
+
+// items returns the widget's headlines and the error of the last failed
+// fetch of one of its feeds.
+func (b *rssBase) items() ([]integrations.FeedItem, error) {
+	if fixture := b.fixture.Load(); fixture != nil {
+		items := make([]integrations.FeedItem, min(len(fixture.Items), b.limit))
+		for i, item := range fixture.Items[:len(items)] {
+			items[i] = integrations.FeedItem{Title: item.Title, Link: item.Link, Feed: item.Feed, Published: item.Published}
+		}
+		var err error
+		if fixture.Error != "" {
+			err = errors.New(fixture.Error)
+		}
+		return items, err
+	}
+
+	client := b.client()
+	if client == nil {
+		return nil, fmt.Errorf("no feeds configured")
+	}
+	return client.Items(b.feeds, b.limit), client.Err(b.feeds)
+}
+
+// GetData returns the widget's headlines.
+func (b *rssBase) GetData() interface{} {
+	items, err := b.items()
+	data := &RSSData{Items: make([]RSSItem, len(items))}
+	for i, item := range items {
+		data.Items[i] = RSSItem{Title: item.Title, Link: item.Link, Feed: item.Feed, Published: item.Published}
+	}
+	if err != nil {
+		data.Error = err.Error()
+	}
+	return data
+}
+
+// LoadFixture shows the headlines in data instead of the feeds.
+func (b *rssBase) LoadFixture(raw json.RawMessage) error {
+	var data RSSData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("invalid rss fixture: %w", err)
+	}
+	b.fixture.Store(&data)
+	b.Invalidate()
+	return nil
+}
+
+func CreateRSSListWidget(id string, config ast.Node, children []Widget, provider Provider, window *app.Window, theme *material.Theme) (Widget, error) {
+	var listConfig RSSListConfig
+	if config != nil {
+		if err := yaml.NodeToValue(config, &listConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse rss list config: %w", err)
+		}
+	}
+
+	if listConfig.Limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative")
+	}
+	limit := listConfig.Limit
+	if limit == 0 {
+		limit = defaultRSSListLimit
+	}
+
+	return &RSSListWidget{
+		rssBase: &rssBase{
+			BaseWidget: &BaseWidget{
+				ID:       id,
+				Type:     "rss.list",
+				Config:   config,
+				Children: children,
+				window:   window,
+			},
+			provider: provider,
+			theme:    theme,
+			feeds:    listConfig.Feeds,
+			limit:    limit,
+		},
+		showSource: listConfig.ShowSource,
+		list:       layout.List{Axis: layout.Vertical},
+	}, nil
+}
+
+func (w *RSSListWidget) Layout(gtx layout.Context) layout.Dimensions {
+	items, err := w.items()
+	if len(items) == 0 {
+		text := "No headlines"
+		if err != nil {
+			text = err.Error()
+		}
+		return material.Body2(w.theme, text).Layout(gtx)
+	}
+
+	now := gtx.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	gtx.Execute(op.InvalidateCmd{At: now.Add(ageRefresh)})
+
+	return w.list.Layout(gtx, len(items), func(gtx layout.Context, i int) layout.Dimensions {
+		item := items[i]
+		meta := formatAge(now.Sub(item.Published))
+		if w.showSource {
+			meta = strings.TrimPrefix(meta+" · "+item.Feed, " · ")
+		}
+
+		return layout.Inset{Bottom: unit.Dp(6)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					title := material.Body1(w.theme, item.Title)
+					title.MaxLines = 2
+					return title.Layout(gtx)
+				}),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					if meta == "" {
+						return layout.Dimensions{}
+					}
+					return material.Caption(w.theme, meta).Layout(gtx)
+				}),
+			)
+		})
+	})
+}
+
+// formatAge renders how long ago an item was published, e.g. "5m ago".
+// Undated items have no age.
+func formatAge(age time.Duration) string {
+	switch {
+	case age > 100*365*24*time.Hour:
+		// Zero publish time
+		return ""
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(age.Hours()/24))
+	}
+}
+
+func CreateRSSTickerWidget(id string, config ast.Node, children []Widget, provider Provider, window *app.Window, theme *material.Theme) (Widget, error) {
+	var tickerConfig RSSTickerConfig
+	if config != nil {
+		if err := yaml.NodeToValue(config, &tickerConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse rss ticker config: %w", err)
+		}
+	}
+
+	if tickerConfig.Limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative")
+	}
+	limit := tickerConfig.Limit
+	if limit == 0 {
+		limit = defaultRSSTickerLimit
+	}
+
+	if tickerConfig.Speed < 0 {
+		return nil, fmt.Errorf("speed must not be negative")
+	}
+	speed := tickerConfig.Speed
+	if speed == 0 {
+		speed = defaultTickerSpeed
+	}
+
+	separator := tickerConfig.Separator
+	if separator == "" {
+		separator = "  •  "
+	}
+
+	return &RSSTickerWidget{
+		rssBase: &rssBase{
+			BaseWidget: &BaseWidget{
+				ID:       id,
+				Type:     "rss.ticker",
+				Config:   config,
+				Children: children,
+				window:   window,
+			},
+			provider: provider,
+			theme:    theme,
+			feeds:    tickerConfig.Feeds,
+			limit:    limit,
+		},
+		speed:     speed,
+		separator: separator,
+	}, nil
+}
+
+func (w *RSSTickerWidget) text() string {
+	items, err := w.items()
+	if len(items) == 0 {
+		if err != nil {
+			return err.Error()
+		}
+		return "No headlines"
+	}
+
+	titles := make([]string, len(items))
+	for i, item := range items {
+		titles[i] = item.Title
+	}
+	return strings.Join(titles, w.separator) + w.separator
+}
+
+// Layout draws the headlines on one line and scrolls them from right to
+// left, repeating the strip as often as needed to fill the width.
+func (w *RSSTickerWidget) Layout(gtx layout.Context) layout.Dimensions {
+	now := gtx.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if w.start.IsZero() {
+		w.start = now
+	}
+
+	label := material.Body1(w.theme, w.text())
+	label.MaxLines = 1
+
+	// Measure the strip without a width limit so it does not wrap
+	measure := gtx
+	measure.Constraints = layout.Constraints{Max: image.Pt(1<<24, gtx.Constraints.Max.Y)}
+	macro := op.Record(gtx.Ops)
+	dims := label.Layout(measure)
+	strip := macro.Stop()
+
+	width := gtx.Constraints.Max.X
+	size := image.Pt(width, dims.Size.Y)
+	if dims.Size.X <= 0 || width <= 0 {
+		return layout.Dimensions{Size: size}
+	}
+
+	defer clip.Rect{Max: size}.Push(gtx.Ops).Pop()
+
+	scrolled := now.Sub(w.start).Seconds() * float64(gtx.Dp(unit.Dp(w.speed)))
+	offset := int(scrolled) % dims.Size.X
+	for x := -offset; x < width; x += dims.Size.X {
+		stack := op.Offset(image.Pt(x, 0)).Push(gtx.Ops)
+		strip.Add(gtx.Ops)
+		stack.Pop()
+	}
+
+	gtx.Execute(op.InvalidateCmd{})
+	return layout.Dimensions{Size: size}
+}
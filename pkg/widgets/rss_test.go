@@ -0,0 +1,110 @@
+package widgets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/mntndev/dash/pkg/config"
+	"github.com/mntndev/dash/pkg/events"
+	"github.com/mntndev/dash/pkg/integrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatAge(t *testing.T) {
+	tests := []struct {
+		age      time.Duration
+		expected string
+	}{
+		{age: 10 * time.Second, expected: "just now"},
+		{age: 5 * time.Minute, expected: "5m ago"},
+		{age: 3*time.Hour + 20*time.Minute, expected: "3h ago"},
+		{age: 50 * time.Hour, expected: "2d ago"},
+		{age: time.Since(time.Time{}), expected: ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, formatAge(tt.age), tt.age.String())
+	}
+}
+
+func TestRSSTickerText(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<rss><channel><title>News</title>
+			<item><title>Older</title><guid>1</guid><pubDate>Mon, 13 Oct 2025 09:00:00 +0000</pubDate></item>
+			<item><title>Newer</title><guid>2</guid><pubDate>Mon, 13 Oct 2025 10:00:00 +0000</pubDate></item>
+		</channel></rss>`))
+	}))
+	defer server.Close()
+
+	client := integrations.NewRSSClient([]config.RSSConfig{{Name: "news", URL: server.URL}})
+	defer func() { _ = client.Stop() }()
+	provider := newFakeProvider(client)
+
+	w, err := CreateRSSTickerWidget("ticker", configToNode(map[string]interface{}{
+		"feeds":     []interface{}{"news"},
+		"separator": " | ",
+	}), nil, provider, nil, nil)
+	require.NoError(t, err)
+	ticker := w.(*RSSTickerWidget)
+	assert.Equal(t, "No headlines", ticker.text())
+
+	provider.bus = events.NewBus()
+	defer provider.bus.Close()
+	updates := events.Subscribe[events.WidgetData](provider.bus, 8)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, ticker.Init(ctx))
+
+	require.NoError(t, client.Start())
+	select {
+	case update := <-updates.C():
+		assert.Equal(t, "ticker", update.ID)
+		assert.Len(t, update.Data.(*RSSData).Items, 2)
+	case <-time.After(testTimeout):
+		t.Fatal("no widget data published")
+	}
+	require.Eventually(t, func() bool {
+		return ticker.text() == "Newer | Older | "
+	}, testTimeout, 10*time.Millisecond)
+
+	data := ticker.GetData().(*RSSData)
+	require.Len(t, data.Items, 2)
+	assert.Equal(t, "Newer", data.Items[0].Title)
+	assert.Equal(t, "news", data.Items[0].Feed)
+	assert.Empty(t, data.Error)
+}
+
+func TestRSSWidgetsWithoutClient(t *testing.T) {
+	w, err := CreateRSSListWidget("list", nil, nil, newFakeProvider(), nil, nil)
+	require.NoError(t, err)
+	list := w.(*RSSListWidget)
+	assert.Equal(t, defaultRSSListLimit, list.limit)
+
+	_, err = list.items()
+	assert.Error(t, err)
+	assert.Equal(t, &RSSData{Items: []RSSItem{}, Error: "no feeds configured"}, list.GetData())
+
+	_, err = CreateRSSTickerWidget("ticker", configToNode(map[string]interface{}{"speed": -5}), nil, newFakeProvider(), nil, nil)
+	assert.Error(t, err)
+}
+
+func TestConfigToNodeQuoting(t *testing.T) {
+	// Feed names and URLs often contain characters YAML only accepts quoted
+	input := map[string]interface{}{
+		"feed":  "https://example.com/feed.xml?tag=go#latest",
+		"name":  "News: Go",
+		"unit":  "%",
+		"color": "#ff0000",
+	}
+
+	var decoded map[string]string
+	require.NoError(t, yaml.NodeToValue(configToNode(input), &decoded))
+	for key, value := range input {
+		assert.Equal(t, value, decoded[key], key)
+	}
+}
@@ -3,16 +3,23 @@ package widgets
 import (
 	"github.com/goccy/go-yaml"
 	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
 )
 
-// Helper function to convert map to ast.Node for tests.
+// Helper function to convert map to ast.Node for tests. The map goes through
+// YAML text, so strings that need quoting come out like they do when parsed
+// from a config file.
 func configToNode(config map[string]interface{}) ast.Node {
 	if config == nil {
 		return nil
 	}
-	node, err := yaml.ValueToNode(config)
+	data, err := yaml.Marshal(config)
 	if err != nil {
 		panic(err) // Should not happen in tests
 	}
-	return node
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		panic(err) // Should not happen in tests
+	}
+	return file.Docs[0].Body
 }
@@ -2,9 +2,12 @@ package widgets
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"gioui.org/app"
@@ -12,13 +15,41 @@ import (
 	"gioui.org/widget/material"
 	"github.com/goccy/go-yaml"
 	"github.com/goccy/go-yaml/ast"
+	"github.com/mntndev/dash/pkg/config"
+	"github.com/mntndev/dash/pkg/events"
 	"github.com/mntndev/dash/pkg/integrations"
 )
 
-// Provider interface combines all the services widgets might need.
+// Provider gives widgets access to the integrations configured in the
+// dashboard and to its event bus.
 type Provider interface {
-	GetHAClient() *integrations.HomeAssistantClient
-	GetDexcomClient() *integrations.DexcomClient
+	// GetIntegration returns the running integration configured under name,
+	// or nil if there is none.
+	GetIntegration(name string) integrations.Integration
+	// Events returns the bus of the dashboard. It may be nil.
+	Events() *events.Bus
+}
+
+// getIntegration looks up an integration and returns it as T. It returns the
+// zero value if the integration is not configured or of another type.
+func getIntegration[T integrations.Integration](provider Provider, name string) T {
+	var zero T
+	if provider == nil {
+		return zero
+	}
+	integration, ok := provider.GetIntegration(name).(T)
+	if !ok {
+		return zero
+	}
+	return integration
+}
+
+// publish sends event on the dashboard's bus, if there is one.
+func publish(provider Provider, event events.Event) {
+	if provider == nil {
+		return
+	}
+	provider.Events().Publish(event)
 }
 
 type Widget interface {
@@ -30,11 +61,41 @@ type Widget interface {
 	Layout(gtx layout.Context) layout.Dimensions
 }
 
+// LastUpdater is implemented by widgets that know when they last got new
+// data, as all based on BaseWidget do. GetLastUpdate must be safe to call
+// from any goroutine.
+type LastUpdater interface {
+	GetLastUpdate() time.Time
+}
+
 // Triggerable interface defines widgets that can be manually triggered.
 type Triggerable interface {
 	Trigger() error
 }
 
+// DataProvider is implemented by widgets that expose the data they show,
+// e.g. to the HTTP API. GetData must be safe to call from any goroutine.
+type DataProvider interface {
+	GetData() interface{}
+}
+
+// AlertSource is implemented by widgets that raise alerts. They publish an
+// events.Alert whenever theirs starts, changes or ends, and the dashboard
+// then asks every widget for its current alert, so events dropped on the way
+// lose nothing.
+type AlertSource interface {
+	// Alert returns the alert of the widget, one that is not Active if
+	// there is none.
+	Alert() events.Alert
+}
+
+// FixtureLoader is implemented by widgets that can show fixed data instead of
+// what their integration reports, so a dashboard renders without live
+// services. The data is JSON in the shape GetData returns.
+type FixtureLoader interface {
+	LoadFixture(data json.RawMessage) error
+}
+
 type BrightnessControllable interface {
 	SetBrightness(brightness int) error
 }
@@ -49,13 +110,24 @@ type Container interface {
 	SetChildren(children []Widget)
 }
 
+// Placer is implemented by containers that place their children on a grid.
+// The dashboard passes the position of every child from the config, nil for
+// children without one, before the widget is initialized.
+type Placer interface {
+	SetPositions(positions []*config.Position) error
+}
+
 type BaseWidget struct {
-	ID         string      `json:"ID"`
-	Type       string      `json:"Type"`
-	Config     ast.Node    `json:"-"`
-	LastUpdate time.Time   `json:"LastUpdate"`
-	Children   []Widget    `json:"Children"`
-	window     *app.Window `json:"-"`
+	ID       string      `json:"ID"`
+	Type     string      `json:"Type"`
+	Config   ast.Node    `json:"-"`
+	Children []Widget    `json:"Children"`
+	window   *app.Window `json:"-"`
+
+	// lastUpdate is written by the goroutines of the widget and read by
+	// the API.
+	updateMu   sync.RWMutex
+	lastUpdate time.Time
 }
 
 func (w *BaseWidget) GetID() string {
@@ -66,6 +138,19 @@ func (w *BaseWidget) GetType() string {
 	return w.Type
 }
 
+// GetLastUpdate returns when the widget last got new data.
+func (w *BaseWidget) GetLastUpdate() time.Time {
+	w.updateMu.RLock()
+	defer w.updateMu.RUnlock()
+	return w.lastUpdate
+}
+
+func (w *BaseWidget) SetLastUpdate(t time.Time) {
+	w.updateMu.Lock()
+	defer w.updateMu.Unlock()
+	w.lastUpdate = t
+}
+
 func (w *BaseWidget) GetChildren() []Widget {
 	return w.Children
 }
@@ -156,6 +241,13 @@ func registerBuiltinWidgets(registry *WidgetRegistry) {
 
 	registry.Register("dexcom", CreateDexcomWidget)
 
+	registry.Register("prometheus.stat", CreatePrometheusStatWidget)
+	registry.Register("prometheus.gauge", CreatePrometheusGaugeWidget)
+	registry.Register("prometheus.graph", CreatePrometheusGraphWidget)
+
+	registry.Register("rss.list", CreateRSSListWidget)
+	registry.Register("rss.ticker", CreateRSSTickerWidget)
+
 	registry.Register("clock", CreateClockWidget)
 
 	// New layout widgets
@@ -163,6 +255,10 @@ func registerBuiltinWidgets(registry *WidgetRegistry) {
 	registry.Register("vstack", CreateVStackWidget)
 	registry.Register("hflex", CreateHFlexWidget)
 	registry.Register("vflex", CreateVFlexWidget)
+	registry.Register("horizontal_split", CreateHorizontalSplitWidget)
+	registry.Register("vertical_split", CreateVerticalSplitWidget)
+	registry.Register("pages", CreatePagesWidget)
+	registry.Register("grid", CreateGridWidget)
 
 	registry.Register("grow", func(id string, config ast.Node, children []Widget, provider Provider, window *app.Window, theme *material.Theme) (Widget, error) {
 		return CreateGrowWidgetWithWindow(id, config, children, window)
@@ -225,6 +321,12 @@ func (wm *WidgetManager) RemoveWidget(id string) {
 	}
 }
 
+// ForgetWidget removes a widget without closing it, for widgets that are
+// closed elsewhere or still in use.
+func (wm *WidgetManager) ForgetWidget(id string) {
+	delete(wm.widgets, id)
+}
+
 type GrowWidget struct {
 	*BaseWidget
 	GrowValue string
@@ -259,7 +361,7 @@ func createGrowWidget(id string, config ast.Node, children []Widget, window *app
 		case string:
 			growValue = strings.Trim(val, `"`)
 		case float64:
-			growValue = fmt.Sprintf("%.0f", val)
+			growValue = strconv.FormatFloat(val, 'f', -1, 64)
 		case int:
 			growValue = fmt.Sprintf("%d", val)
 		case int64:
@@ -287,7 +389,7 @@ func createGrowWidget(id string, config ast.Node, children []Widget, window *app
 }
 
 func (w *GrowWidget) Init(ctx context.Context) error {
-	w.LastUpdate = time.Now()
+	w.SetLastUpdate(time.Now())
 	return nil
 }
 
@@ -301,7 +403,7 @@ func (w *GrowWidget) IsContainer() bool {
 
 func (w *GrowWidget) SetChildren(children []Widget) {
 	w.Children = children
-	w.LastUpdate = time.Now()
+	w.SetLastUpdate(time.Now())
 }
 
 func (w *GrowWidget) Close() error {
@@ -314,5 +416,5 @@ func (w *GrowWidget) Layout(gtx layout.Context) layout.Dimensions {
 		return layout.Dimensions{}
 	}
 	// Grow widget just renders its first child
-	return children[0].Layout(gtx)
+	return LayoutWidget(gtx, children[0])
 }